@@ -34,6 +34,11 @@ func testMirrorPush(t *testing.T, u *url.URL) {
 
 	setting.Migrations.AllowLocalNetworks = true
 	assert.NoError(t, migrations.Init())
+	// the push mirror pushes to a repository of the instance under test
+	defer func(allow bool) {
+		setting.Mirror.AllowSameInstancePush = allow
+	}(setting.Mirror.AllowSameInstancePush)
+	setting.Mirror.AllowSameInstancePush = true
 
 	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2}).(*user_model.User)
 	srcRepo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
@@ -45,6 +45,7 @@ type Issue struct {
 	Poster           *user_model.User       `xorm:"-"`
 	OriginalAuthor   string
 	OriginalAuthorID int64      `xorm:"index"`
+	OriginalURL      string     `xorm:"TEXT"`
 	Title            string     `xorm:"name"`
 	Content          string     `xorm:"LONGTEXT"`
 	RenderedContent  string     `xorm:"-"`
@@ -1141,10 +1142,15 @@ func GetIssueByIndex(repoID, index int64) (*Issue, error) {
 
 // GetIssueByForeignIndex returns raw issue by foreign ID
 func GetIssueByForeignIndex(ctx context.Context, repoID, foreignIndex int64) (*Issue, error) {
+	return GetIssueByForeignIndexAndType(ctx, repoID, foreignIndex, foreignreference.TypeIssue)
+}
+
+// GetIssueByForeignIndexAndType returns raw issue or pull request by foreign ID and reference type
+func GetIssueByForeignIndexAndType(ctx context.Context, repoID, foreignIndex int64, tp string) (*Issue, error) {
 	reference := &foreignreference.ForeignReference{
 		RepoID:       repoID,
 		ForeignIndex: strconv.FormatInt(foreignIndex, 10),
-		Type:         foreignreference.TypeIssue,
+		Type:         tp,
 	}
 	has, err := db.GetEngine(ctx).Get(reference)
 	if err != nil {
@@ -1153,7 +1159,7 @@ func GetIssueByForeignIndex(ctx context.Context, repoID, foreignIndex int64) (*I
 		return nil, foreignreference.ErrLocalIndexNotExist{
 			RepoID:       repoID,
 			ForeignIndex: foreignIndex,
-			Type:         foreignreference.TypeIssue,
+			Type:         tp,
 		}
 	}
 	return GetIssueByIndex(repoID, reference.LocalIndex)
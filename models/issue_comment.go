@@ -15,6 +15,7 @@ import (
 	"unicode/utf8"
 
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/foreignreference"
 	"code.gitea.io/gitea/models/issues"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
@@ -195,6 +196,7 @@ type Comment struct {
 	Poster           *user_model.User `xorm:"-"`
 	OriginalAuthor   string
 	OriginalAuthorID int64
+	OriginalURL      string `xorm:"TEXT"`
 	IssueID          int64  `xorm:"INDEX"`
 	Issue            *Issue `xorm:"-"`
 	LabelID          int64
@@ -268,6 +270,9 @@ type Comment struct {
 	NewCommit   string                    `xorm:"-"`
 	CommitsNum  int64                     `xorm:"-"`
 	IsForcePush bool                      `xorm:"-"`
+
+	// ForeignReference is inserted with migrated comments, its LocalIndex is the ID of the comment
+	ForeignReference *foreignreference.ForeignReference `xorm:"-"`
 }
 
 func init() {
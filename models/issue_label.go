@@ -32,6 +32,7 @@ type Label struct {
 	Name            string
 	Description     string
 	Color           string `xorm:"VARCHAR(7)"`
+	Exclusive       bool   `xorm:"NOT NULL DEFAULT false"`
 	NumIssues       int
 	NumClosedIssues int
 	CreatedUnix     timeutil.TimeStamp `xorm:"INDEX created"`
@@ -146,6 +147,41 @@ func (label *Label) BelongsToRepo() bool {
 	return label.RepoID > 0
 }
 
+// ExclusiveScope returns the scope of an exclusive label named "scope/name",
+// an issue can only have one label of each scope. It is empty for other labels.
+func (label *Label) ExclusiveScope() string {
+	if !label.Exclusive {
+		return ""
+	}
+	lastIndex := strings.LastIndex(label.Name, "/")
+	if lastIndex <= 0 || lastIndex == len(label.Name)-1 {
+		return ""
+	}
+	return label.Name[:lastIndex]
+}
+
+// RemoveDuplicateExclusiveLabels keeps only the last label of each exclusive scope
+func RemoveDuplicateExclusiveLabels(labels []*Label) []*Label {
+	validLabels := make([]*Label, 0, len(labels))
+	for i, label := range labels {
+		scope := label.ExclusiveScope()
+		if scope != "" {
+			foundOther := false
+			for _, otherLabel := range labels[i+1:] {
+				if otherLabel.ExclusiveScope() == scope {
+					foundOther = true
+					break
+				}
+			}
+			if foundOther {
+				continue
+			}
+		}
+		validLabels = append(validLabels, label)
+	}
+	return validLabels
+}
+
 // SrgbToLinear converts a component of an sRGB color to its linear intensity
 // See: https://en.wikipedia.org/wiki/SRGB#The_reverse_transformation_(sRGB_to_CIE_XYZ)
 func SrgbToLinear(color uint8) float64 {
@@ -681,9 +717,35 @@ func HasIssueLabel(issueID, labelID int64) bool {
 	return hasIssueLabel(db.GetEngine(db.DefaultContext), issueID, labelID)
 }
 
+// removeDuplicateExclusiveIssueLabels removes the labels of the issue which have the exclusive scope of the label
+func removeDuplicateExclusiveIssueLabels(ctx context.Context, issue *Issue, label *Label, doer *user_model.User) error {
+	scope := label.ExclusiveScope()
+	if scope == "" {
+		return nil
+	}
+
+	issueLabels, err := getLabelsByIssueID(db.GetEngine(ctx), issue.ID)
+	if err != nil {
+		return err
+	}
+	for _, issueLabel := range issueLabels {
+		if issueLabel.ID != label.ID && issueLabel.ExclusiveScope() == scope {
+			if err := deleteIssueLabel(ctx, issue, issueLabel, doer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // newIssueLabel this function creates a new label it does not check if the label is valid for the issue
 // YOU MUST CHECK THIS BEFORE THIS FUNCTION
 func newIssueLabel(ctx context.Context, issue *Issue, label *Label, doer *user_model.User) (err error) {
+	// an issue only has one label of each exclusive scope
+	if err = removeDuplicateExclusiveIssueLabels(ctx, issue, label, doer); err != nil {
+		return err
+	}
+
 	e := db.GetEngine(ctx)
 	if _, err = e.Insert(&IssueLabel{
 		IssueID: issue.ID,
@@ -353,6 +353,41 @@ func TestNewIssueLabels(t *testing.T) {
 	unittest.CheckConsistencyFor(t, &Issue{}, &Label{})
 }
 
+func TestNewIssueLabelsExclusive(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+	issue := unittest.AssertExistsAndLoadBean(t, &Issue{ID: 5}).(*Issue)
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2}).(*user_model.User)
+
+	high := &Label{RepoID: issue.RepoID, Name: "priority/high", Color: "#ee0701", Exclusive: true}
+	low := &Label{RepoID: issue.RepoID, Name: "priority/low", Color: "#00ff00", Exclusive: true}
+	scopedOnly := &Label{RepoID: issue.RepoID, Name: "priority/medium", Color: "#0000ff"}
+	assert.NoError(t, NewLabels(high, low, scopedOnly))
+
+	// only the last label of an exclusive scope is added
+	assert.NoError(t, NewIssueLabels(issue, []*Label{high, scopedOnly, low}, doer))
+	unittest.AssertNotExistsBean(t, &IssueLabel{IssueID: issue.ID, LabelID: high.ID})
+	unittest.AssertExistsAndLoadBean(t, &IssueLabel{IssueID: issue.ID, LabelID: low.ID})
+	unittest.AssertExistsAndLoadBean(t, &IssueLabel{IssueID: issue.ID, LabelID: scopedOnly.ID})
+
+	// a label replaces the label of its exclusive scope
+	assert.NoError(t, NewIssueLabel(issue, high, doer))
+	unittest.AssertExistsAndLoadBean(t, &IssueLabel{IssueID: issue.ID, LabelID: high.ID})
+	unittest.AssertNotExistsBean(t, &IssueLabel{IssueID: issue.ID, LabelID: low.ID})
+	unittest.AssertExistsAndLoadBean(t, &IssueLabel{IssueID: issue.ID, LabelID: scopedOnly.ID})
+	unittest.AssertExistsAndLoadBean(t, &Comment{Type: CommentTypeLabel, IssueID: issue.ID, LabelID: low.ID, Content: ""})
+
+	unittest.CheckConsistencyFor(t, &Issue{}, &Label{})
+}
+
+func TestRemoveDuplicateExclusiveLabels(t *testing.T) {
+	bug := &Label{ID: 1, Name: "kind/bug", Exclusive: true}
+	feature := &Label{ID: 2, Name: "kind/feature", Exclusive: true}
+	ui := &Label{ID: 3, Name: "area/ui"}
+	api := &Label{ID: 4, Name: "area/api"}
+	assert.Equal(t, []*Label{ui, api, feature}, RemoveDuplicateExclusiveLabels([]*Label{bug, ui, api, feature}))
+	assert.Empty(t, RemoveDuplicateExclusiveLabels(nil))
+}
+
 func TestDeleteIssueLabel(t *testing.T) {
 	assert.NoError(t, unittest.PrepareTestDatabase())
 	testSuccess := func(labelID, issueID, doerID int64) {
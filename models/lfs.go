@@ -72,6 +72,48 @@ func NewLFSMetaObject(m *LFSMetaObject) (*LFSMetaObject, error) {
 	return m, committer.Commit()
 }
 
+// NewLFSMetaObjects creates the meta objects of the pointers in a repository in a single transaction.
+// Pointers which already have a meta object in the repository are skipped.
+func NewLFSMetaObjects(repoID int64, pointers []lfs.Pointer) error {
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	oids := make([]string, 0, len(pointers))
+	for _, p := range pointers {
+		oids = append(oids, p.Oid)
+	}
+	var existing []string
+	if err := db.GetEngine(ctx).Table("lfs_meta_object").Where("repository_id = ?", repoID).In("oid", oids).Cols("oid").Find(&existing); err != nil {
+		return err
+	}
+	skip := make(map[string]bool, len(pointers))
+	for _, oid := range existing {
+		skip[oid] = true
+	}
+
+	metas := make([]*LFSMetaObject, 0, len(pointers))
+	for _, p := range pointers {
+		if skip[p.Oid] {
+			continue
+		}
+		skip[p.Oid] = true
+		metas = append(metas, &LFSMetaObject{Pointer: p, RepositoryID: repoID})
+	}
+	if len(metas) > 0 {
+		if err := db.Insert(ctx, metas); err != nil {
+			return err
+		}
+	}
+	return committer.Commit()
+}
+
 // GetLFSMetaObjectByOid selects a LFSMetaObject entry from database by its OID.
 // It may return ErrLFSObjectNotExist or a database error. If the error is nil,
 // the returned pointer is a valid LFSMetaObject.
@@ -116,6 +158,51 @@ func RemoveLFSMetaObjectByOid(repoID int64, oid string) (int64, error) {
 	return count, committer.Commit()
 }
 
+// RemoveLFSMetaObjectsByRepoID removes all LFSMetaObjects of a repository from the database.
+// It returns the relative paths of the LFS contents which are no longer referenced by any repository
+// and can be removed from the storage.
+func RemoveLFSMetaObjectsByRepoID(repoID int64) ([]string, error) {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return nil, err
+	}
+	defer committer.Close()
+
+	lfsPaths, err := removeLFSMetaObjectsByRepoID(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	return lfsPaths, committer.Commit()
+}
+
+func removeLFSMetaObjectsByRepoID(ctx context.Context, repoID int64) ([]string, error) {
+	sess := db.GetEngine(ctx)
+
+	var lfsObjects []*LFSMetaObject
+	if err := sess.Where("repository_id=?", repoID).Find(&lfsObjects); err != nil {
+		return nil, err
+	}
+
+	lfsPaths := make([]string, 0, len(lfsObjects))
+	for _, v := range lfsObjects {
+		count, err := sess.Count(&LFSMetaObject{Pointer: lfs.Pointer{Oid: v.Oid}})
+		if err != nil {
+			return nil, err
+		}
+		// the content is shared with another repository
+		if count > 1 {
+			continue
+		}
+
+		lfsPaths = append(lfsPaths, v.RelativePath())
+	}
+
+	if _, err := sess.Delete(&LFSMetaObject{RepositoryID: repoID}); err != nil {
+		return nil, err
+	}
+	return lfsPaths, nil
+}
+
 // GetLFSMetaObjects returns all LFSMetaObjects associated with a repository
 func GetLFSMetaObjects(repoID int64, page, pageSize int) ([]*LFSMetaObject, error) {
 	sess := db.GetEngine(db.DefaultContext)
@@ -6,8 +6,10 @@ package models
 
 import (
 	"context"
+	"strconv"
 
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/foreignreference"
 	"code.gitea.io/gitea/modules/structs"
 
 	"xorm.io/builder"
@@ -27,13 +29,17 @@ func InsertMilestones(ms ...*Milestone) (err error) {
 	sess := db.GetEngine(ctx)
 
 	// to return the id, so we should not use batch insert
+	numClosed := 0
 	for _, m := range ms {
 		if _, err = sess.NoAutoTime().Insert(m); err != nil {
 			return err
 		}
+		if m.IsClosed {
+			numClosed++
+		}
 	}
 
-	if _, err = db.Exec(ctx, "UPDATE `repository` SET num_milestones = num_milestones + ? WHERE id = ?", len(ms), ms[0].RepoID); err != nil {
+	if _, err = db.Exec(ctx, "UPDATE `repository` SET num_milestones = num_milestones + ?, num_closed_milestones = num_closed_milestones + ? WHERE id = ?", len(ms), numClosed, ms[0].RepoID); err != nil {
 		return err
 	}
 	return committer.Commit()
@@ -60,6 +66,7 @@ func insertIssue(ctx context.Context, issue *Issue) error {
 	if _, err := sess.NoAutoTime().Insert(issue); err != nil {
 		return err
 	}
+	issue.Labels = RemoveDuplicateExclusiveLabels(issue.Labels)
 	issueLabels := make([]IssueLabel, 0, len(issue.Labels))
 	for _, label := range issue.Labels {
 		issueLabels = append(issueLabels, IssueLabel{
@@ -114,6 +121,24 @@ func InsertIssueComments(comments []*Comment) error {
 			return err
 		}
 
+		if comment.ForeignReference != nil {
+			// a foreign index which is already taken by a comment of another issue is not recorded again
+			has, err := db.GetEngine(ctx).Exist(&foreignreference.ForeignReference{
+				RepoID:       comment.ForeignReference.RepoID,
+				ForeignIndex: comment.ForeignReference.ForeignIndex,
+				Type:         comment.ForeignReference.Type,
+			})
+			if err != nil {
+				return err
+			}
+			if !has {
+				comment.ForeignReference.LocalIndex = comment.ID
+				if err := db.Insert(ctx, comment.ForeignReference); err != nil {
+					return err
+				}
+			}
+		}
+
 		for _, reaction := range comment.Reactions {
 			reaction.IssueID = comment.IssueID
 			reaction.CommentID = comment.ID
@@ -133,6 +158,26 @@ func InsertIssueComments(comments []*Comment) error {
 	return committer.Commit()
 }
 
+// InsertTrackedTimes inserts the tracked times of migrated issues keeping their creation time
+func InsertTrackedTimes(times []*TrackedTime) error {
+	if len(times) == 0 {
+		return nil
+	}
+
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+	sess := db.GetEngine(ctx)
+	for _, t := range times {
+		if _, err := sess.NoAutoTime().Insert(t); err != nil {
+			return err
+		}
+	}
+	return committer.Commit()
+}
+
 // InsertPullRequests inserted pull requests
 func InsertPullRequests(prs ...*PullRequest) error {
 	ctx, committer, err := db.TxContext()
@@ -181,6 +226,83 @@ func InsertReleases(rels ...*Release) error {
 	return committer.Commit()
 }
 
+// UpdateMigratedContents updates the contents of migrated issues and comments without changing their update time
+func UpdateMigratedContents(issues []*Issue, comments []*Comment) error {
+	if len(issues) == 0 && len(comments) == 0 {
+		return nil
+	}
+
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+	sess := db.GetEngine(ctx)
+	for _, issue := range issues {
+		if _, err := sess.ID(issue.ID).Cols("content").NoAutoTime().Update(issue); err != nil {
+			return err
+		}
+	}
+	for _, comment := range comments {
+		if _, err := sess.ID(comment.ID).Cols("content").NoAutoTime().Update(comment); err != nil {
+			return err
+		}
+	}
+	return committer.Commit()
+}
+
+// UpdateMigratedIssue updates the title, content and state of an issue which is imported again
+func UpdateMigratedIssue(ctx context.Context, issue *Issue) error {
+	_, err := db.GetEngine(ctx).ID(issue.ID).Cols("name", "content", "is_closed", "closed_unix", "updated_unix").NoAutoTime().Update(issue)
+	return err
+}
+
+// UpdateMigratedComment updates the content of a comment which is imported again
+func UpdateMigratedComment(ctx context.Context, comment *Comment) error {
+	_, err := db.GetEngine(ctx).ID(comment.ID).Cols("content", "updated_unix").NoAutoTime().Update(comment)
+	return err
+}
+
+// GetCommentByForeignIndex returns the comment of a repository which has been imported from the comment
+// with the foreign index. A reference to a comment which has been deleted since is removed.
+func GetCommentByForeignIndex(ctx context.Context, repoID, foreignIndex int64) (*Comment, error) {
+	reference := &foreignreference.ForeignReference{
+		RepoID:       repoID,
+		ForeignIndex: strconv.FormatInt(foreignIndex, 10),
+		Type:         foreignreference.TypeComment,
+	}
+	notExist := foreignreference.ErrLocalIndexNotExist{
+		RepoID:       repoID,
+		ForeignIndex: foreignIndex,
+		Type:         foreignreference.TypeComment,
+	}
+	has, err := db.GetEngine(ctx).Get(reference)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, notExist
+	}
+
+	comment, err := getCommentByID(db.GetEngine(ctx), reference.LocalIndex)
+	if IsErrCommentNotExist(err) {
+		if _, err := db.GetEngine(ctx).Delete(reference); err != nil {
+			return nil, err
+		}
+		return nil, notExist
+	}
+	return comment, err
+}
+
+// InsertCommentForeignReference records the foreign index a comment has been imported from
+func InsertCommentForeignReference(ctx context.Context, repoID, commentID, foreignIndex int64) error {
+	return db.Insert(ctx, &foreignreference.ForeignReference{
+		RepoID:       repoID,
+		LocalIndex:   commentID,
+		ForeignIndex: strconv.FormatInt(foreignIndex, 10),
+		Type:         foreignreference.TypeComment,
+	})
+}
+
 func migratedIssueCond(tp structs.GitServiceType) builder.Cond {
 	return builder.In("issue_id",
 		builder.Select("issue.id").
@@ -25,11 +25,18 @@ func TestMigrate_InsertMilestones(t *testing.T) {
 		RepoID: repo.ID,
 		Name:   name,
 	}
-	err := InsertMilestones(ms)
+	closedMs := &Milestone{
+		RepoID:   repo.ID,
+		Name:     "milestonetest2",
+		IsClosed: true,
+	}
+	err := InsertMilestones(ms, closedMs)
 	assert.NoError(t, err)
 	unittest.AssertExistsAndLoadBean(t, ms)
+	unittest.AssertExistsAndLoadBean(t, closedMs)
 	repoModified := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: repo.ID}).(*repo_model.Repository)
-	assert.EqualValues(t, repo.NumMilestones+1, repoModified.NumMilestones)
+	assert.EqualValues(t, repo.NumMilestones+2, repoModified.NumMilestones)
+	assert.EqualValues(t, repo.NumClosedMilestones+1, repoModified.NumClosedMilestones)
 
 	unittest.CheckConsistencyFor(t, &Milestone{})
 }
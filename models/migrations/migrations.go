@@ -378,6 +378,36 @@ var migrations = []Migration{
 
 	// v211 -> v212
 	NewMigration("Create ForeignReference table", createForeignReferenceTable),
+	// v212 -> v213
+	NewMigration("Add bundle columns to push_mirror table", addBundleColumnsToPushMirror),
+	// v213 -> v214
+	NewMigration("Add allow unrelated history column to push_mirror table", addAllowUnrelatedHistoryToPushMirror),
+	// v214 -> v215
+	NewMigration("Add tag filter column to push_mirror table", addTagFilterToPushMirror),
+	// v215 -> v216
+	NewMigration("Create push_mirror_sync_log table", createPushMirrorSyncLogTable),
+	// v216 -> v217
+	NewMigration("Add exclusive column to label table", addExclusiveToLabel),
+	// v217 -> v218
+	NewMigration("Add LFS only column to push_mirror table", addLFSOnlyToPushMirror),
+	// v218 -> v219
+	NewMigration("Add ref mapping column to push_mirror table", addRefMappingToPushMirror),
+	// v219 -> v220
+	NewMigration("Add pending LFS objects column to push_mirror table", addPendingLFSObjectsToPushMirror),
+	// v220 -> v221
+	NewMigration("Add notify webhook URL column to push_mirror table", addNotifyWebhookURLToPushMirror),
+	// v221 -> v222
+	NewMigration("Add original URL column to issue and comment tables", addOriginalURLToIssueAndComment),
+	// v222 -> v223
+	NewMigration("Add syncs since gc column to mirror and push_mirror tables", addSyncsSinceGCToMirrors),
+	// v223 -> v224
+	NewMigration("Add timeout seconds column to push_mirror table", addTimeoutSecondsToPushMirror),
+	// v224 -> v225
+	NewMigration("Add bundle upload URL column to push_mirror table", addBundleUploadURLToPushMirror),
+	// v225 -> v226
+	NewMigration("Add last changed refs column to push_mirror table", addLastChangedRefsToPushMirror),
+	// v226 -> v227
+	NewMigration("Add skip LFS column to push_mirror table", addSkipLFSToPushMirror),
 }
 
 // GetCurrentDBVersion returns the current db version
@@ -0,0 +1,32 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func createPushMirrorSyncLogTable(x *xorm.Engine) error {
+	type PushMirrorSyncLog struct {
+		ID           int64 `xorm:"pk autoincr"`
+		PushMirrorID int64 `xorm:"INDEX"`
+		RepoID       int64 `xorm:"INDEX"`
+		Success      bool  `xorm:"NOT NULL DEFAULT false"`
+		Duration     time.Duration
+		Error        string             `xorm:"TEXT"`
+		RefsChanged  string             `xorm:"TEXT"`
+		CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	}
+
+	if err := x.Sync2(new(PushMirrorSyncLog)); err != nil {
+		return fmt.Errorf("Sync2: %v", err)
+	}
+	return nil
+}
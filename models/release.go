@@ -450,6 +450,9 @@ func SaveOrUpdateTag(repo *repo_model.Repository, newRel *Release) error {
 		if rel.IsTag && newRel.PublisherID > 0 {
 			rel.PublisherID = newRel.PublisherID
 		}
+		if rel.IsTag {
+			rel.IsPrerelease = newRel.IsPrerelease
+		}
 		if _, err = db.GetEngine(db.DefaultContext).ID(rel.ID).AllCols().Update(rel); err != nil {
 			return fmt.Errorf("Update: %v", err)
 		}
@@ -25,7 +25,6 @@ import (
 	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/models/webhook"
-	"code.gitea.io/gitea/modules/lfs"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/options"
 	"code.gitea.io/gitea/modules/setting"
@@ -301,6 +300,7 @@ func updateRepoSize(e db.Engine, repo *repo_model.Repository) error {
 }
 
 // UpdateRepoSize updates the repository size, calculating it using util.GetDirectorySize
+// and adding the size of the LFS objects of the repository
 func UpdateRepoSize(ctx context.Context, repo *repo_model.Repository) error {
 	return updateRepoSize(db.GetEngine(ctx), repo)
 }
@@ -813,6 +813,7 @@ func DeleteRepository(doer *user_model.User, uid, repoID int64) error {
 		&ProtectedTag{RepoID: repoID},
 		&PullRequest{BaseRepoID: repoID},
 		&repo_model.PushMirror{RepoID: repoID},
+		&repo_model.PushMirrorSyncLog{RepoID: repoID},
 		&Release{RepoID: repoID},
 		&repo_model.RepoIndexerStatus{RepoID: repoID},
 		&repo_model.Redirect{RedirectRepoID: repoID},
@@ -870,25 +871,8 @@ func DeleteRepository(doer *user_model.User, uid, repoID int64) error {
 	}
 
 	// Remove LFS objects
-	var lfsObjects []*LFSMetaObject
-	if err = sess.Where("repository_id=?", repoID).Find(&lfsObjects); err != nil {
-		return err
-	}
-
-	lfsPaths := make([]string, 0, len(lfsObjects))
-	for _, v := range lfsObjects {
-		count, err := sess.Count(&LFSMetaObject{Pointer: lfs.Pointer{Oid: v.Oid}})
-		if err != nil {
-			return err
-		}
-		if count > 1 {
-			continue
-		}
-
-		lfsPaths = append(lfsPaths, v.RelativePath())
-	}
-
-	if _, err := sess.Delete(&LFSMetaObject{RepositoryID: repoID}); err != nil {
+	lfsPaths, err := removeLFSMetaObjectsByRepoID(ctx, repoID)
+	if err != nil {
 		return err
 	}
 
@@ -40,6 +40,9 @@ type Mirror struct {
 	LFS         bool   `xorm:"lfs_enabled NOT NULL DEFAULT false"`
 	LFSEndpoint string `xorm:"lfs_endpoint TEXT"`
 
+	// SyncsSinceGC counts the successful syncs since `git gc --auto` last ran, see setting.Mirror.AutoGCInterval
+	SyncsSinceGC int `xorm:"NOT NULL DEFAULT 0"`
+
 	Address string `xorm:"-"`
 }
 
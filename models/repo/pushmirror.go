@@ -6,18 +6,27 @@ package repo
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 
+	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
 // ErrPushMirrorNotExist mirror does not exist error
 var ErrPushMirrorNotExist = errors.New("PushMirror does not exist")
 
+// ErrPushMirrorRepoNotExist the repository of a push-mirror does not exist anymore error, see CleanupOrphanPushMirrors
+var ErrPushMirrorRepoNotExist = errors.New("the repository of the PushMirror does not exist")
+
+// ErrPushMirrorIntervalInvalid push mirror interval is below setting.Mirror.MinInterval error
+var ErrPushMirrorIntervalInvalid = errors.New("PushMirror interval is below the minimum interval")
+
 // PushMirror represents mirror information of a repository.
 type PushMirror struct {
 	ID         int64       `xorm:"pk autoincr"`
@@ -25,6 +34,34 @@ type PushMirror struct {
 	Repo       *Repository `xorm:"-"`
 	RemoteName string
 
+	// IsBundle mirrors write git bundles to setting.Mirror.BundlePath instead of pushing to a remote
+	IsBundle       bool   `xorm:"NOT NULL DEFAULT false"`
+	LastBundleRefs string `xorm:"TEXT"`
+	// BundleUploadURL is the destination the bundles are uploaded to after they have been written, e.g. a SFTP URL
+	BundleUploadURL string `xorm:"TEXT"`
+
+	// AllowUnrelatedHistory skips the setting.Mirror.CheckUnrelatedPushRemote check
+	AllowUnrelatedHistory bool `xorm:"NOT NULL DEFAULT false"`
+	// TagFilter is a glob, only matching tags are pushed if it is set
+	TagFilter string
+	// LFSOnly mirrors only push the LFS objects, the git data is mirrored by external tooling
+	LFSOnly bool `xorm:"NOT NULL DEFAULT false"`
+	// SkipLFS mirrors never push LFS objects, only the pointers in the git data, e.g. for remotes without LFS storage
+	SkipLFS bool `xorm:"NOT NULL DEFAULT false"`
+	// RefMapping contains "<local>:<remote>" lines, only the mapped refs are pushed to the remote names if it is set
+	RefMapping string `xorm:"TEXT"`
+	// PendingLFSObjects contains "<oid> <size>" lines of the LFS objects which failed to upload during the last sync
+	PendingLFSObjects string `xorm:"TEXT"`
+	// NotifyWebhookURL is a Slack or Discord compatible webhook notified when syncing fails or recovers,
+	// setting.Mirror.PushNotifyWebhookURL is used if it is empty
+	NotifyWebhookURL string `xorm:"TEXT"`
+	// SyncsSinceGC counts the successful syncs since `git gc --auto` last ran, see setting.Mirror.AutoGCInterval
+	SyncsSinceGC int `xorm:"NOT NULL DEFAULT 0"`
+	// TimeoutSeconds overrides setting.Git.Timeout.Mirror for the git and LFS operations of the sync if it is greater than 0
+	TimeoutSeconds int64 `xorm:"NOT NULL DEFAULT 0"`
+	// LastChangedRefs is the number of refs created, updated or deleted by the last successful sync
+	LastChangedRefs int `xorm:"NOT NULL DEFAULT 0"`
+
 	Interval       time.Duration
 	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
 	LastUpdateUnix timeutil.TimeStamp `xorm:"INDEX last_update"`
@@ -44,6 +81,7 @@ func (m *PushMirror) AfterLoad(session *xorm.Session) {
 	var err error
 	m.Repo, err = getRepositoryByID(session, m.RepoID)
 	if err != nil {
+		// m.Repo is nil then, users of the push-mirror have to check it, see ErrPushMirrorRepoNotExist
 		log.Error("getRepositoryByID[%d]: %v", m.ID, err)
 	}
 }
@@ -70,18 +108,73 @@ func UpdatePushMirror(m *PushMirror) error {
 	return err
 }
 
+// UpdatePushMirrorIntervalByRepoID updates the interval of all push-mirrors of a repository.
+// The next sync of a push-mirror is derived from its last update and interval, so it changes accordingly.
+func UpdatePushMirrorIntervalByRepoID(repoID int64, interval time.Duration) error {
+	return updatePushMirrorInterval(builder.Eq{"repo_id": repoID}, interval)
+}
+
+// UpdateAllPushMirrorIntervals updates the interval of all push-mirrors
+func UpdateAllPushMirrorIntervals(interval time.Duration) error {
+	return updatePushMirrorInterval(builder.NewCond(), interval)
+}
+
+func updatePushMirrorInterval(cond builder.Cond, interval time.Duration) error {
+	if interval != 0 && interval < setting.Mirror.MinInterval {
+		return fmt.Errorf("%w: %s < %s", ErrPushMirrorIntervalInvalid, interval, setting.Mirror.MinInterval)
+	}
+	_, err := db.GetEngine(db.DefaultContext).Where(cond).Cols("`interval`").Update(&PushMirror{Interval: interval})
+	return err
+}
+
 // DeletePushMirrorByID deletes a push-mirrors by ID
 func DeletePushMirrorByID(ID int64) error {
+	if _, err := db.GetEngine(db.DefaultContext).Delete(&PushMirrorSyncLog{PushMirrorID: ID}); err != nil {
+		return err
+	}
 	_, err := db.GetEngine(db.DefaultContext).ID(ID).Delete(&PushMirror{})
 	return err
 }
 
 // DeletePushMirrorsByRepoID deletes all push-mirrors by repoID
 func DeletePushMirrorsByRepoID(repoID int64) error {
+	if _, err := db.GetEngine(db.DefaultContext).Delete(&PushMirrorSyncLog{RepoID: repoID}); err != nil {
+		return err
+	}
 	_, err := db.GetEngine(db.DefaultContext).Delete(&PushMirror{RepoID: repoID})
 	return err
 }
 
+// CleanupOrphanPushMirrors deletes the push-mirrors and their sync logs whose repository doesn't exist anymore
+// and returns how many push-mirrors have been deleted. Their git remotes were removed together with the repository.
+func CleanupOrphanPushMirrors() (int, error) {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return 0, err
+	}
+	defer committer.Close()
+	sess := db.GetEngine(ctx)
+
+	ids := make([]int64, 0, 10)
+	if err := sess.Table("push_mirror").
+		Where(builder.NotIn("repo_id", builder.Select("id").From("repository"))).
+		Cols("id").
+		Find(&ids); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if _, err := sess.In("push_mirror_id", ids).Delete(&PushMirrorSyncLog{}); err != nil {
+		return 0, err
+	}
+	if _, err := sess.In("id", ids).Delete(&PushMirror{}); err != nil {
+		return 0, err
+	}
+	return len(ids), committer.Commit()
+}
+
 // GetPushMirrorByID returns push-mirror information.
 func GetPushMirrorByID(ID int64) (*PushMirror, error) {
 	m := &PushMirror{}
@@ -100,6 +193,16 @@ func GetPushMirrorsByRepoID(repoID int64) ([]*PushMirror, error) {
 	return mirrors, db.GetEngine(db.DefaultContext).Where("repo_id=?", repoID).Find(&mirrors)
 }
 
+// FindPushMirrors returns a page of all push-mirrors ordered by ID
+func FindPushMirrors(listOptions db.ListOptions) ([]*PushMirror, error) {
+	mirrors := make([]*PushMirror, 0, listOptions.PageSize)
+	sess := db.GetEngine(db.DefaultContext).OrderBy("id ASC")
+	if listOptions.Page != 0 {
+		sess = db.SetSessionPagination(sess, &listOptions)
+	}
+	return mirrors, sess.Find(&mirrors)
+}
+
 // PushMirrorsIterate iterates all push-mirror repositories.
 func PushMirrorsIterate(limit int, f func(idx int, bean interface{}) error) error {
 	return db.GetEngine(db.DefaultContext).
@@ -0,0 +1,74 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// PushMirrorSyncLog represents a sync attempt of a push-mirror
+type PushMirrorSyncLog struct {
+	ID           int64 `xorm:"pk autoincr"`
+	PushMirrorID int64 `xorm:"INDEX"`
+	RepoID       int64 `xorm:"INDEX"`
+	Success      bool  `xorm:"NOT NULL DEFAULT false"`
+	Duration     time.Duration
+	Error        string `xorm:"TEXT"`
+	// RefsChanged contains the names of the refs updated by the push, one per line
+	RefsChanged string             `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(PushMirrorSyncLog))
+}
+
+// InsertPushMirrorSyncLog inserts a sync log of a push-mirror and deletes the oldest
+// logs of the push-mirror exceeding the retention count
+func InsertPushMirrorSyncLog(l *PushMirrorSyncLog, retention int) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	e := db.GetEngine(ctx)
+	if _, err := e.Insert(l); err != nil {
+		return err
+	}
+
+	if retention > 0 {
+		ids := make([]int64, 0, retention)
+		if err := e.Table("push_mirror_sync_log").Cols("id").
+			Where("push_mirror_id = ?", l.PushMirrorID).
+			Desc("id").
+			Limit(retention).
+			Find(&ids); err != nil {
+			return err
+		}
+		if len(ids) == retention {
+			if _, err := e.Where("push_mirror_id = ?", l.PushMirrorID).
+				And("id < ?", ids[len(ids)-1]).
+				Delete(&PushMirrorSyncLog{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return committer.Commit()
+}
+
+// GetPushMirrorSyncLogs returns the most recent sync logs of a push-mirror, newest first
+func GetPushMirrorSyncLogs(pushMirrorID int64, limit int) ([]*PushMirrorSyncLog, error) {
+	logs := make([]*PushMirrorSyncLog, 0, limit)
+	sess := db.GetEngine(db.DefaultContext).Where("push_mirror_id = ?", pushMirrorID).Desc("id")
+	if limit > 0 {
+		sess = sess.Limit(limit)
+	}
+	return logs, sess.Find(&logs)
+}
@@ -5,10 +5,12 @@
 package repo
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 
 	"github.com/stretchr/testify/assert"
@@ -48,3 +50,105 @@ func TestPushMirrorsIterate(t *testing.T) {
 		return nil
 	})
 }
+
+func TestUpdatePushMirrorInterval(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	for _, m := range []*PushMirror{
+		{RepoID: 1, RemoteName: "test-1", Interval: time.Hour},
+		{RepoID: 1, RemoteName: "test-2", Interval: 0},
+		{RepoID: 2, RemoteName: "test-3", Interval: time.Hour},
+	} {
+		assert.NoError(t, InsertPushMirror(m))
+	}
+
+	assertIntervals := func(repoID int64, expected time.Duration) {
+		mirrors, err := GetPushMirrorsByRepoID(repoID)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, mirrors)
+		for _, m := range mirrors {
+			assert.Equal(t, expected, m.Interval, m.RemoteName)
+		}
+	}
+
+	assert.NoError(t, UpdatePushMirrorIntervalByRepoID(1, 12*time.Hour))
+	assertIntervals(1, 12*time.Hour)
+	assertIntervals(2, time.Hour)
+
+	assert.NoError(t, UpdateAllPushMirrorIntervals(0))
+	assertIntervals(1, 0)
+	assertIntervals(2, 0)
+
+	assert.ErrorIs(t, UpdatePushMirrorIntervalByRepoID(1, setting.Mirror.MinInterval-time.Second), ErrPushMirrorIntervalInvalid)
+	assert.ErrorIs(t, UpdateAllPushMirrorIntervals(time.Second), ErrPushMirrorIntervalInvalid)
+	assertIntervals(1, 0)
+	assertIntervals(2, 0)
+}
+
+func TestPushMirrorSyncLogRetention(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, InsertPushMirrorSyncLog(&PushMirrorSyncLog{
+			PushMirrorID: 1,
+			RepoID:       1,
+			Success:      i%2 == 0,
+			Error:        fmt.Sprintf("attempt %d", i),
+		}, 3))
+	}
+	// logs of other push-mirrors are not pruned
+	assert.NoError(t, InsertPushMirrorSyncLog(&PushMirrorSyncLog{PushMirrorID: 2, RepoID: 1, Error: "other"}, 3))
+
+	logs, err := GetPushMirrorSyncLogs(1, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, logs, 3) {
+		assert.Equal(t, "attempt 5", logs[0].Error)
+		assert.Equal(t, "attempt 4", logs[1].Error)
+		assert.True(t, logs[1].Success)
+		assert.Equal(t, "attempt 3", logs[2].Error)
+	}
+
+	logs, err = GetPushMirrorSyncLogs(1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+
+	logs, err = GetPushMirrorSyncLogs(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+
+	assert.NoError(t, DeletePushMirrorByID(1))
+	unittest.AssertNotExistsBean(t, &PushMirrorSyncLog{PushMirrorID: 1})
+	unittest.AssertExistsAndLoadBean(t, &PushMirrorSyncLog{PushMirrorID: 2})
+}
+
+func TestCleanupOrphanPushMirrors(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+	// push mirrors are not part of the fixtures, remove the ones of other tests without repository
+	_, err := CleanupOrphanPushMirrors()
+	assert.NoError(t, err)
+
+	valid := &PushMirror{RepoID: 1, RemoteName: "valid"}
+	orphan := &PushMirror{RepoID: 9999, RemoteName: "orphan"}
+	assert.NoError(t, InsertPushMirror(valid))
+	assert.NoError(t, InsertPushMirror(orphan))
+	assert.NoError(t, InsertPushMirrorSyncLog(&PushMirrorSyncLog{PushMirrorID: orphan.ID, RepoID: orphan.RepoID}, 0))
+
+	// the repository of an orphan can't be loaded anymore
+	m, err := GetPushMirrorByID(orphan.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, m.Repo)
+
+	count, err := CleanupOrphanPushMirrors()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = GetPushMirrorByID(orphan.ID)
+	assert.ErrorIs(t, err, ErrPushMirrorNotExist)
+	unittest.AssertNotExistsBean(t, &PushMirrorSyncLog{PushMirrorID: orphan.ID})
+	_, err = GetPushMirrorByID(valid.ID)
+	assert.NoError(t, err)
+
+	count, err = CleanupOrphanPushMirrors()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -177,6 +177,17 @@ func checkDBConsistency(ctx context.Context, logger log.Logger, autofix bool) er
 		// find access without repository
 		genericOrphanCheck("Access entries without existing repository",
 			"access", "repository", "access.repo_id=repository.id"),
+		// find push mirrors without repository
+		consistencyCheck{
+			Name: "Push mirrors without existing repository",
+			Counter: func() (int64, error) {
+				return models.CountOrphanedObjects("push_mirror", "repository", "push_mirror.repo_id=repository.id")
+			},
+			Fixer: func() (int64, error) {
+				count, err := repo_model.CleanupOrphanPushMirrors()
+				return int64(count), err
+			},
+		},
 	)
 
 	for _, c := range consistencyChecks {
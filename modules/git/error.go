@@ -176,3 +176,37 @@ func IsErrMoreThanOne(err error) bool {
 func (err *ErrMoreThanOne) Error() string {
 	return fmt.Sprintf("ErrMoreThanOne Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
 }
+
+// ErrPushAtomicNotSupported represents an error if an atomic push fails because the remote doesn't support atomic pushes
+type ErrPushAtomicNotSupported struct {
+	StdOut string
+	StdErr string
+	Err    error
+}
+
+// IsErrPushAtomicNotSupported checks if an error is a ErrPushAtomicNotSupported
+func IsErrPushAtomicNotSupported(err error) bool {
+	_, ok := err.(*ErrPushAtomicNotSupported)
+	return ok
+}
+
+func (err *ErrPushAtomicNotSupported) Error() string {
+	return fmt.Sprintf("ErrPushAtomicNotSupported Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
+}
+
+// ErrPushOptionsNotSupported represents an error if a push with push options fails because the remote doesn't support them
+type ErrPushOptionsNotSupported struct {
+	StdOut string
+	StdErr string
+	Err    error
+}
+
+// IsErrPushOptionsNotSupported checks if an error is a ErrPushOptionsNotSupported
+func IsErrPushOptionsNotSupported(err error) bool {
+	_, ok := err.(*ErrPushOptionsNotSupported)
+	return ok
+}
+
+func (err *ErrPushOptionsNotSupported) Error() string {
+	return fmt.Sprintf("ErrPushOptionsNotSupported Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
+}
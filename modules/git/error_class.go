@@ -0,0 +1,105 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrorClass is the kind of failure of a git command talking to a remote
+type ErrorClass int
+
+// enumerate all the error classes
+const (
+	ErrorClassUnknown ErrorClass = iota
+	ErrorClassAuth
+	ErrorClassNotFound
+	ErrorClassNetwork
+	ErrorClassNonFastForward
+)
+
+var errorClassNames = map[ErrorClass]string{
+	ErrorClassUnknown:        "unknown",
+	ErrorClassAuth:           "auth",
+	ErrorClassNotFound:       "notfound",
+	ErrorClassNetwork:        "network",
+	ErrorClassNonFastForward: "nonfastforward",
+}
+
+func (c ErrorClass) String() string {
+	return errorClassNames[c]
+}
+
+// IsRetryable returns whether running the command again may succeed without any change of the configuration.
+// Unknown errors are considered retryable.
+func (c ErrorClass) IsRetryable() bool {
+	return c == ErrorClassNetwork || c == ErrorClassUnknown
+}
+
+// Description returns a short explanation of the error class for users, it is empty for unknown errors
+func (c ErrorClass) Description() string {
+	switch c {
+	case ErrorClassAuth:
+		return "authentication with the remote failed, check the credentials"
+	case ErrorClassNotFound:
+		return "the remote repository does not exist or is not accessible"
+	case ErrorClassNetwork:
+		return "the remote could not be reached"
+	case ErrorClassNonFastForward:
+		return "the remote has commits which are missing locally"
+	}
+	return ""
+}
+
+// errorClassPatterns are the lower case stderr messages of git and git remote helpers which identify an error class.
+// They are checked in order, so a more specific message has to come before a more generic one.
+var errorClassPatterns = []struct {
+	class    ErrorClass
+	patterns []string
+}{
+	{ErrorClassNonFastForward, []string{"non-fast-forward", "[rejected]", "fetch first", "updates were rejected"}},
+	{ErrorClassAuth, []string{
+		"authentication failed", "could not read username", "could not read password", "terminal prompts disabled",
+		"permission denied", "access denied", "invalid username or password", "returned error: 401", "returned error: 403",
+	}},
+	{ErrorClassNotFound, []string{
+		"repository not found", "does not appear to be a git repository", "not a git repository",
+		"returned error: 404", "' not found", "does not exist",
+	}},
+	{ErrorClassNetwork, []string{
+		"could not resolve host", "connection refused", "connection reset", "connection timed out", "operation timed out",
+		"network is unreachable", "no route to host", "failed to connect", "the remote end hung up unexpectedly", "early eof",
+		"rpc failed", "ssl certificate", "ssl connect", "tls handshake", "gnutls",
+		"returned error: 500", "returned error: 502", "returned error: 503", "returned error: 504",
+	}},
+}
+
+// ClassifyGitError returns the class of an error of a git command talking to a remote, e.g. a clone, fetch or push,
+// based on its type and the stderr output it contains.
+func ClassifyGitError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+
+	var outOfDate *ErrPushOutOfDate
+	if errors.As(err, &outOfDate) {
+		return ErrorClassNonFastForward
+	}
+	if IsErrExecTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassNetwork
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, c := range errorClassPatterns {
+		for _, pattern := range c.patterns {
+			if strings.Contains(msg, pattern) {
+				return c.class
+			}
+		}
+	}
+	return ErrorClassUnknown
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyGitError(t *testing.T) {
+	exitErr := errors.New("exit status 128")
+	for _, tc := range []struct {
+		stderr string
+		class  ErrorClass
+	}{
+		{"fatal: Authentication failed for 'https://example.com/org/repo.git/'", ErrorClassAuth},
+		{"fatal: could not read Username for 'https://example.com': terminal prompts disabled", ErrorClassAuth},
+		{"git@example.com: Permission denied (publickey).\nfatal: Could not read from remote repository.", ErrorClassAuth},
+		{"remote: HTTP Basic: Access denied\nfatal: Authentication failed for 'https://gitlab.com/org/repo.git/'", ErrorClassAuth},
+		{"fatal: unable to access 'https://example.com/org/repo.git/': The requested URL returned error: 403", ErrorClassAuth},
+		{"remote: Repository not found.\nfatal: repository 'https://github.com/org/missing.git/' not found", ErrorClassNotFound},
+		{"fatal: '/tmp/missing.git' does not appear to be a git repository", ErrorClassNotFound},
+		{"fatal: repository '/tmp/missing.git' does not exist", ErrorClassNotFound},
+		{"fatal: unable to access 'https://example.com/org/repo.git/': The requested URL returned error: 404", ErrorClassNotFound},
+		{"fatal: unable to access 'https://example.com/org/repo.git/': Could not resolve host: example.com", ErrorClassNetwork},
+		{"fatal: unable to access 'https://example.com/org/repo.git/': Failed to connect to example.com port 443: Connection refused", ErrorClassNetwork},
+		{"ssh: connect to host example.com port 22: Connection timed out\nfatal: Could not read from remote repository.", ErrorClassNetwork},
+		{"error: RPC failed; curl 18 transfer closed with outstanding read data remaining\nfatal: early EOF", ErrorClassNetwork},
+		{"fatal: unable to access 'https://example.com/org/repo.git/': The requested URL returned error: 502", ErrorClassNetwork},
+		{" ! [rejected]        main -> main (fetch first)\nerror: failed to push some refs to 'https://example.com/org/repo.git'", ErrorClassNonFastForward},
+		{" ! [remote rejected] main -> main (pre-receive hook declined)", ErrorClassUnknown},
+		{"fatal: destination path 'repo.git' already exists and is not an empty directory.", ErrorClassUnknown},
+	} {
+		t.Run(tc.stderr, func(t *testing.T) {
+			assert.Equal(t, tc.class, ClassifyGitError(ConcatenateError(exitErr, tc.stderr)))
+		})
+	}
+
+	assert.Equal(t, ErrorClassNonFastForward, ClassifyGitError(fmt.Errorf("push: %w", &ErrPushOutOfDate{Err: exitErr})))
+	assert.Equal(t, ErrorClassNetwork, ClassifyGitError(ErrExecTimeout{}))
+	assert.Equal(t, ErrorClassNetwork, ClassifyGitError(fmt.Errorf("clone: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrorClassUnknown, ClassifyGitError(nil))
+}
+
+func TestErrorClassIsRetryable(t *testing.T) {
+	assert.True(t, ErrorClassNetwork.IsRetryable())
+	assert.True(t, ErrorClassUnknown.IsRetryable())
+	assert.False(t, ErrorClassAuth.IsRetryable())
+	assert.False(t, ErrorClassNotFound.IsRetryable())
+	assert.False(t, ErrorClassNonFastForward.IsRetryable())
+}
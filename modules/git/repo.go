@@ -180,39 +180,67 @@ func CloneWithArgs(ctx context.Context, from, to string, args []string, opts Clo
 
 // PushOptions options when push to remote
 type PushOptions struct {
-	Remote  string
-	Branch  string
-	Force   bool
-	Mirror  bool
+	Remote   string
+	Branch   string
+	Refspecs []string
+	Force    bool
+	Mirror   bool
+	Prune    bool
+	Atomic   bool
+	// Options are the push options sent to the hooks of the remote
+	Options []string
+	// Config are additional config values of the push in the form "name=value"
+	Config  []string
 	Env     []string
 	Timeout time.Duration
+	// Stderr additionally receives the stderr of git push, which lists the updated refs
+	Stderr io.Writer
 }
 
 // Push pushs local commits to given remote branch.
 func Push(ctx context.Context, repoPath string, opts PushOptions) error {
-	cmd := NewCommand(ctx, "push")
+	cmd := NewCommand(ctx)
+	for _, config := range opts.Config {
+		cmd.AddArguments("-c", config)
+	}
+	cmd.AddArguments("push")
 	if opts.Force {
 		cmd.AddArguments("-f")
 	}
 	if opts.Mirror {
 		cmd.AddArguments("--mirror")
 	}
+	if opts.Prune {
+		cmd.AddArguments("--prune")
+	}
+	if opts.Atomic {
+		cmd.AddArguments("--atomic")
+	}
+	for _, option := range opts.Options {
+		cmd.AddArguments("--push-option=" + option)
+	}
 	cmd.AddArguments("--", opts.Remote)
 	if len(opts.Branch) > 0 {
 		cmd.AddArguments(opts.Branch)
 	}
+	cmd.AddArguments(opts.Refspecs...)
 	var outbuf, errbuf strings.Builder
 
 	if opts.Timeout == 0 {
 		opts.Timeout = -1
 	}
 
+	var stderr io.Writer = &errbuf
+	if opts.Stderr != nil {
+		stderr = io.MultiWriter(&errbuf, opts.Stderr)
+	}
+
 	err := cmd.RunWithContext(&RunContext{
 		Env:     opts.Env,
 		Timeout: opts.Timeout,
 		Dir:     repoPath,
 		Stdout:  &outbuf,
-		Stderr:  &errbuf,
+		Stderr:  stderr,
 	})
 	if err != nil {
 		if strings.Contains(errbuf.String(), "non-fast-forward") {
@@ -229,6 +257,20 @@ func Push(ctx context.Context, repoPath string, opts PushOptions) error {
 			}
 			err.GenerateMessage()
 			return err
+		} else if strings.Contains(errbuf.String(), "does not support --atomic push") {
+			return &ErrPushAtomicNotSupported{
+				StdOut: outbuf.String(),
+				StdErr: errbuf.String(),
+				Err:    err,
+			}
+		} else if strings.Contains(errbuf.String(), "does not support push options") ||
+			// with nothing to push git may die of SIGPIPE instead when the remote hangs up on the push options
+			(len(opts.Options) > 0 && errbuf.Len() == 0 && strings.Contains(err.Error(), "signal: broken pipe")) {
+			return &ErrPushOptionsNotSupported{
+				StdOut: outbuf.String(),
+				StdErr: errbuf.String(),
+				Err:    err,
+			}
 		} else if strings.Contains(errbuf.String(), "matches more than one") {
 			err := &ErrMoreThanOne{
 				StdOut: outbuf.String(),
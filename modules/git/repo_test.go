@@ -7,6 +7,7 @@ package git
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,3 +31,50 @@ func TestRepoIsEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, isEmpty)
 }
+
+func TestPushAtomic(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+
+	remoteBranches := func(t *testing.T, remotePath string) []string {
+		remote, err := OpenRepository(remotePath)
+		assert.NoError(t, err)
+		defer remote.Close()
+		branches, _, err := remote.GetBranchNames(0, 0)
+		assert.NoError(t, err)
+		return branches
+	}
+
+	// the non-fast-forward update of master is rejected, the new branch would be fine
+	push := func(t *testing.T, atomic, advertiseAtomic bool) (string, error) {
+		remotePath := filepath.Join(t.TempDir(), "remote.git")
+		assert.NoError(t, Clone(DefaultContext, bareRepo1Path, remotePath, CloneRepoOptions{Bare: true, Quiet: true}))
+		if !advertiseAtomic {
+			_, err := NewCommand(DefaultContext, "config", "receive.advertiseAtomic", "false").RunInDir(remotePath)
+			assert.NoError(t, err)
+		}
+		return remotePath, Push(DefaultContext, bareRepo1Path, PushOptions{
+			Remote:   remotePath,
+			Refspecs: []string{"master~1:refs/heads/master", "master:refs/heads/new-branch"},
+			Atomic:   atomic,
+			Timeout:  time.Minute,
+		})
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		remotePath, err := push(t, true, true)
+		assert.Error(t, err)
+		assert.NotContains(t, remoteBranches(t, remotePath), "new-branch")
+	})
+
+	t.Run("NonAtomic", func(t *testing.T) {
+		remotePath, err := push(t, false, true)
+		assert.Error(t, err)
+		assert.Contains(t, remoteBranches(t, remotePath), "new-branch")
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		remotePath, err := push(t, true, false)
+		assert.True(t, IsErrPushAtomicNotSupported(err), "%v", err)
+		assert.NotContains(t, remoteBranches(t, remotePath), "new-branch")
+	})
+}
@@ -9,6 +9,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
 )
 
 // DownloadCallback gets called for every requested LFS object to process its content
@@ -31,3 +34,22 @@ func NewClient(endpoint *url.URL, httpTransport *http.Transport) Client {
 	}
 	return newHTTPClient(endpoint, httpTransport)
 }
+
+// BatchThrottle delays every batch of LFS objects which follows another one by setting.LFSClient.BatchDelay,
+// so rate-limited LFS servers aren't hit by the batches back-to-back
+type BatchThrottle struct {
+	started bool
+}
+
+// Wait blocks until the next batch may be sent, it returns the error of the context if it is done before
+func (t *BatchThrottle) Wait(ctx context.Context) error {
+	if t.started && setting.LFSClient.BatchDelay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(setting.LFSClient.BatchDelay):
+		}
+	}
+	t.started = true
+	return nil
+}
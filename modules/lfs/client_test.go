@@ -5,8 +5,17 @@
 package lfs
 
 import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,3 +29,68 @@ func TestNewClient(t *testing.T) {
 	c = NewClient(u, nil)
 	assert.IsType(t, &HTTPClient{}, c)
 }
+
+func TestNewClientTransport(t *testing.T) {
+	transport, err := NewHTTPTransport("")
+	assert.NoError(t, err)
+	assert.NotNil(t, transport.Proxy)
+
+	u, _ := url.Parse("https://test.com/lfs")
+	c := NewClient(u, transport)
+	if assert.IsType(t, &HTTPClient{}, c) {
+		assert.Same(t, transport, c.(*HTTPClient).client.Transport)
+	}
+}
+
+func TestNewHTTPTransportCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+
+	// the certificate of the server is not trusted by default
+	transport, err := NewHTTPTransport("")
+	assert.NoError(t, err)
+	_, err = NewClient(u, transport).(*HTTPClient).client.Get(server.URL)
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	transport, err = NewHTTPTransport(caFile)
+	assert.NoError(t, err)
+	resp, err := NewClient(u, transport).(*HTTPClient).client.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+
+	_, err = NewHTTPTransport(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(caFile, []byte("no certificate"), 0o600))
+	_, err = NewHTTPTransport(caFile)
+	assert.Error(t, err)
+}
+
+func TestBatchThrottle(t *testing.T) {
+	defer func(delay time.Duration) {
+		setting.LFSClient.BatchDelay = delay
+	}(setting.LFSClient.BatchDelay)
+	setting.LFSClient.BatchDelay = 50 * time.Millisecond
+
+	// only the batches following another one are delayed
+	var throttle BatchThrottle
+	start := time.Now()
+	assert.NoError(t, throttle.Wait(context.Background()))
+	assert.Less(t, time.Since(start), setting.LFSClient.BatchDelay)
+	assert.NoError(t, throttle.Wait(context.Background()))
+	assert.NoError(t, throttle.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 2*setting.LFSClient.BatchDelay)
+
+	// the wait ends with the context
+	setting.LFSClient.BatchDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, throttle.Wait(ctx), context.Canceled)
+}
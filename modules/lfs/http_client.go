@@ -7,10 +7,13 @@ package lfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
 	"code.gitea.io/gitea/modules/json"
@@ -33,6 +36,31 @@ func (c *HTTPClient) BatchSize() int {
 	return batchSize
 }
 
+// NewHTTPTransport returns a HTTP transport for LFS clients using the proxy settings.
+// If caFile is set, the certificates it contains are trusted in addition to the system ones.
+func NewHTTPTransport(caFile string) (*http.Transport, error) {
+	transport := &http.Transport{
+		Proxy: proxy.Proxy(),
+	}
+	if caFile == "" {
+		return transport, nil
+	}
+
+	certs, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(certs) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return transport, nil
+}
+
 func newHTTPClient(endpoint *url.URL, httpTransport *http.Transport) *HTTPClient {
 	if httpTransport == nil {
 		httpTransport = &http.Transport{
@@ -0,0 +1,91 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lfstest provides the LFS client mock and test repositories shared by the LFS tests of several packages
+package lfstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// MockClient is a lfs.Client which transfers the objects with DownloadFunc and UploadFunc and counts the transferred batches.
+// A transfer without a function fails.
+type MockClient struct {
+	MaxBatchSize int
+	Downloads    int
+	Uploads      int
+	DownloadFunc func(objects []lfs.Pointer, callback lfs.DownloadCallback) error
+	UploadFunc   func(objects []lfs.Pointer, callback lfs.UploadCallback) error
+}
+
+// BatchSize implements lfs.Client
+func (c *MockClient) BatchSize() int {
+	return c.MaxBatchSize
+}
+
+// Download implements lfs.Client
+func (c *MockClient) Download(ctx context.Context, objects []lfs.Pointer, callback lfs.DownloadCallback) error {
+	c.Downloads++
+	if c.DownloadFunc == nil {
+		return errors.New("not implemented")
+	}
+	return c.DownloadFunc(objects, callback)
+}
+
+// Upload implements lfs.Client
+func (c *MockClient) Upload(ctx context.Context, objects []lfs.Pointer, callback lfs.UploadCallback) error {
+	c.Uploads++
+	if c.UploadFunc == nil {
+		return errors.New("not implemented")
+	}
+	return c.UploadFunc(objects, callback)
+}
+
+// CreateRepository creates a repository with a commit adding a pointer for each of the contents,
+// it returns the path of the repository and the pointers
+func CreateRepository(t testing.TB, contents ...string) (string, []lfs.Pointer) {
+	repoPath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, repoPath, false))
+
+	pointers := make([]lfs.Pointer, 0, len(contents))
+	for i, content := range contents {
+		p, err := lfs.GeneratePointer(strings.NewReader(content))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(repoPath, fmt.Sprintf("object%d.bin", i)), []byte(p.StringContent()), 0o644))
+		pointers = append(pointers, p)
+	}
+
+	assert.NoError(t, git.AddChanges(repoPath, true))
+	signature := git.Signature{
+		Email: "test@example.com",
+		Name:  "test",
+		When:  time.Now(),
+	}
+	assert.NoError(t, git.CommitChanges(repoPath, git.CommitChangesOptions{
+		Committer: &signature,
+		Author:    &signature,
+		Message:   "Add LFS pointers",
+	}))
+	return repoPath, pointers
+}
+
+// IsSearchPointerBlobsRunning checks if a goroutine of lfs.SearchPointerBlobs is still running, e.g. to detect leaks
+func IsSearchPointerBlobsRunning() bool {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	return strings.Contains(string(buf[:n]), "lfs.SearchPointerBlobs")
+}
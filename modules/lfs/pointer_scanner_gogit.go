@@ -10,55 +10,102 @@ package lfs
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// SearchPointerBlobs scans the whole repository for LFS pointer files
+// SearchPointerBlobs scans the whole repository for LFS pointer files.
+// The blobs are read by setting.LFSClient.SearchPointerWorkers workers, so the pointers are sent in no particular order.
 func SearchPointerBlobs(ctx context.Context, repo *git.Repository, pointerChan chan<- PointerBlob, errChan chan<- error) {
 	gitRepo := repo.GoGitRepo()
 
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errOnce sync.Once
+	var firstErr error
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	workers := setting.LFSClient.SearchPointerWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	blobChan := make(chan *object.Blob)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for blob := range blobChan {
+				if err := readPointerBlob(searchCtx, blob, pointerChan); err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
 	err := func() error {
+		defer close(blobChan)
+
 		blobs, err := gitRepo.BlobObjects()
 		if err != nil {
 			return fmt.Errorf("lfs.SearchPointerBlobs BlobObjects: %w", err)
 		}
 
 		return blobs.ForEach(func(blob *object.Blob) error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-
 			if blob.Size > blobSizeCutoff {
 				return nil
 			}
 
-			reader, err := blob.Reader()
-			if err != nil {
-				return fmt.Errorf("lfs.SearchPointerBlobs blob.Reader: %w", err)
-			}
-			defer reader.Close()
-
-			pointer, _ := ReadPointer(reader)
-			if pointer.IsValid() {
-				pointerChan <- PointerBlob{Hash: blob.Hash.String(), Pointer: pointer}
+			select {
+			case blobChan <- blob:
+				return nil
+			case <-searchCtx.Done():
+				return searchCtx.Err()
 			}
-
-			return nil
 		})
 	}()
 	if err != nil {
+		setErr(err)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
 		select {
 		case <-ctx.Done():
 		default:
-			errChan <- err
+			errChan <- firstErr
 		}
 	}
 
 	close(pointerChan)
 	close(errChan)
 }
+
+func readPointerBlob(ctx context.Context, blob *object.Blob, pointerChan chan<- PointerBlob) error {
+	reader, err := blob.Reader()
+	if err != nil {
+		return fmt.Errorf("lfs.SearchPointerBlobs blob.Reader: %w", err)
+	}
+	defer reader.Close()
+
+	pointer, _ := ReadPointer(reader)
+	if pointer.IsValid() {
+		select {
+		case pointerChan <- PointerBlob{Hash: blob.Hash.String(), Pointer: pointer}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -17,27 +17,40 @@ import (
 
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/git/pipeline"
+	"code.gitea.io/gitea/modules/setting"
 )
 
-// SearchPointerBlobs scans the whole repository for LFS pointer files
+// SearchPointerBlobs scans the whole repository for LFS pointer files.
+// The blobs are read by setting.LFSClient.SearchPointerWorkers workers, so the pointers are sent in no particular order.
 func SearchPointerBlobs(ctx context.Context, repo *git.Repository, pointerChan chan<- PointerBlob, errChan chan<- error) {
 	basePath := repo.Path
 
 	catFileCheckReader, catFileCheckWriter := io.Pipe()
 	shasToBatchReader, shasToBatchWriter := io.Pipe()
-	catFileBatchReader, catFileBatchWriter := io.Pipe()
 
 	wg := sync.WaitGroup{}
-	wg.Add(4)
+	wg.Add(1)
 
 	// Create the go-routines in reverse order.
 
-	// 4. Take the output of cat-file --batch and check if each file in turn
-	// to see if they're pointers to files in the LFS store
-	go createPointerResultsFromCatFileBatch(ctx, catFileBatchReader, &wg, pointerChan)
-
-	// 3. Take the shas of the blobs and batch read them
-	go pipeline.CatFileBatch(ctx, shasToBatchReader, catFileBatchWriter, &wg, basePath)
+	// 3. Let every worker batch read its share of the shas and
+	// 4. check if each file in turn is a pointer to a file in the LFS store
+	shaReaders := []*io.PipeReader{shasToBatchReader}
+	if workers := setting.LFSClient.SearchPointerWorkers; workers > 1 {
+		shaReaders = make([]*io.PipeReader, workers)
+		shaWriters := make([]*io.PipeWriter, workers)
+		for i := range shaReaders {
+			shaReaders[i], shaWriters[i] = io.Pipe()
+		}
+		wg.Add(1)
+		go distributeShas(shasToBatchReader, shaWriters, &wg)
+	}
+	for _, shaReader := range shaReaders {
+		catFileBatchReader, catFileBatchWriter := io.Pipe()
+		wg.Add(2)
+		go createPointerResultsFromCatFileBatch(ctx, catFileBatchReader, &wg, pointerChan)
+		go pipeline.CatFileBatch(ctx, shaReader, catFileBatchWriter, &wg, basePath)
+	}
 
 	// 2. From the provided objects restrict to blobs <=1k
 	go pipeline.BlobsLessThan1024FromCatFileBatchCheck(catFileCheckReader, shasToBatchWriter, &wg)
@@ -46,11 +59,12 @@ func SearchPointerBlobs(ctx context.Context, repo *git.Repository, pointerChan c
 	if git.CheckGitVersionAtLeast("2.6.0") != nil {
 		revListReader, revListWriter := io.Pipe()
 		shasToCheckReader, shasToCheckWriter := io.Pipe()
-		wg.Add(2)
+		wg.Add(3)
 		go pipeline.CatFileBatchCheck(ctx, shasToCheckReader, catFileCheckWriter, &wg, basePath)
 		go pipeline.BlobsFromRevListObjects(revListReader, shasToCheckWriter, &wg)
 		go pipeline.RevListAllObjects(ctx, revListWriter, &wg, basePath, errChan)
 	} else {
+		wg.Add(1)
 		go pipeline.CatFileBatchCheckAllObjects(ctx, catFileCheckWriter, &wg, basePath, errChan)
 	}
 	wg.Wait()
@@ -59,6 +73,28 @@ func SearchPointerBlobs(ctx context.Context, repo *git.Repository, pointerChan c
 	close(errChan)
 }
 
+// distributeShas spreads the shas read line by line from shasReader over the writers of the workers
+func distributeShas(shasReader *io.PipeReader, shaWriters []*io.PipeWriter, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer shasReader.Close()
+
+	scanner := bufio.NewScanner(shasReader)
+	err := func() error {
+		for i := 0; scanner.Scan(); i++ {
+			if _, err := shaWriters[i%len(shaWriters)].Write([]byte(scanner.Text() + "\n")); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}()
+	if err != nil {
+		_ = shasReader.CloseWithError(err)
+	}
+	for _, w := range shaWriters {
+		_ = w.CloseWithError(err)
+	}
+}
+
 func createPointerResultsFromCatFileBatch(ctx context.Context, catFileBatchReader *io.PipeReader, wg *sync.WaitGroup, pointerChan chan<- PointerBlob) {
 	defer wg.Done()
 	defer catFileBatchReader.Close()
@@ -108,6 +144,10 @@ loop:
 			continue
 		}
 
-		pointerChan <- PointerBlob{Hash: sha, Pointer: pointer}
+		select {
+		case pointerChan <- PointerBlob{Hash: sha, Pointer: pointer}:
+		case <-ctx.Done():
+			break loop
+		}
 	}
 }
@@ -0,0 +1,73 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPointerBlobs(t *testing.T) {
+	assert.NoError(t, git.Init(git.DefaultContext))
+
+	defer func(workers int) {
+		setting.LFSClient.SearchPointerWorkers = workers
+	}(setting.LFSClient.SearchPointerWorkers)
+
+	repoPath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, repoPath, false))
+
+	expected := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		p, err := GeneratePointer(strings.NewReader(fmt.Sprintf("LFS object %d", i)))
+		assert.NoError(t, err)
+		expected = append(expected, p.Oid)
+		assert.NoError(t, os.WriteFile(filepath.Join(repoPath, fmt.Sprintf("object%d.bin", i)), []byte(p.StringContent()), 0o644))
+	}
+	// blobs which are no pointers are skipped
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("no pointer"), 0o644))
+
+	assert.NoError(t, git.AddChanges(repoPath, true))
+	signature := git.Signature{
+		Email: "test@example.com",
+		Name:  "test",
+		When:  time.Now(),
+	}
+	assert.NoError(t, git.CommitChanges(repoPath, git.CommitChangesOptions{
+		Committer: &signature,
+		Author:    &signature,
+		Message:   "Add LFS pointers",
+	}))
+
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repoPath)
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("Workers%d", workers), func(t *testing.T) {
+			setting.LFSClient.SearchPointerWorkers = workers
+
+			pointerChan := make(chan PointerBlob)
+			errChan := make(chan error, 1)
+			go SearchPointerBlobs(git.DefaultContext, gitRepo, pointerChan, errChan)
+
+			found := make([]string, 0, len(expected))
+			for pointerBlob := range pointerChan {
+				found = append(found, pointerBlob.Oid)
+			}
+			assert.NoError(t, <-errChan)
+			assert.ElementsMatch(t, expected, found)
+		})
+	}
+}
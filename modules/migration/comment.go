@@ -25,6 +25,16 @@ type Comment struct {
 	Updated     time.Time
 	Content     string
 	Reactions   []*Reaction
+	OriginalURL string `yaml:"original_url"`
+	// History contains the previous versions of the content, oldest first
+	History []*CommentVersion `yaml:"history,omitempty"`
+}
+
+// CommentVersion is a previous version of the content of a comment
+type CommentVersion struct {
+	Content string
+	// Edited is the time this version has been written
+	Edited time.Time
 }
 
 // GetExternalName ExternalUserMigrated interface
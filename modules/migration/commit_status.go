@@ -0,0 +1,26 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+import "time"
+
+// CommitStatus represents a status of a commit reported by an external CI system
+type CommitStatus struct {
+	SHA         string
+	State       string // pending, success, error, failure or warning
+	TargetURL   string `yaml:"target_url"`
+	Description string
+	Context     string
+	CreatorID   int64  `yaml:"creator_id"`
+	CreatorName string `yaml:"creator_name"`
+	Created     time.Time
+	Updated     time.Time
+}
+
+// GetExternalName ExternalUserMigrated interface
+func (s *CommitStatus) GetExternalName() string { return s.CreatorName }
+
+// GetExternalID ExternalUserMigrated interface
+func (s *CommitStatus) GetExternalID() int64 { return s.CreatorID }
@@ -0,0 +1,33 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+import "time"
+
+// Discussion is a discussion thread of a source which has discussions besides issues.
+// Discussions are migrated as labeled issues, the replies become their comments.
+type Discussion struct {
+	Number      int64
+	Category    string
+	PosterID    int64  `yaml:"poster_id"`
+	PosterName  string `yaml:"poster_name"`
+	PosterEmail string `yaml:"poster_email"`
+	Title       string
+	Content     string
+	State       string // closed, open
+	IsLocked    bool   `yaml:"is_locked"`
+	Created     time.Time
+	Updated     time.Time
+	Closed      *time.Time
+	OriginalURL string `yaml:"original_url"`
+	// Replies are the comments of the discussion in chronological order, their IssueIndex is the discussion number
+	Replies []*Comment
+}
+
+// GetExternalName ExternalUserMigrated interface
+func (d *Discussion) GetExternalName() string { return d.PosterName }
+
+// GetExternalID ExternalUserMigrated interface
+func (d *Discussion) GetExternalID() int64 { return d.PosterID }
@@ -7,6 +7,7 @@ package migration
 
 import (
 	"context"
+	"io"
 
 	"code.gitea.io/gitea/modules/structs"
 )
@@ -16,18 +17,35 @@ type Downloader interface {
 	SetContext(context.Context)
 	GetRepoInfo() (*Repository, error)
 	GetTopics() ([]string, error)
+	GetRepoAvatar() (io.ReadCloser, error)
 	GetMilestones() ([]*Milestone, error)
 	GetReleases() ([]*Release, error)
 	GetLabels() ([]*Label, error)
 	GetIssues(page, perPage int) ([]*Issue, bool, error)
+	GetDiscussions(page, perPage int) ([]*Discussion, bool, error)
 	GetComments(commentable Commentable) ([]*Comment, bool, error)
 	GetAllComments(page, perPage int) ([]*Comment, bool, error)
 	SupportGetRepoComments() bool
+	SupportedUnits() MigrateUnits
 	GetPullRequests(page, perPage int) ([]*PullRequest, bool, error)
 	GetReviews(reviewable Reviewable) ([]*Review, error)
+	GetCommitStatuses(sha string) ([]*CommitStatus, error)
+	GetCommentHistory(comment *Comment) ([]*CommentVersion, error)
+	GetTrackedTimes(commentable Commentable) ([]*TrackedTime, error)
+	GetIssueSubscribers(commentable Commentable) ([]*IssueSubscriber, error)
+	GetWebhooks() ([]*Webhook, error)
 	FormatCloneURL(opts MigrateOptions, remoteAddr string) (string, error)
 }
 
+// CountingDownloader is implemented by the downloaders which can count the items of the source without
+// downloading all of them, e.g. from the total count the API returns with the first page.
+// A method returns ErrNotSupported if the downloader can't count the items.
+type CountingDownloader interface {
+	CountIssues() (int, error)
+	CountPullRequests() (int, error)
+	CountComments() (int, error)
+}
+
 // DownloaderFactory defines an interface to match a downloader implementation and create a downloader
 type DownloaderFactory interface {
 	New(ctx context.Context, opts MigrateOptions) (Downloader, error)
@@ -24,3 +24,20 @@ func (err ErrNotSupported) Error() string {
 	}
 	return "not supported"
 }
+
+// ErrInvalidMigrateOptions represents an invalid or conflicting migration option
+type ErrInvalidMigrateOptions struct {
+	Option string
+	Reason string
+}
+
+// IsErrInvalidMigrateOptions checks if an error is an ErrInvalidMigrateOptions
+func IsErrInvalidMigrateOptions(err error) bool {
+	_, ok := err.(ErrInvalidMigrateOptions)
+	return ok
+}
+
+// Error return error message
+func (err ErrInvalidMigrateOptions) Error() string {
+	return fmt.Sprintf("invalid migration option '%s': %s", err.Option, err.Reason)
+}
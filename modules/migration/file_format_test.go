@@ -16,8 +16,14 @@ func TestMigrationJSON_IssueOK(t *testing.T) {
 	issues := make([]*Issue, 0, 10)
 	err := Load("file_format_testdata/issue_a.json", &issues, true)
 	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, "https://example.com/owner/repo/issues/1", issues[0].OriginalURL)
+	}
 	err = Load("file_format_testdata/issue_a.yml", &issues, true)
 	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, "https://example.com/owner/repo/issues/1", issues[0].OriginalURL)
+	}
 }
 
 func TestMigrationJSON_IssueFail(t *testing.T) {
@@ -19,6 +19,7 @@ type Issue struct {
 	Milestone    string            `json:"milestone"`
 	State        string            `json:"state"` // closed, open
 	IsLocked     bool              `yaml:"is_locked" json:"is_locked"`
+	LockReason   string            `yaml:"lock_reason" json:"lock_reason"`
 	Created      time.Time         `json:"created"`
 	Updated      time.Time         `json:"updated"`
 	Closed       *time.Time        `json:"closed"`
@@ -26,6 +27,7 @@ type Issue struct {
 	Reactions    []*Reaction       `json:"reactions"`
 	Assignees    []string          `json:"assignees"`
 	ForeignIndex int64             `json:"foreign_id"`
+	OriginalURL  string            `yaml:"original_url" json:"original_url"`
 	Context      DownloaderContext `yaml:"-"`
 }
 
@@ -0,0 +1,18 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+// IssueSubscriber represents a user who is subscribed to an issue or a pull request
+type IssueSubscriber struct {
+	IssueIndex int64  `yaml:"issue_index"`
+	UserID     int64  `yaml:"user_id"`
+	UserName   string `yaml:"user_name"`
+}
+
+// GetExternalName ExternalUserMigrated interface
+func (s *IssueSubscriber) GetExternalName() string { return s.UserName }
+
+// GetExternalID ExternalUserMigrated interface
+func (s *IssueSubscriber) GetExternalID() int64 { return s.UserID }
@@ -10,4 +10,6 @@ type Label struct {
 	Name        string `json:"name"`
 	Color       string `json:"color"`
 	Description string `json:"description"`
+	// Exclusive marks a scoped label named "scope/name", an issue can only have one label of each scope
+	Exclusive bool `json:"exclusive"`
 }
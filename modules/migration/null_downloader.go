@@ -6,6 +6,7 @@ package migration
 
 import (
 	"context"
+	"io"
 	"net/url"
 )
 
@@ -19,52 +20,87 @@ func (n NullDownloader) SetContext(_ context.Context) {}
 
 // GetRepoInfo returns a repository information
 func (n NullDownloader) GetRepoInfo() (*Repository, error) {
-	return nil, &ErrNotSupported{Entity: "RepoInfo"}
+	return nil, ErrNotSupported{Entity: "RepoInfo"}
 }
 
 // GetTopics return repository topics
 func (n NullDownloader) GetTopics() ([]string, error) {
-	return nil, &ErrNotSupported{Entity: "Topics"}
+	return nil, ErrNotSupported{Entity: "Topics"}
+}
+
+// GetRepoAvatar returns the avatar image of the repository
+func (n NullDownloader) GetRepoAvatar() (io.ReadCloser, error) {
+	return nil, ErrNotSupported{Entity: "RepoAvatar"}
+}
+
+// GetWebhooks returns the webhooks of the repository
+func (n NullDownloader) GetWebhooks() ([]*Webhook, error) {
+	return nil, ErrNotSupported{Entity: "Webhooks"}
 }
 
 // GetMilestones returns milestones
 func (n NullDownloader) GetMilestones() ([]*Milestone, error) {
-	return nil, &ErrNotSupported{Entity: "Milestones"}
+	return nil, ErrNotSupported{Entity: "Milestones"}
 }
 
 // GetReleases returns releases
 func (n NullDownloader) GetReleases() ([]*Release, error) {
-	return nil, &ErrNotSupported{Entity: "Releases"}
+	return nil, ErrNotSupported{Entity: "Releases"}
 }
 
 // GetLabels returns labels
 func (n NullDownloader) GetLabels() ([]*Label, error) {
-	return nil, &ErrNotSupported{Entity: "Labels"}
+	return nil, ErrNotSupported{Entity: "Labels"}
 }
 
 // GetIssues returns issues according start and limit
 func (n NullDownloader) GetIssues(page, perPage int) ([]*Issue, bool, error) {
-	return nil, false, &ErrNotSupported{Entity: "Issues"}
+	return nil, false, ErrNotSupported{Entity: "Issues"}
 }
 
 // GetComments returns comments of an issue or PR
 func (n NullDownloader) GetComments(commentable Commentable) ([]*Comment, bool, error) {
-	return nil, false, &ErrNotSupported{Entity: "Comments"}
+	return nil, false, ErrNotSupported{Entity: "Comments"}
 }
 
 // GetAllComments returns paginated comments
 func (n NullDownloader) GetAllComments(page, perPage int) ([]*Comment, bool, error) {
-	return nil, false, &ErrNotSupported{Entity: "AllComments"}
+	return nil, false, ErrNotSupported{Entity: "AllComments"}
+}
+
+// GetDiscussions returns discussions according page and perPage
+func (n NullDownloader) GetDiscussions(page, perPage int) ([]*Discussion, bool, error) {
+	return nil, false, ErrNotSupported{Entity: "Discussions"}
 }
 
 // GetPullRequests returns pull requests according page and perPage
 func (n NullDownloader) GetPullRequests(page, perPage int) ([]*PullRequest, bool, error) {
-	return nil, false, &ErrNotSupported{Entity: "PullRequests"}
+	return nil, false, ErrNotSupported{Entity: "PullRequests"}
 }
 
 // GetReviews returns pull requests review
 func (n NullDownloader) GetReviews(reviewable Reviewable) ([]*Review, error) {
-	return nil, &ErrNotSupported{Entity: "Reviews"}
+	return nil, ErrNotSupported{Entity: "Reviews"}
+}
+
+// GetCommentHistory returns the previous versions of a comment
+func (n NullDownloader) GetCommentHistory(comment *Comment) ([]*CommentVersion, error) {
+	return nil, ErrNotSupported{Entity: "CommentHistory"}
+}
+
+// GetCommitStatuses returns the statuses of a commit
+func (n NullDownloader) GetCommitStatuses(sha string) ([]*CommitStatus, error) {
+	return nil, ErrNotSupported{Entity: "CommitStatuses"}
+}
+
+// GetTrackedTimes returns the time spent on an issue or a pull request
+func (n NullDownloader) GetTrackedTimes(commentable Commentable) ([]*TrackedTime, error) {
+	return nil, ErrNotSupported{Entity: "TrackedTimes"}
+}
+
+// GetIssueSubscribers returns the users subscribed to an issue or a pull request
+func (n NullDownloader) GetIssueSubscribers(commentable Commentable) ([]*IssueSubscriber, error) {
+	return nil, ErrNotSupported{Entity: "IssueSubscribers"}
 }
 
 // FormatCloneURL add authentication into remote URLs
@@ -87,3 +123,9 @@ func (n NullDownloader) FormatCloneURL(opts MigrateOptions, remoteAddr string) (
 func (n NullDownloader) SupportGetRepoComments() bool {
 	return false
 }
+
+// SupportedUnits returns the units the downloader may be able to migrate,
+// the methods of the unsupported data of the others return ErrNotSupported
+func (n NullDownloader) SupportedUnits() MigrateUnits {
+	return MigrateUnitsAll
+}
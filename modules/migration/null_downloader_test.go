@@ -0,0 +1,64 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullDownloaderFormatCloneURL(t *testing.T) {
+	const remoteAddr = "https://example.com/owner/repo.git"
+	var downloader NullDownloader
+
+	cloneURL, err := downloader.FormatCloneURL(MigrateOptions{}, remoteAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteAddr, cloneURL)
+
+	for _, opts := range []MigrateOptions{
+		{AuthUsername: "user@example.com", AuthPassword: "p@ss:w/o#r%d"},
+		{AuthUsername: "us:er/#", AuthPassword: "%41@"},
+		{AuthUsername: "user", AuthToken: "t@k:e/n#%"},
+	} {
+		cloneURL, err := downloader.FormatCloneURL(opts, remoteAddr)
+		assert.NoError(t, err)
+
+		u, err := url.Parse(cloneURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com", u.Host)
+		assert.Equal(t, "/owner/repo.git", u.Path)
+		assert.Empty(t, u.Fragment)
+
+		password, _ := u.User.Password()
+		if opts.AuthToken != "" {
+			assert.Equal(t, "oauth2", u.User.Username())
+			assert.Equal(t, opts.AuthToken, password)
+		} else {
+			assert.Equal(t, opts.AuthUsername, u.User.Username())
+			assert.Equal(t, opts.AuthPassword, password)
+		}
+	}
+}
+
+func TestNullDownloaderFormatCloneURLIPv6(t *testing.T) {
+	const remoteAddr = "https://[2001:db8::1]:443/org/repo.git"
+	var downloader NullDownloader
+
+	cloneURL, err := downloader.FormatCloneURL(MigrateOptions{AuthUsername: "user", AuthPassword: "p@ss"}, remoteAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://user:p%40ss@[2001:db8::1]:443/org/repo.git", cloneURL)
+
+	u, err := url.Parse(cloneURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:443", u.Host)
+	assert.Equal(t, "2001:db8::1", u.Hostname())
+	assert.Equal(t, "443", u.Port())
+
+	cloneURL, err = downloader.FormatCloneURL(MigrateOptions{AuthToken: "token"}, "https://[2001:db8::1]/org/repo.git")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://oauth2:token@[2001:db8::1]/org/repo.git", cloneURL)
+}
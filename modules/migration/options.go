@@ -5,7 +5,14 @@
 
 package migration
 
-import "code.gitea.io/gitea/modules/structs"
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
+)
 
 // MigrateOptions defines the way a repository gets migrated
 // this is for internal usage by migrations module and func who interact with it
@@ -39,4 +46,119 @@ type MigrateOptions struct {
 	ReleaseAssets   bool
 	MigrateToRepoID int64
 	MirrorInterval  string `json:"mirror_interval"`
+	// RenameDefaultBranch renames the default branch of the migrated repository, e.g. to "main"
+	RenameDefaultBranch string
+	// MergeIntoExisting imports the issue tracker data into the existing repository
+	// MigrateToRepoID without touching its git data
+	MergeIntoExisting bool
+	// CommentHistory migrates the edit history of comments, if the source exposes it
+	CommentHistory bool
+	// TrackedTimes migrates the time spent on issues and pull requests, if the source exposes it
+	TrackedTimes bool
+	// IssueSubscribers migrates the subscriptions of the users mapped to local users to issues and pull requests,
+	// if the source exposes them
+	IssueSubscribers bool
+	// Discussions migrates the discussions of the source as issues labeled with their category, if the source has them
+	Discussions bool
+	// Webhooks recreates the webhooks of the source with new secrets, if the source exposes them
+	Webhooks bool
+	// ArchiveIfSourceArchived archives the migrated repository if the source repository is archived
+	ArchiveIfSourceArchived bool
+	// LockIssuesIfSourceArchived imports the issues and pull requests of an archived source repository locked
+	LockIssuesIfSourceArchived bool
+	// CloneDepth creates a shallow clone with the given number of commits if greater than zero,
+	// tags pointing outside of the cloned history are not synchronized to releases
+	CloneDepth int
+	// LFSMaxFileSize overrides the maximum size of downloaded LFS objects if greater than zero,
+	// it is capped by setting.Migrations.LFSMaxFileSizeCeiling
+	LFSMaxFileSize int64
+	// ArchivePath initializes the repository from a local git bundle, tarball of a bare repository
+	// or zip file of a working tree instead of cloning CloneAddr, e.g. for offline imports
+	ArchivePath string `json:"-"`
+	// TargetOwnerName and TargetRepoName override the owner and the name of the migrated repository,
+	// e.g. to rename it in an organization to organization migration
+	TargetOwnerName string
+	TargetRepoName  string
+	// SkipReleaseSync neither imports the releases of the source nor synchronizes the git tags to releases,
+	// e.g. for releases which are managed externally. It overrides Releases and ReleaseAssets.
+	SkipReleaseSync bool
+	// SubdirectoryPrefix moves the files of every migrated branch into this directory with a subtree merge commit
+	// on top of the original history, e.g. to consolidate several repositories. Tags keep pointing to the original commits.
+	SubdirectoryPrefix string
+	// Units selects exactly the data migrated besides the git data, e.g. issues and releases without pull requests.
+	// If it is empty, the boolean flags like Issues select the data, MigrateUnits returns the effective selection.
+	Units MigrateUnits `json:"units,omitempty"`
+}
+
+// Validate checks the options for invalid values and conflicting flags before a migration is started
+func (opts MigrateOptions) Validate() error {
+	if len(opts.RepoName) == 0 {
+		return ErrInvalidMigrateOptions{Option: "repo_name", Reason: "must not be empty"}
+	}
+	if len(opts.CloneAddr) == 0 && len(opts.ArchivePath) == 0 {
+		return ErrInvalidMigrateOptions{Option: "clone_addr", Reason: "must not be empty"}
+	}
+	if _, err := url.Parse(opts.CloneAddr); err != nil {
+		return ErrInvalidMigrateOptions{Option: "clone_addr", Reason: "is not a valid URL"}
+	}
+	if opts.LFS && len(opts.LFSEndpoint) > 0 {
+		if u, err := url.Parse(opts.LFSEndpoint); err != nil || len(u.Scheme) == 0 {
+			return ErrInvalidMigrateOptions{Option: "lfs_endpoint", Reason: "is not a valid URL"}
+		}
+	}
+
+	if opts.Mirror {
+		if _, err := setting.ParseMirrorInterval(opts.MirrorInterval); err != nil {
+			return ErrInvalidMigrateOptions{Option: "mirror_interval", Reason: err.Error()}
+		}
+	}
+
+	if opts.Units&^MigrateUnitsAll != 0 {
+		return ErrInvalidMigrateOptions{Option: "units", Reason: "contains unknown units"}
+	}
+	if opts.LFSMaxFileSize < 0 {
+		return ErrInvalidMigrateOptions{Option: "lfs_max_file_size", Reason: "must not be negative"}
+	}
+	if opts.CloneDepth < 0 {
+		return ErrInvalidMigrateOptions{Option: "clone_depth", Reason: "must not be negative"}
+	}
+	if opts.MergeIntoExisting {
+		if opts.MigrateToRepoID <= 0 {
+			return ErrInvalidMigrateOptions{Option: "merge_into_existing", Reason: "requires an existing repository"}
+		}
+		if opts.Mirror {
+			return ErrInvalidMigrateOptions{Option: "merge_into_existing", Reason: "cannot be combined with a mirror"}
+		}
+	}
+	if len(opts.ArchivePath) > 0 {
+		if opts.Mirror {
+			return ErrInvalidMigrateOptions{Option: "archive_path", Reason: "cannot be combined with a mirror"}
+		}
+		if opts.CloneDepth > 0 {
+			return ErrInvalidMigrateOptions{Option: "clone_depth", Reason: "cannot be combined with an archive"}
+		}
+		if opts.LFS && len(opts.LFSEndpoint) == 0 && len(opts.CloneAddr) == 0 {
+			return ErrInvalidMigrateOptions{Option: "lfs_endpoint", Reason: "is required to download the LFS objects of an archive"}
+		}
+	}
+	if opts.Mirror && opts.SkipReleaseSync {
+		// updating the mirror synchronizes the tags to releases
+		return ErrInvalidMigrateOptions{Option: "skip_release_sync", Reason: "cannot be combined with a mirror"}
+	}
+	if len(opts.SubdirectoryPrefix) > 0 {
+		if opts.Mirror {
+			// updating the mirror would restore the original branches
+			return ErrInvalidMigrateOptions{Option: "subdirectory_prefix", Reason: "cannot be combined with a mirror"}
+		}
+		prefix := path.Clean(strings.Trim(opts.SubdirectoryPrefix, "/"))
+		if prefix == "." || prefix == ".." || strings.HasPrefix(prefix, "../") || strings.Contains(opts.SubdirectoryPrefix, "\\") ||
+			prefix == ".git" || strings.HasPrefix(prefix, ".git/") {
+			return ErrInvalidMigrateOptions{Option: "subdirectory_prefix", Reason: "is not a valid directory"}
+		}
+	}
+	if opts.Mirror && opts.CloneDepth > 0 {
+		// updating the mirror would fetch the whole history anyway
+		return ErrInvalidMigrateOptions{Option: "clone_depth", Reason: "cannot be combined with a mirror"}
+	}
+	return nil
 }
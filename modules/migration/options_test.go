@@ -0,0 +1,102 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateOptionsValidate(t *testing.T) {
+	defer func(minInterval time.Duration) {
+		setting.Mirror.MinInterval = minInterval
+	}(setting.Mirror.MinInterval)
+	setting.Mirror.MinInterval = 10 * time.Minute
+
+	valid := func(modify func(opts *MigrateOptions)) MigrateOptions {
+		opts := MigrateOptions{
+			CloneAddr: "https://example.com/owner/repo.git",
+			RepoName:  "repo",
+		}
+		modify(&opts)
+		return opts
+	}
+
+	for _, opts := range []MigrateOptions{
+		valid(func(opts *MigrateOptions) {}),
+		valid(func(opts *MigrateOptions) { opts.Mirror = true }),
+		valid(func(opts *MigrateOptions) { opts.Mirror, opts.MirrorInterval = true, "0" }),
+		valid(func(opts *MigrateOptions) { opts.Mirror, opts.MirrorInterval = true, "1h" }),
+		valid(func(opts *MigrateOptions) { opts.LFS, opts.LFSEndpoint = true, "https://example.com/lfs" }),
+		valid(func(opts *MigrateOptions) { opts.CloneDepth = 1 }),
+		valid(func(opts *MigrateOptions) { opts.MergeIntoExisting, opts.MigrateToRepoID = true, 1 }),
+		valid(func(opts *MigrateOptions) { opts.CloneAddr, opts.ArchivePath = "", "/tmp/repo.tar.gz" }),
+		valid(func(opts *MigrateOptions) { opts.Releases, opts.SkipReleaseSync = true, true }),
+		valid(func(opts *MigrateOptions) { opts.SubdirectoryPrefix = "libs/repo/" }),
+	} {
+		assert.NoError(t, opts.Validate())
+	}
+
+	for option, opts := range map[string]MigrateOptions{
+		"repo_name":           valid(func(opts *MigrateOptions) { opts.RepoName = "" }),
+		"clone_addr":          valid(func(opts *MigrateOptions) { opts.CloneAddr = "https://exa mple.com/%zz" }),
+		"lfs_endpoint":        valid(func(opts *MigrateOptions) { opts.LFS, opts.LFSEndpoint = true, "example.com/lfs" }),
+		"mirror_interval":     valid(func(opts *MigrateOptions) { opts.Mirror, opts.MirrorInterval = true, "5m" }),
+		"units":               valid(func(opts *MigrateOptions) { opts.Units = MigrateUnitsAll + 1 }),
+		"clone_depth":         valid(func(opts *MigrateOptions) { opts.Mirror, opts.CloneDepth = true, 1 }),
+		"merge_into_existing": valid(func(opts *MigrateOptions) { opts.MergeIntoExisting = true }),
+		"archive_path":        valid(func(opts *MigrateOptions) { opts.Mirror, opts.ArchivePath = true, "/tmp/repo.tar.gz" }),
+		"skip_release_sync":   valid(func(opts *MigrateOptions) { opts.Mirror, opts.SkipReleaseSync = true, true }),
+		"subdirectory_prefix": valid(func(opts *MigrateOptions) { opts.Mirror, opts.SubdirectoryPrefix = true, "libs" }),
+	} {
+		err := opts.Validate()
+		assert.True(t, IsErrInvalidMigrateOptions(err), "%s: %v", option, err)
+		assert.Equal(t, option, err.(ErrInvalidMigrateOptions).Option)
+	}
+
+	for _, interval := range []string{"-1h", "daily"} {
+		err := valid(func(opts *MigrateOptions) { opts.Mirror, opts.MirrorInterval = true, interval }).Validate()
+		assert.True(t, IsErrInvalidMigrateOptions(err), interval)
+	}
+	for _, prefix := range []string{"/", ".", "../outside", "libs/../..", ".git/hooks", "libs\\repo"} {
+		err := valid(func(opts *MigrateOptions) { opts.SubdirectoryPrefix = prefix }).Validate()
+		assert.True(t, IsErrInvalidMigrateOptions(err), prefix)
+	}
+	assert.True(t, IsErrInvalidMigrateOptions(valid(func(opts *MigrateOptions) { opts.CloneDepth = -1 }).Validate()))
+	assert.True(t, IsErrInvalidMigrateOptions(valid(func(opts *MigrateOptions) {
+		opts.Mirror, opts.MergeIntoExisting, opts.MigrateToRepoID = true, true, 1
+	}).Validate()))
+	assert.True(t, IsErrInvalidMigrateOptions(valid(func(opts *MigrateOptions) {
+		opts.CloneAddr, opts.ArchivePath, opts.LFS = "", "/tmp/repo.tar.gz", true
+	}).Validate()))
+}
+
+func TestMigrateUnits(t *testing.T) {
+	opts := MigrateOptions{Issues: true, Comments: true, Releases: true}
+	assert.Equal(t, MigrateUnitIssues|MigrateUnitComments|MigrateUnitReleases, opts.MigrateUnits())
+
+	// the selected units take precedence over the flags
+	opts.Units = MigrateUnitPullRequests
+	assert.Equal(t, MigrateUnitPullRequests, opts.MigrateUnits())
+
+	opts.SetMigrateUnits(MigrateUnitIssues | MigrateUnitReleases | MigrateUnitReleaseAssets)
+	assert.Equal(t, MigrateUnitIssues|MigrateUnitReleases|MigrateUnitReleaseAssets, opts.MigrateUnits())
+	assert.True(t, opts.Issues)
+	assert.True(t, opts.Releases)
+	assert.True(t, opts.ReleaseAssets)
+	assert.False(t, opts.Comments)
+	assert.False(t, opts.PullRequests)
+
+	assert.True(t, opts.MigrateUnits().Has(MigrateUnitIssues|MigrateUnitReleases))
+	assert.False(t, opts.MigrateUnits().Has(MigrateUnitIssues|MigrateUnitPullRequests))
+
+	opts.SetMigrateUnits(MigrateUnitsAll)
+	assert.True(t, opts.Wiki)
+	assert.True(t, opts.Webhooks)
+}
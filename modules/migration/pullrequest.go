@@ -33,9 +33,11 @@ type PullRequest struct {
 	Head           PullRequestBranch
 	Base           PullRequestBranch
 	Assignees      []string
-	IsLocked       bool `yaml:"is_locked"`
+	IsLocked       bool   `yaml:"is_locked"`
+	LockReason     string `yaml:"lock_reason"`
 	Reactions      []*Reaction
 	ForeignIndex   int64
+	OriginalURL    string            `yaml:"original_url"`
 	Context        DownloaderContext `yaml:"-"`
 }
 
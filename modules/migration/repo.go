@@ -11,8 +11,21 @@ type Repository struct {
 	Owner         string
 	IsPrivate     bool `yaml:"is_private"`
 	IsMirror      bool `yaml:"is_mirror"`
+	IsArchived    bool `yaml:"is_archived"`
 	Description   string
 	CloneURL      string `yaml:"clone_url"`
 	OriginalURL   string `yaml:"original_url"`
 	DefaultBranch string
+	// MergeSettings are nil if the source doesn't provide them, the migrated repository keeps the defaults of Gitea then
+	MergeSettings *MergeSettings `yaml:"merge_settings,omitempty"`
+}
+
+// MergeSettings defines how the pull requests of a repository may be merged
+type MergeSettings struct {
+	AllowMerge             bool   `yaml:"allow_merge"`
+	AllowRebase            bool   `yaml:"allow_rebase"`
+	AllowRebaseMerge       bool   `yaml:"allow_rebase_merge"`
+	AllowSquash            bool   `yaml:"allow_squash"`
+	DefaultMergeStyle      string `yaml:"default_merge_style"` // merge, rebase, rebase-merge or squash
+	DeleteBranchAfterMerge bool   `yaml:"delete_branch_after_merge"`
 }
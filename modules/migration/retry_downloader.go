@@ -6,6 +6,7 @@ package migration
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -86,6 +87,36 @@ func (d *RetryDownloader) GetTopics() ([]string, error) {
 	return topics, err
 }
 
+// GetRepoAvatar returns a repository's avatar with retry
+func (d *RetryDownloader) GetRepoAvatar() (io.ReadCloser, error) {
+	var (
+		avatar io.ReadCloser
+		err    error
+	)
+
+	err = d.retry(func() error {
+		avatar, err = d.Downloader.GetRepoAvatar()
+		return err
+	})
+
+	return avatar, err
+}
+
+// GetWebhooks returns a repository's webhooks with retry
+func (d *RetryDownloader) GetWebhooks() ([]*Webhook, error) {
+	var (
+		webhooks []*Webhook
+		err      error
+	)
+
+	err = d.retry(func() error {
+		webhooks, err = d.Downloader.GetWebhooks()
+		return err
+	})
+
+	return webhooks, err
+}
+
 // GetMilestones returns a repository's milestones with retry
 func (d *RetryDownloader) GetMilestones() ([]*Milestone, error) {
 	var (
@@ -147,6 +178,22 @@ func (d *RetryDownloader) GetIssues(page, perPage int) ([]*Issue, bool, error) {
 	return issues, isEnd, err
 }
 
+// GetDiscussions returns a repository's discussions with retry
+func (d *RetryDownloader) GetDiscussions(page, perPage int) ([]*Discussion, bool, error) {
+	var (
+		discussions []*Discussion
+		isEnd       bool
+		err         error
+	)
+
+	err = d.retry(func() error {
+		discussions, isEnd, err = d.Downloader.GetDiscussions(page, perPage)
+		return err
+	})
+
+	return discussions, isEnd, err
+}
+
 // GetComments returns a repository's comments with retry
 func (d *RetryDownloader) GetComments(commentable Commentable) ([]*Comment, bool, error) {
 	var (
@@ -193,3 +240,63 @@ func (d *RetryDownloader) GetReviews(reviewable Reviewable) ([]*Review, error) {
 
 	return reviews, err
 }
+
+// GetCommitStatuses returns the statuses of a commit
+func (d *RetryDownloader) GetCommitStatuses(sha string) ([]*CommitStatus, error) {
+	var (
+		statuses []*CommitStatus
+		err      error
+	)
+
+	err = d.retry(func() error {
+		statuses, err = d.Downloader.GetCommitStatuses(sha)
+		return err
+	})
+
+	return statuses, err
+}
+
+// GetCommentHistory returns the previous versions of a comment
+func (d *RetryDownloader) GetCommentHistory(comment *Comment) ([]*CommentVersion, error) {
+	var (
+		history []*CommentVersion
+		err     error
+	)
+
+	err = d.retry(func() error {
+		history, err = d.Downloader.GetCommentHistory(comment)
+		return err
+	})
+
+	return history, err
+}
+
+// GetTrackedTimes returns the time spent on an issue or a pull request
+func (d *RetryDownloader) GetTrackedTimes(commentable Commentable) ([]*TrackedTime, error) {
+	var (
+		times []*TrackedTime
+		err   error
+	)
+
+	err = d.retry(func() error {
+		times, err = d.Downloader.GetTrackedTimes(commentable)
+		return err
+	})
+
+	return times, err
+}
+
+// GetIssueSubscribers returns the users subscribed to an issue or a pull request
+func (d *RetryDownloader) GetIssueSubscribers(commentable Commentable) ([]*IssueSubscriber, error) {
+	var (
+		subscribers []*IssueSubscriber
+		err         error
+	)
+
+	err = d.retry(func() error {
+		subscribers, err = d.Downloader.GetIssueSubscribers(commentable)
+		return err
+	})
+
+	return subscribers, err
+}
@@ -54,4 +54,19 @@ type ReviewComment struct {
 	Reactions []*Reaction
 	CreatedAt time.Time `yaml:"created_at"`
 	UpdatedAt time.Time `yaml:"updated_at"`
+	// Resolved is set on the first comment of a thread which has been marked as resolved
+	Resolved     bool
+	ResolverID   int64  `yaml:"resolver_id"`
+	ResolverName string `yaml:"resolver_name"`
 }
+
+// ReviewCommentResolver is the user who resolved the thread of a review comment
+type ReviewCommentResolver struct {
+	Comment *ReviewComment
+}
+
+// GetExternalName ExternalUserMigrated interface
+func (r *ReviewCommentResolver) GetExternalName() string { return r.Comment.ResolverName }
+
+// GetExternalID ExternalUserMigrated interface
+func (r *ReviewCommentResolver) GetExternalID() int64 { return r.Comment.ResolverID }
@@ -0,0 +1,22 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+import "time"
+
+// TrackedTime represents the time spent on an issue or a pull request
+type TrackedTime struct {
+	IssueIndex int64  `yaml:"issue_index"`
+	UserID     int64  `yaml:"user_id"`
+	UserName   string `yaml:"user_name"`
+	Time       int64  // in seconds
+	Created    time.Time
+}
+
+// GetExternalName ExternalUserMigrated interface
+func (t *TrackedTime) GetExternalName() string { return t.UserName }
+
+// GetExternalID ExternalUserMigrated interface
+func (t *TrackedTime) GetExternalID() int64 { return t.UserID }
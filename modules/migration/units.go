@@ -0,0 +1,74 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+// MigrateUnits is a set of the kinds of data migrated besides the git data of a repository
+type MigrateUnits uint
+
+// The units which can be selected for a migration
+const (
+	MigrateUnitWiki MigrateUnits = 1 << iota
+	MigrateUnitMilestones
+	MigrateUnitLabels
+	MigrateUnitReleases
+	MigrateUnitReleaseAssets
+	MigrateUnitIssues
+	MigrateUnitComments
+	MigrateUnitPullRequests
+	MigrateUnitCommentHistory
+	MigrateUnitTrackedTimes
+	MigrateUnitIssueSubscribers
+	MigrateUnitDiscussions
+	MigrateUnitWebhooks
+
+	// MigrateUnitsAll contains all units
+	MigrateUnitsAll = MigrateUnitWebhooks<<1 - 1
+)
+
+// Has returns true if all the given units are in the set
+func (units MigrateUnits) Has(unit MigrateUnits) bool {
+	return units&unit == unit
+}
+
+// migrateUnitFlags returns pointers to the boolean flag of every unit in opts
+func (opts *MigrateOptions) migrateUnitFlags() map[MigrateUnits]*bool {
+	return map[MigrateUnits]*bool{
+		MigrateUnitWiki:             &opts.Wiki,
+		MigrateUnitMilestones:       &opts.Milestones,
+		MigrateUnitLabels:           &opts.Labels,
+		MigrateUnitReleases:         &opts.Releases,
+		MigrateUnitReleaseAssets:    &opts.ReleaseAssets,
+		MigrateUnitIssues:           &opts.Issues,
+		MigrateUnitComments:         &opts.Comments,
+		MigrateUnitPullRequests:     &opts.PullRequests,
+		MigrateUnitCommentHistory:   &opts.CommentHistory,
+		MigrateUnitTrackedTimes:     &opts.TrackedTimes,
+		MigrateUnitIssueSubscribers: &opts.IssueSubscribers,
+		MigrateUnitDiscussions:      &opts.Discussions,
+		MigrateUnitWebhooks:         &opts.Webhooks,
+	}
+}
+
+// MigrateUnits returns the units selected by Units or, if it is empty, by the boolean flags like Issues
+func (opts MigrateOptions) MigrateUnits() MigrateUnits {
+	if opts.Units != 0 {
+		return opts.Units
+	}
+	var units MigrateUnits
+	for unit, flag := range opts.migrateUnitFlags() {
+		if *flag {
+			units |= unit
+		}
+	}
+	return units
+}
+
+// SetMigrateUnits selects exactly the given units and updates the boolean flags accordingly
+func (opts *MigrateOptions) SetMigrateUnits(units MigrateUnits) {
+	opts.Units = units
+	for unit, flag := range opts.migrateUnitFlags() {
+		*flag = units.Has(unit)
+	}
+}
@@ -10,6 +10,7 @@ type Uploader interface {
 	MaxBatchInsertSize(tp string) int
 	CreateRepo(repo *Repository, opts MigrateOptions) error
 	CreateTopics(topic ...string) error
+	CreateRepoAvatar(data []byte) error
 	CreateMilestones(milestones ...*Milestone) error
 	CreateReleases(releases ...*Release) error
 	SyncTags() error
@@ -18,6 +19,10 @@ type Uploader interface {
 	CreateComments(comments ...*Comment) error
 	CreatePullRequests(prs ...*PullRequest) error
 	CreateReviews(reviews ...*Review) error
+	CreateCommitStatuses(statuses ...*CommitStatus) error
+	CreateTrackedTimes(times ...*TrackedTime) error
+	CreateIssueSubscribers(subscribers ...*IssueSubscriber) error
+	CreateWebhooks(webhooks ...*Webhook) error
 	Rollback() error
 	Finish() error
 	Close()
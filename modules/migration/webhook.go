@@ -0,0 +1,20 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migration
+
+// Webhook is a webhook of the migrated repository. The secret of the source is never exposed,
+// so a new one is generated for the migrated webhook.
+type Webhook struct {
+	ID  int64
+	URL string
+	// Type is the Gitea webhook type whose payloads the receiver expects, e.g. "gitea" or "gogs"
+	Type string
+	// ContentType is "json" or "form"
+	ContentType string `yaml:"content_type"`
+	// Events are Gitea webhook events like "push" or "issues", "*" sends everything
+	Events       []string
+	BranchFilter string `yaml:"branch_filter"`
+	Active       bool
+}
@@ -32,6 +32,8 @@ type GitPushOptions map[string]string
 const (
 	GitPushOptionRepoPrivate  = "repo.private"
 	GitPushOptionRepoTemplate = "repo.template"
+	// GitPushOptionPushMirror lists the repositories a push mirror pushed the content from, separated by spaces
+	GitPushOptionPushMirror = "gitea-push-mirror"
 )
 
 // Bool checks for a key in the map and parses as a boolean
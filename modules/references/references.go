@@ -228,6 +228,27 @@ func FindAllMentionsBytes(content []byte) []RefSpan {
 	return ret
 }
 
+// FindAllLocalIssueReferencesBytes matches references to issues of the same repository, e.g. #1287,
+// in given content and returns a list of their locations, including the # or ! prefix.
+func FindAllLocalIssueReferencesBytes(content []byte) []RefSpan {
+	ret := make([]RefSpan, 0, 5)
+	pos := 0
+	for {
+		match := issueNumericPattern.FindSubmatchIndex(content[pos:])
+		if match == nil {
+			break
+		}
+		ret = append(ret, RefSpan{Start: match[2] + pos, End: match[3] + pos})
+		notrail := spaceTrimmedPattern.FindSubmatchIndex(content[match[2]+pos : match[3]+pos])
+		if notrail == nil {
+			pos = match[3] + pos
+		} else {
+			pos = match[3] + pos + notrail[1] - notrail[3]
+		}
+	}
+	return ret
+}
+
 // FindFirstMentionBytes matches the first mention in then given content
 // and returns the location of the unvalidated user name, including the @ prefix.
 func FindFirstMentionBytes(content []byte) (bool, RefSpan) {
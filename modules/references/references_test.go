@@ -304,6 +304,16 @@ func TestFindAllMentions(t *testing.T) {
 	}, res)
 }
 
+func TestFindAllLocalIssueReferences(t *testing.T) {
+	res := FindAllLocalIssueReferencesBytes([]byte("#1 #2, (!3) user/repo#4 #5."))
+	assert.EqualValues(t, []RefSpan{
+		{Start: 0, End: 2},
+		{Start: 3, End: 5},
+		{Start: 8, End: 10},
+		{Start: 24, End: 26},
+	}, res)
+}
+
 func TestRegExp_mentionPattern(t *testing.T) {
 	trueTestCases := []struct {
 		pat string
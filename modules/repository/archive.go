@@ -0,0 +1,325 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// cloneFromArchive initializes the repository at repoPath from a local archive instead of cloning it over the network.
+// Supported are git bundles (*.bundle), tarballs of a bare repository (*.tar, *.tar.gz, *.tgz) and
+// zip files of a working tree containing its .git directory (*.zip).
+func cloneFromArchive(ctx context.Context, archivePath, repoPath string, timeout time.Duration) error {
+	cloneOpts := git.CloneRepoOptions{
+		Mirror:        true,
+		Quiet:         true,
+		Timeout:       timeout,
+		SkipTLSVerify: setting.Migrations.SkipTLSVerify,
+	}
+
+	name := strings.ToLower(archivePath)
+	if isBundle(archivePath) {
+		return git.Clone(ctx, archivePath, repoPath, cloneOpts)
+	}
+
+	tmpDir, err := models.CreateTemporaryPath("migrate-archive")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpDir); err != nil {
+			log.Error("Unable to remove temporary directory %s: %v", tmpDir, err)
+		}
+	}()
+
+	remaining := archiveSizeLimit()
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		err = extractTarArchive(archivePath, tmpDir, false, &remaining)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		err = extractTarArchive(archivePath, tmpDir, true, &remaining)
+	case strings.HasSuffix(name, ".zip"):
+		err = extractZipArchive(archivePath, tmpDir, &remaining)
+	default:
+		return fmt.Errorf("unsupported repository archive %s", filepath.Base(archivePath))
+	}
+	if err != nil {
+		return err
+	}
+
+	gitDir, err := findArchivedRepository(tmpDir)
+	if err != nil {
+		return err
+	}
+	// a local clone would copy the objects these files refer to from outside of the archive
+	for _, name := range []string{"commondir", "objects/info/alternates", "objects/info/http-alternates"} {
+		if exists, _ := util.IsExist(filepath.Join(gitDir, filepath.FromSlash(name))); exists {
+			return fmt.Errorf("archived repository must not contain %s", name)
+		}
+	}
+	return git.Clone(ctx, gitDir, repoPath, cloneOpts)
+}
+
+// ErrInvalidBundle represents a git bundle which is rejected before it is imported,
+// e.g. because it was tampered with or it is incomplete
+type ErrInvalidBundle struct {
+	Path   string
+	Reason string
+}
+
+// IsErrInvalidBundle checks if an error is a ErrInvalidBundle.
+func IsErrInvalidBundle(err error) bool {
+	_, ok := err.(ErrInvalidBundle)
+	return ok
+}
+
+func (err ErrInvalidBundle) Error() string {
+	return fmt.Sprintf("invalid git bundle %s: %s", filepath.Base(err.Path), err.Reason)
+}
+
+// bundleSHA256ConfigKey is the git config key the checksum of the bundle a repository was imported from is stored under
+const bundleSHA256ConfigKey = "gitea.bundlesha256"
+
+func isBundle(archivePath string) bool {
+	return strings.HasSuffix(strings.ToLower(archivePath), ".bundle")
+}
+
+// verifyBundle checks the checksum of the pack in a git bundle and lets `git bundle verify` check
+// that the bundle doesn't need any prerequisite commits. It returns the SHA256 of the bundle file.
+func verifyBundle(ctx context.Context, bundlePath string) (string, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	fileHash := sha256.New()
+	r := bufio.NewReader(io.TeeReader(f, fileHash))
+
+	// the header lists the capabilities, prerequisites and refs up to an empty line, the pack follows it
+	var packHash hash.Hash = sha1.New()
+	headerSize := int64(0)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", ErrInvalidBundle{Path: bundlePath, Reason: "incomplete header"}
+		}
+		if headerSize == 0 && line != "# v2 git bundle\n" && line != "# v3 git bundle\n" {
+			return "", ErrInvalidBundle{Path: bundlePath, Reason: "not a v2 or v3 bundle"}
+		}
+		headerSize += int64(len(line))
+		if line == "@object-format=sha256\n" {
+			packHash = sha256.New()
+		}
+		if line == "\n" {
+			break
+		}
+	}
+
+	// the pack ends with the checksum of its content
+	trailer := make([]byte, packHash.Size())
+	packSize := fi.Size() - headerSize
+	if packSize < int64(len("PACK")+len(trailer)) {
+		return "", ErrInvalidBundle{Path: bundlePath, Reason: "incomplete pack"}
+	}
+	if _, err := io.CopyN(packHash, r, packSize-int64(len(trailer))); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(r, trailer); err != nil {
+		return "", err
+	}
+	if !bytes.Equal(packHash.Sum(nil), trailer) {
+		return "", ErrInvalidBundle{Path: bundlePath, Reason: "pack checksum mismatch"}
+	}
+
+	tmpDir, err := models.CreateTemporaryPath("verify-bundle")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpDir); err != nil {
+			log.Error("Unable to remove temporary directory %s: %v", tmpDir, err)
+		}
+	}()
+	// an empty repository has none of the prerequisites of an incremental bundle
+	if err := git.InitRepository(ctx, tmpDir, true); err != nil {
+		return "", err
+	}
+	if _, err := git.NewCommand(ctx, "bundle", "verify", bundlePath).RunInDir(tmpDir); err != nil {
+		return "", ErrInvalidBundle{Path: bundlePath, Reason: err.Error()}
+	}
+
+	return hex.EncodeToString(fileHash.Sum(nil)), nil
+}
+
+// archiveEntryPath returns the path an archive entry is extracted to, entries escaping the destination are rejected
+func archiveEntryPath(dest, name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q is outside of the repository", name)
+	}
+	return filepath.Join(dest, cleaned), nil
+}
+
+// archiveSizeLimit returns the total size in bytes the files of an archive may extract to
+func archiveSizeLimit() int64 {
+	if setting.Migrations.ArchiveMaxSize <= 0 {
+		// one below the maximum, extractArchiveFile reads a byte more than remains
+		return math.MaxInt64 - 1
+	}
+	return setting.Migrations.ArchiveMaxSize * 1024 * 1024
+}
+
+// extractArchiveFile writes the file to path and subtracts its size from the remaining size of the archive,
+// the sizes in the headers of the archive aren't trusted
+func extractArchiveFile(path string, mode os.FileMode, r io.Reader, remaining *int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, *remaining+1))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	*remaining -= n
+	if *remaining < 0 {
+		f.Close()
+		return fmt.Errorf("archive extracts to more than %d MB", setting.Migrations.ArchiveMaxSize)
+	}
+	return f.Close()
+}
+
+func extractTarArchive(archivePath, dest string, gzipped bool, remaining *int64) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("invalid gzip archive: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid tar archive: %v", err)
+		}
+
+		path, err := archiveEntryPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractArchiveFile(path, hdr.FileInfo().Mode(), tr, remaining); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			// pax global headers don't describe a file
+		default:
+			// links could point outside of the repository
+			return fmt.Errorf("archive entry %q is not a regular file or directory", hdr.Name)
+		}
+	}
+}
+
+func extractZipArchive(archivePath, dest string, remaining *int64) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %v", err)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		path, err := archiveEntryPath(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = extractArchiveFile(path, mode, rc, remaining)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("archive entry %q is not a regular file or directory", file.Name)
+		}
+	}
+	return nil
+}
+
+// findArchivedRepository returns the git directory of an extracted archive. The repository may be
+// at the root of the archive or in its only top level directory, either bare or as a working tree.
+func findArchivedRepository(dir string) (string, error) {
+	candidates := []string{dir}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		candidates = append(candidates, filepath.Join(dir, entries[0].Name()))
+	}
+
+	for _, candidate := range candidates {
+		for _, gitDir := range []string{filepath.Join(candidate, ".git"), candidate} {
+			hasHead, _ := util.IsFile(filepath.Join(gitDir, "HEAD"))
+			hasObjects, _ := util.IsDir(filepath.Join(gitDir, "objects"))
+			if hasHead && hasObjects {
+				return gitDir, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("archive does not contain a git repository")
+}
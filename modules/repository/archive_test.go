@@ -0,0 +1,283 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestArchive archives the files of dir below the prefix, as tarball or as zip file depending on the file name
+func writeTestArchive(t *testing.T, archivePath, dir, prefix string) {
+	f, err := os.Create(archivePath)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var addFile func(name string, info os.FileInfo, r io.Reader) error
+	if filepath.Ext(archivePath) == ".zip" {
+		zw := zip.NewWriter(f)
+		defer zw.Close()
+		addFile = func(name string, info os.FileInfo, r io.Reader) error {
+			hdr, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			w, err := zw.CreateHeader(hdr)
+			if err != nil || r == nil {
+				return err
+			}
+			_, err = io.Copy(w, r)
+			return err
+		}
+	} else {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		tw := tar.NewWriter(gz)
+		defer tw.Close()
+		addFile = func(name string, info os.FileInfo, r io.Reader) error {
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			if err := tw.WriteHeader(hdr); err != nil || r == nil {
+				return err
+			}
+			_, err = io.Copy(tw, r)
+			return err
+		}
+	}
+
+	assert.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(filepath.Join(prefix, rel))
+		if info.IsDir() {
+			return addFile(name+"/", info, nil)
+		}
+		content, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		return addFile(name, info, content)
+	}))
+}
+
+func TestMigrateRepositoryGitDataFromArchive(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: repo.OwnerID}).(*user_model.User)
+
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "source.git")
+	assert.NoError(t, git.Clone(git.DefaultContext, repo.RepoPath(), source, git.CloneRepoOptions{Mirror: true, Quiet: true}))
+	sourceHead, err := git.NewCommand(git.DefaultContext, "rev-parse", "master").RunInDir(source)
+	assert.NoError(t, err)
+
+	workTree := filepath.Join(tmpDir, "worktree")
+	assert.NoError(t, git.Clone(git.DefaultContext, source, workTree, git.CloneRepoOptions{Quiet: true}))
+
+	bundle := filepath.Join(tmpDir, "repo.bundle")
+	_, err = git.NewCommand(git.DefaultContext, "bundle", "create", bundle, "--all").RunInDir(source)
+	assert.NoError(t, err)
+	tarball := filepath.Join(tmpDir, "repo.tar.gz")
+	writeTestArchive(t, tarball, source, "repo1.git")
+	zipFile := filepath.Join(tmpDir, "repo.zip")
+	writeTestArchive(t, zipFile, workTree, ".")
+
+	for _, archive := range []string{bundle, tarball, zipFile} {
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			repo, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+				RepoName:    repo.Name,
+				ArchivePath: archive,
+				Releases:    true,
+			}, nil)
+			assert.NoError(t, err)
+
+			head, err := git.NewCommand(git.DefaultContext, "rev-parse", "master").RunInDir(repo.RepoPath())
+			assert.NoError(t, err)
+			assert.Equal(t, sourceHead, head)
+
+			// the temporary location of the archive is not kept as remote
+			_, err = git.GetRemoteAddress(git.DefaultContext, repo.RepoPath(), "origin")
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("PathTraversal", func(t *testing.T) {
+		evil := filepath.Join(tmpDir, "evil.tar.gz")
+		writeTestArchive(t, evil, source, "../evil")
+		_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+			RepoName:    repo.Name,
+			ArchivePath: evil,
+		}, nil)
+		assert.Error(t, err)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(tmpDir), "evil", "HEAD"))
+	})
+
+	t.Run("Alternates", func(t *testing.T) {
+		for _, name := range []string{"commondir", "objects/info/alternates", "objects/info/http-alternates"} {
+			borrowing := filepath.Join(t.TempDir(), "borrowing.git")
+			assert.NoError(t, git.InitRepository(git.DefaultContext, borrowing, true))
+			assert.NoError(t, os.MkdirAll(filepath.Join(borrowing, "objects", "info"), os.ModePerm))
+			assert.NoError(t, os.WriteFile(filepath.Join(borrowing, filepath.FromSlash(name)), []byte(filepath.Join(source, "objects")+"\n"), 0o644))
+
+			archive := filepath.Join(t.TempDir(), "borrowing.tar.gz")
+			writeTestArchive(t, archive, borrowing, "borrowing.git")
+			_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+				RepoName:    repo.Name,
+				ArchivePath: archive,
+			}, nil)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), name)
+			}
+		}
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		defer func(maxSize int64) {
+			setting.Migrations.ArchiveMaxSize = maxSize
+		}(setting.Migrations.ArchiveMaxSize)
+		setting.Migrations.ArchiveMaxSize = 1
+
+		large := filepath.Join(t.TempDir(), "large.git")
+		assert.NoError(t, git.Clone(git.DefaultContext, source, large, git.CloneRepoOptions{Mirror: true, Quiet: true}))
+		assert.NoError(t, os.WriteFile(filepath.Join(large, "padding"), make([]byte, 2*1024*1024), 0o644))
+
+		for _, name := range []string{"large.tar.gz", "large.zip"} {
+			archive := filepath.Join(t.TempDir(), name)
+			writeTestArchive(t, archive, large, "large.git")
+			_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+				RepoName:    repo.Name,
+				ArchivePath: archive,
+			}, nil)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), "more than 1 MB")
+			}
+		}
+	})
+
+	t.Run("NoRepository", func(t *testing.T) {
+		empty := filepath.Join(tmpDir, "empty.zip")
+		writeTestArchive(t, empty, t.TempDir(), "empty")
+		_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+			RepoName:    repo.Name,
+			ArchivePath: empty,
+		}, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrateRepositoryGitDataFromBundle(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: repo.OwnerID}).(*user_model.User)
+
+	tmpDir := t.TempDir()
+	bundle := filepath.Join(tmpDir, "repo.bundle")
+	_, err := git.NewCommand(git.DefaultContext, "bundle", "create", bundle, "--all").RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+	data, err := os.ReadFile(bundle)
+	assert.NoError(t, err)
+	checksum := sha256.Sum256(data)
+
+	t.Run("Valid", func(t *testing.T) {
+		repo, result, err := MigrateRepositoryGitDataWithResult(git.DefaultContext, owner, repo, migration.MigrateOptions{
+			RepoName:    repo.Name,
+			ArchivePath: bundle,
+		}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString(checksum[:]), result.BundleSHA256)
+
+		recorded, err := git.NewCommand(git.DefaultContext, "config", "--get", bundleSHA256ConfigKey).RunInDir(repo.RepoPath())
+		assert.NoError(t, err)
+		assert.Equal(t, result.BundleSHA256, strings.TrimSpace(recorded))
+	})
+
+	corrupted := make([]byte, len(data))
+	copy(corrupted, data)
+	corrupted[len(corrupted)-30] ^= 0xff
+	workTree := filepath.Join(tmpDir, "worktree")
+	assert.NoError(t, git.Clone(git.DefaultContext, repo.RepoPath(), workTree, git.CloneRepoOptions{Quiet: true}))
+	_, err = git.NewCommand(git.DefaultContext, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "incremental").RunInDir(workTree)
+	assert.NoError(t, err)
+	incremental := filepath.Join(tmpDir, "incremental.bundle")
+	_, err = git.NewCommand(git.DefaultContext, "bundle", "create", incremental, "master~1..master").RunInDir(workTree)
+	assert.NoError(t, err)
+
+	for name, content := range map[string][]byte{
+		"corrupted.bundle":  corrupted,
+		"truncated.bundle":  data[:len(data)-10],
+		"header.bundle":     data[:20],
+		"notabundle.bundle": []byte("# v1 git bundle\n\n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(tmpDir, name)
+			assert.NoError(t, os.WriteFile(invalid, content, 0o644))
+			_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+				RepoName:    repo.Name,
+				ArchivePath: invalid,
+			}, nil)
+			assert.True(t, IsErrInvalidBundle(err), "%v", err)
+		})
+	}
+
+	t.Run("Incremental", func(t *testing.T) {
+		// the prerequisite commits of the bundle are missing
+		_, err := MigrateRepositoryGitData(git.DefaultContext, owner, repo, migration.MigrateOptions{
+			RepoName:    repo.Name,
+			ArchivePath: incremental,
+		}, nil)
+		assert.True(t, IsErrInvalidBundle(err), "%v", err)
+	})
+}
+
+func TestArchiveEntryPath(t *testing.T) {
+	dest := filepath.Join(os.TempDir(), "archive")
+	for name, expected := range map[string]string{
+		"repo.git/HEAD":         filepath.Join(dest, "repo.git", "HEAD"),
+		"./HEAD":                filepath.Join(dest, "HEAD"),
+		"a/../HEAD":             filepath.Join(dest, "HEAD"),
+		"..foo/HEAD":            filepath.Join(dest, "..foo", "HEAD"),
+		"../HEAD":               "",
+		"a/../../HEAD":          "",
+		"/etc/passwd":           "",
+		"repo.git/../../../etc": "",
+	} {
+		path, err := archiveEntryPath(dest, name)
+		if expected == "" {
+			assert.Error(t, err, name)
+		} else {
+			assert.NoError(t, err, name)
+			assert.Equal(t, expected, path, name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"errors"
+	"strconv"
+
+	"code.gitea.io/gitea/modules/sync"
+)
+
+// ErrRepoSyncRunning is returned if a migration or mirror sync of the repository is already running
+var ErrRepoSyncRunning = errors.New("a migration or mirror sync of the repository is already running")
+
+// repoSyncStatusTable contains the repositories which are being migrated or mirrored at the moment
+var repoSyncStatusTable = sync.NewStatusTable()
+
+// StartRepoSync marks the repository as being migrated or mirrored.
+// It returns false if another migration or mirror sync of the repository is already running.
+func StartRepoSync(repoID int64) bool {
+	return repoSyncStatusTable.StartIfNotRunning(strconv.FormatInt(repoID, 10))
+}
+
+// StopRepoSync marks the migration or mirror sync of the repository as finished
+func StopRepoSync(repoID int64) {
+	repoSyncStatusTable.Stop(strconv.FormatInt(repoID, 10))
+}
+
+// IsRepoSyncRunning returns whether a migration or mirror sync of the repository is running
+func IsRepoSyncRunning(repoID int64) bool {
+	return repoSyncStatusTable.IsRunning(strconv.FormatInt(repoID, 10))
+}
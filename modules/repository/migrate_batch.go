@@ -0,0 +1,77 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// MigrateBatchItem is a repository whose git data is migrated by MigrateRepositoriesGitData
+type MigrateBatchItem struct {
+	Owner *user_model.User
+	Repo  *repo_model.Repository
+	Opts  migration.MigrateOptions
+}
+
+// MigrateBatchItemResult is the outcome of the migration of a MigrateBatchItem
+type MigrateBatchItemResult struct {
+	Repo   *repo_model.Repository
+	Result *MigrateResult
+	Err    error
+}
+
+// migrateGitDataWithResult is replaced by tests
+var migrateGitDataWithResult = MigrateRepositoryGitDataWithResult
+
+// MigrateRepositoriesGitData migrates the git data of multiple repositories, e.g. the repositories of an organization,
+// running up to setting.Migrations.BatchConcurrency migrations at the same time.
+// The results have the order of the items and a failed migration doesn't stop the others.
+// Once ctx is done no more migrations are started, the remaining items fail with the error of ctx.
+func MigrateRepositoriesGitData(ctx context.Context, items []MigrateBatchItem, httpTransport *http.Transport) []MigrateBatchItemResult {
+	concurrency := setting.Migrations.BatchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]MigrateBatchItemResult, len(items))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		if ctx.Err() == nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			results[i] = MigrateBatchItemResult{Repo: items[i].Repo, Result: &MigrateResult{}, Err: err}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			item := items[i]
+			repo, result, err := migrateGitDataWithResult(ctx, item.Owner, item.Repo, item.Opts, httpTransport)
+			if err != nil {
+				log.Error("Batch migration of %s/%s failed: %v", item.Owner.Name, item.Opts.RepoName, err)
+			}
+			results[i] = MigrateBatchItemResult{Repo: repo, Result: result, Err: err}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
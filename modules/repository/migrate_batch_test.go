@@ -0,0 +1,101 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateRepositoriesGitData(t *testing.T) {
+	defer func(concurrency int) {
+		setting.Migrations.BatchConcurrency = concurrency
+	}(setting.Migrations.BatchConcurrency)
+	defer func() {
+		migrateGitDataWithResult = MigrateRepositoryGitDataWithResult
+	}()
+	setting.Migrations.BatchConcurrency = 2
+
+	owner := &user_model.User{ID: 1, Name: "org"}
+	items := []MigrateBatchItem{
+		{Owner: owner, Repo: &repo_model.Repository{ID: 1}, Opts: migration.MigrateOptions{RepoName: "one"}},
+		{Owner: owner, Repo: &repo_model.Repository{ID: 2}, Opts: migration.MigrateOptions{RepoName: "two"}},
+		{Owner: owner, Repo: &repo_model.Repository{ID: 3}, Opts: migration.MigrateOptions{RepoName: "three"}},
+	}
+
+	t.Run("Concurrency", func(t *testing.T) {
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		migrateGitDataWithResult = func(ctx context.Context, u *user_model.User, repo *repo_model.Repository, opts migration.MigrateOptions, httpTransport *http.Transport) (*repo_model.Repository, *MigrateResult, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if opts.RepoName == "two" {
+				return repo, &MigrateResult{}, errors.New("clone failed")
+			}
+			return repo, &MigrateResult{Releases: MigrateStepResult{Status: MigrateStepSucceeded}}, nil
+		}
+
+		results := MigrateRepositoriesGitData(context.Background(), items, nil)
+		assert.Equal(t, 2, maxRunning)
+		if assert.Len(t, results, 3) {
+			for i, result := range results {
+				assert.Equal(t, items[i].Repo, result.Repo)
+				assert.NotNil(t, result.Result)
+			}
+			assert.NoError(t, results[0].Err)
+			assert.Equal(t, MigrateStepSucceeded, results[0].Result.Releases.Status)
+			assert.EqualError(t, results[1].Err, "clone failed")
+			assert.NoError(t, results[2].Err)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var started []string
+		migrateGitDataWithResult = func(ctx context.Context, u *user_model.User, repo *repo_model.Repository, opts migration.MigrateOptions, httpTransport *http.Transport) (*repo_model.Repository, *MigrateResult, error) {
+			mu.Lock()
+			started = append(started, opts.RepoName)
+			if len(started) == 2 {
+				// both slots are taken, the remaining migration must not be started after the cancellation
+				cancel()
+			}
+			mu.Unlock()
+			<-ctx.Done()
+			return repo, &MigrateResult{}, ctx.Err()
+		}
+
+		results := MigrateRepositoriesGitData(ctx, items, nil)
+		assert.Len(t, started, 2)
+		assert.NotContains(t, started, "three")
+		for _, result := range results {
+			assert.ErrorIs(t, result.Err, context.Canceled)
+		}
+		assert.Equal(t, items[2].Repo, results[2].Repo)
+	})
+}
@@ -5,11 +5,16 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,10 +26,13 @@ import (
 	"code.gitea.io/gitea/modules/lfs"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/proxy"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/sync"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 
+	"github.com/gobwas/glob"
 	"gopkg.in/ini.v1"
 )
 
@@ -47,12 +55,148 @@ func WikiRemoteURL(ctx context.Context, remote string) string {
 	return ""
 }
 
+// ErrMigrateWikiNotFound is returned if there is no accessible wiki next to the clone address of a migration
+var ErrMigrateWikiNotFound = errors.New("no accessible wiki found for the clone address")
+
+// MigrateWiki clones the wiki of the migration source of an existing repository, e.g. to retry a wiki clone
+// which failed during the migration. u is the owner of the repository, an existing wiki is replaced.
+// The wiki of a mirror keeps its remote so it is updated by the mirror syncs.
+func MigrateWiki(ctx context.Context, u *user_model.User, repo *repo_model.Repository, opts migration.MigrateOptions) error {
+	if repo.OwnerID != u.ID {
+		return fmt.Errorf("user %d is not the owner of repository %d", u.ID, repo.ID)
+	}
+	if !StartRepoSync(repo.ID) {
+		return ErrRepoSyncRunning
+	}
+	defer StopRepoSync(repo.ID)
+
+	wikiPath := repo_model.WikiPath(u.Name, repo.Name)
+	if err := cloneWiki(ctx, opts.CloneAddr, wikiPath, time.Duration(setting.Git.Timeout.Migrate)*time.Second); err != nil {
+		return err
+	}
+	if repo.IsMirror {
+		return nil
+	}
+
+	if err := createDelegateHooks(wikiPath); err != nil {
+		return fmt.Errorf("createDelegateHooks.(wiki): %v", err)
+	}
+	if err := cleanUpMigrateGitConfig(path.Join(wikiPath, "config")); err != nil {
+		return fmt.Errorf("cleanUpMigrateGitConfig (wiki): %v", err)
+	}
+	return nil
+}
+
+// cloneWiki clones the wiki found by WikiRemoteURL for the clone address into wikiPath, replacing an existing wiki.
+// A failed clone leaves no wiki behind.
+func cloneWiki(ctx context.Context, cloneAddr, wikiPath string, timeout time.Duration) error {
+	wikiRemotePath := WikiRemoteURL(ctx, cloneAddr)
+	if len(wikiRemotePath) == 0 {
+		return ErrMigrateWikiNotFound
+	}
+	if err := util.RemoveAll(wikiPath); err != nil {
+		return fmt.Errorf("Failed to remove %s: %v", wikiPath, err)
+	}
+
+	if err := git.Clone(ctx, wikiRemotePath, wikiPath, git.CloneRepoOptions{
+		Mirror:        true,
+		Quiet:         true,
+		Timeout:       timeout,
+		Branch:        "master",
+		SkipTLSVerify: setting.Migrations.SkipTLSVerify,
+	}); err != nil {
+		if err := util.RemoveAll(wikiPath); err != nil {
+			log.Error("Failed to remove %s: %v", wikiPath, err)
+		}
+		return fmt.Errorf("Clone wiki: %v", err)
+	}
+	return nil
+}
+
+// MigrateStepStatus is the outcome of an optional step of a git data migration
+type MigrateStepStatus int
+
+// enumerate all the statuses of a migration step
+const (
+	MigrateStepSkipped MigrateStepStatus = iota
+	MigrateStepSucceeded
+	MigrateStepFailed
+)
+
+// MigrateStepResult describes the outcome of an optional step of a git data migration.
+// Count is the number of items (releases, LFS objects) the repository has after the step.
+type MigrateStepResult struct {
+	Status MigrateStepStatus
+	Err    error
+	Count  int64
+}
+
+func (r *MigrateStepResult) done(err error) {
+	if err != nil {
+		r.Status = MigrateStepFailed
+		r.Err = err
+		return
+	}
+	r.Status = MigrateStepSucceeded
+}
+
+// MigrateResult summarizes the optional steps of a git data migration whose failures don't fail the migration
+type MigrateResult struct {
+	Wiki     MigrateStepResult
+	Releases MigrateStepResult
+	LFS      MigrateStepResult
+	RepoSize MigrateStepResult
+	// Shallow is set if the migrated repository is a shallow clone, either by request or because its source is one
+	Shallow bool
+	// BundleSHA256 is the checksum of the verified git bundle the repository was imported from
+	BundleSHA256 string
+}
+
+// HasFailures returns whether any step of the migration failed
+func (r *MigrateResult) HasFailures() bool {
+	return r.Wiki.Status == MigrateStepFailed ||
+		r.Releases.Status == MigrateStepFailed ||
+		r.LFS.Status == MigrateStepFailed ||
+		r.RepoSize.Status == MigrateStepFailed
+}
+
 // MigrateRepositoryGitData starts migrating git related data after created migrating repository
 func MigrateRepositoryGitData(ctx context.Context, u *user_model.User,
 	repo *repo_model.Repository, opts migration.MigrateOptions,
 	httpTransport *http.Transport,
 ) (*repo_model.Repository, error) {
-	repoPath := repo_model.RepoPath(u.Name, opts.RepoName)
+	repo, _, err := MigrateRepositoryGitDataWithResult(ctx, u, repo, opts, httpTransport)
+	return repo, err
+}
+
+// MigrateRepositoryGitDataWithResult works like MigrateRepositoryGitData and additionally returns
+// the outcome of the steps whose failures are only logged, e.g. the wiki clone or the LFS download.
+// The result is never nil, it describes the steps done so far if an error is returned.
+func MigrateRepositoryGitDataWithResult(ctx context.Context, u *user_model.User,
+	repo *repo_model.Repository, opts migration.MigrateOptions,
+	httpTransport *http.Transport,
+) (*repo_model.Repository, *MigrateResult, error) {
+	result := &MigrateResult{}
+	repo, err := migrateRepositoryGitData(ctx, u, repo, opts, httpTransport, result)
+	return repo, result, err
+}
+
+func migrateRepositoryGitData(ctx context.Context, u *user_model.User,
+	repo *repo_model.Repository, opts migration.MigrateOptions,
+	httpTransport *http.Transport, result *MigrateResult,
+) (*repo_model.Repository, error) {
+	if repo.ID > 0 {
+		if !StartRepoSync(repo.ID) {
+			return repo, ErrRepoSyncRunning
+		}
+		defer StopRepoSync(repo.ID)
+	}
+
+	u, repoName, err := migrateTarget(u, repo, opts)
+	if err != nil {
+		return repo, err
+	}
+	repoPath := repo_model.RepoPath(u.Name, repoName)
 
 	if u.IsOrganization() {
 		t, err := models.OrgFromUser(u).GetOwnerTeam()
@@ -66,40 +210,56 @@ func MigrateRepositoryGitData(ctx context.Context, u *user_model.User,
 
 	migrateTimeout := time.Duration(setting.Git.Timeout.Migrate) * time.Second
 
-	var err error
 	if err = util.RemoveAll(repoPath); err != nil {
 		return repo, fmt.Errorf("Failed to remove %s: %v", repoPath, err)
 	}
 
-	if err = git.Clone(ctx, opts.CloneAddr, repoPath, git.CloneRepoOptions{
+	if len(opts.ArchivePath) > 0 {
+		if isBundle(opts.ArchivePath) {
+			if result.BundleSHA256, err = verifyBundle(ctx, opts.ArchivePath); err != nil {
+				return repo, err
+			}
+		}
+		if err = cloneFromArchive(ctx, opts.ArchivePath, repoPath, migrateTimeout); err != nil {
+			return repo, fmt.Errorf("Clone from archive: %v", err)
+		}
+		if len(result.BundleSHA256) > 0 {
+			// keep the checksum with the repository to trace it back to the bundle it was recovered from
+			if _, err = git.NewCommand(ctx, "config", bundleSHA256ConfigKey, result.BundleSHA256).RunInDir(repoPath); err != nil {
+				return repo, fmt.Errorf("Record bundle checksum: %v", err)
+			}
+		}
+	} else if err = cloneWithResume(ctx, opts.CloneAddr, repoPath, git.CloneRepoOptions{
 		Mirror:        true,
 		Quiet:         true,
 		Timeout:       migrateTimeout,
 		SkipTLSVerify: setting.Migrations.SkipTLSVerify,
+		Depth:         opts.CloneDepth,
 	}); err != nil {
 		return repo, fmt.Errorf("Clone: %v", err)
 	}
 
-	if opts.Wiki {
-		wikiPath := repo_model.WikiPath(u.Name, opts.RepoName)
-		wikiRemotePath := WikiRemoteURL(ctx, opts.CloneAddr)
-		if len(wikiRemotePath) > 0 {
-			if err := util.RemoveAll(wikiPath); err != nil {
-				return repo, fmt.Errorf("Failed to remove %s: %v", wikiPath, err)
+	if opts.CloneDepth == 0 && isShallowRepository(repoPath) {
+		if setting.Migrations.UnshallowSource && len(opts.ArchivePath) == 0 {
+			log.Info("The source of %-v is a shallow repository, fetching its missing history", repo)
+			if err := unshallowClone(ctx, opts.CloneAddr, repoPath, migrateTimeout); err != nil {
+				log.Warn("Unable to fetch the missing history of the shallow source of %-v: %v", repo, err)
 			}
+		}
+		if isShallowRepository(repoPath) {
+			log.Warn("The source of %-v is a shallow repository, the migrated history is incomplete", repo)
+		}
+	}
+	result.Shallow = isShallowRepository(repoPath)
 
-			if err = git.Clone(ctx, wikiRemotePath, wikiPath, git.CloneRepoOptions{
-				Mirror:        true,
-				Quiet:         true,
-				Timeout:       migrateTimeout,
-				Branch:        "master",
-				SkipTLSVerify: setting.Migrations.SkipTLSVerify,
-			}); err != nil {
-				log.Warn("Clone wiki: %v", err)
-				if err := util.RemoveAll(wikiPath); err != nil {
-					return repo, fmt.Errorf("Failed to remove %s: %v", wikiPath, err)
-				}
-			}
+	// an archive only contains the repository itself
+	if opts.Wiki && len(opts.ArchivePath) == 0 {
+		err := cloneWiki(ctx, opts.CloneAddr, repo_model.WikiPath(u.Name, repoName), migrateTimeout)
+		if err == nil {
+			result.Wiki.done(nil)
+		} else if err != ErrMigrateWikiNotFound {
+			log.Warn("%v", err)
+			result.Wiki.done(err)
 		}
 	}
 
@@ -118,6 +278,15 @@ func MigrateRepositoryGitData(ctx context.Context, u *user_model.User,
 		return repo, fmt.Errorf("error in MigrateRepositoryGitData(git update-server-info): %v", err)
 	}
 
+	if setting.Migrations.WriteCommitGraph {
+		if stdout, err := git.NewCommand(ctx, "commit-graph", "write", "--reachable").
+			SetDescription(fmt.Sprintf("MigrateRepositoryGitData(git commit-graph write): %s", repoPath)).
+			RunInDirTimeout(migrateTimeout, repoPath); err != nil {
+			// the commit-graph only speeds up log and graph operations, so this is not fatal
+			log.Warn("MigrateRepositoryGitData(git commit-graph write) in %v: Stdout: %s\nError: %v", repo, stdout, err)
+		}
+	}
+
 	gitRepo, err := git.OpenRepositoryCtx(ctx, repoPath)
 	if err != nil {
 		return repo, fmt.Errorf("OpenRepository: %v", err)
@@ -141,56 +310,74 @@ func MigrateRepositoryGitData(ctx context.Context, u *user_model.User,
 			}
 		}
 
-		if !opts.Releases {
+		if len(opts.RenameDefaultBranch) > 0 && opts.RenameDefaultBranch != repo.DefaultBranch {
+			if opts.Mirror {
+				// the next mirror update would restore the original branch
+				log.Warn("Not renaming the default branch of the mirror %-v to %s", repo, opts.RenameDefaultBranch)
+			} else if err = renameDefaultBranch(repo, gitRepo, opts.RenameDefaultBranch); err != nil {
+				return repo, err
+			}
+		}
+
+		if len(opts.SubdirectoryPrefix) > 0 && !opts.Mirror {
+			if err = moveIntoSubdirectory(ctx, u, gitRepo, opts.SubdirectoryPrefix); err != nil {
+				return repo, fmt.Errorf("moveIntoSubdirectory: %v", err)
+			}
+		}
+
+		if opts.SkipReleaseSync {
+			log.Trace("Not synchronizing the tags of %-v to releases", repo)
+		} else if !opts.Releases && result.Shallow {
+			// the commits of the tags are likely missing in a shallow clone
+			log.Warn("Not synchronizing the tags of the shallow clone %-v to releases", repo)
+		} else if !opts.Releases {
 			if err = SyncReleasesWithTags(repo, gitRepo); err != nil {
 				log.Error("Failed to synchronize tags to releases for repository: %v", err)
 			}
+			result.Releases.done(err)
+			result.Releases.Count, _ = models.GetReleaseCountByRepoID(repo.ID, models.FindReleasesOptions{IncludeTags: true})
 		}
 
 		if opts.LFS {
-			endpoint := lfs.DetermineEndpoint(opts.CloneAddr, opts.LFSEndpoint)
+			endpoint := migrationLFSEndpoint(ctx, repoPath, opts)
 			lfsClient := lfs.NewClient(endpoint, httpTransport)
-			if err = StoreMissingLfsObjectsInRepository(ctx, repo, gitRepo, lfsClient); err != nil {
+			progressOpts := LFSProgressOptions{
+				CountTotal: setting.LFSClient.CountObjectsBeforeMigration,
+				Callback:   logLFSProgress(repo),
+				Interval:   lfsProgressLogInterval,
+			}
+			if err = StoreMissingLfsObjectsInRepositoryWithProgress(ctx, repo, gitRepo, lfsClient, migrationLFSMaxFileSize(opts.LFSMaxFileSize), progressOpts); err != nil {
 				log.Error("Failed to store missing LFS objects for repository: %v", err)
 			}
+			result.LFS.done(err)
+			result.LFS.Count, _ = models.CountLFSMetaObjects(repo.ID)
 		}
 	}
 
 	if err = models.UpdateRepoSize(ctx, repo); err != nil {
 		log.Error("Failed to update size for repository: %v", err)
 	}
+	result.RepoSize.done(err)
 
 	if opts.Mirror {
+		interval, err := setting.ParseMirrorInterval(opts.MirrorInterval)
+		if err != nil {
+			log.Error("Failed to set Interval: %v", err)
+			return repo, err
+		}
 		mirrorModel := repo_model.Mirror{
-			RepoID:         repo.ID,
-			Interval:       setting.Mirror.DefaultInterval,
-			EnablePrune:    true,
-			NextUpdateUnix: timeutil.TimeStampNow().AddDuration(setting.Mirror.DefaultInterval),
-			LFS:            opts.LFS,
+			RepoID:      repo.ID,
+			Interval:    interval,
+			EnablePrune: true,
+			LFS:         opts.LFS,
+		}
+		if interval != 0 {
+			mirrorModel.NextUpdateUnix = timeutil.TimeStampNow().AddDuration(interval)
 		}
 		if opts.LFS {
 			mirrorModel.LFSEndpoint = opts.LFSEndpoint
 		}
 
-		if opts.MirrorInterval != "" {
-			parsedInterval, err := time.ParseDuration(opts.MirrorInterval)
-			if err != nil {
-				log.Error("Failed to set Interval: %v", err)
-				return repo, err
-			}
-			if parsedInterval == 0 {
-				mirrorModel.Interval = 0
-				mirrorModel.NextUpdateUnix = 0
-			} else if parsedInterval < setting.Mirror.MinInterval {
-				err := fmt.Errorf("Interval %s is set below Minimum Interval of %s", parsedInterval, setting.Mirror.MinInterval)
-				log.Error("Interval: %s is too frequent", opts.MirrorInterval)
-				return repo, err
-			} else {
-				mirrorModel.Interval = parsedInterval
-				mirrorModel.NextUpdateUnix = timeutil.TimeStampNow().AddDuration(parsedInterval)
-			}
-		}
-
 		if err = repo_model.InsertMirror(&mirrorModel); err != nil {
 			return repo, fmt.Errorf("InsertOne: %v", err)
 		}
@@ -204,6 +391,35 @@ func MigrateRepositoryGitData(ctx context.Context, u *user_model.User,
 	return repo, err
 }
 
+// migrateTarget returns the owner and the name of the repository the git data is migrated to, taking the
+// target overrides of the options into account. The overridden target must be the repository record itself,
+// e.g. created by the uploader under the target name, as the git data is only found at the path of its record.
+func migrateTarget(u *user_model.User, repo *repo_model.Repository, opts migration.MigrateOptions) (*user_model.User, string, error) {
+	if len(opts.TargetOwnerName) == 0 && len(opts.TargetRepoName) == 0 {
+		return u, opts.RepoName, nil
+	}
+
+	owner, repoName := u, opts.RepoName
+	if len(opts.TargetOwnerName) > 0 && !strings.EqualFold(opts.TargetOwnerName, u.Name) {
+		var err error
+		if owner, err = user_model.GetUserByName(opts.TargetOwnerName); err != nil {
+			return nil, "", fmt.Errorf("GetUserByName: %w", err)
+		}
+	}
+	if len(opts.TargetRepoName) > 0 {
+		repoName = opts.TargetRepoName
+	}
+
+	existing, err := repo_model.GetRepositoryByOwnerAndName(owner.Name, repoName)
+	if err != nil {
+		return nil, "", err
+	}
+	if existing.ID != repo.ID {
+		return nil, "", repo_model.ErrRepoAlreadyExist{Uname: owner.Name, Name: repoName}
+	}
+	return owner, repoName, nil
+}
+
 // cleanUpMigrateGitConfig removes mirror info which prevents "push --all".
 // This also removes possible user credentials.
 func cleanUpMigrateGitConfig(configPath string) error {
@@ -218,6 +434,194 @@ func cleanUpMigrateGitConfig(configPath string) error {
 	return nil
 }
 
+// renameDefaultBranch renames the default branch of a migrated repository and points HEAD to the new name
+func renameDefaultBranch(repo *repo_model.Repository, gitRepo *git.Repository, to string) error {
+	if gitRepo.IsBranchExist(to) {
+		return fmt.Errorf("cannot rename the default branch %s: branch %s already exists", repo.DefaultBranch, to)
+	}
+	if err := gitRepo.RenameBranch(repo.DefaultBranch, to); err != nil {
+		return fmt.Errorf("RenameBranch: %v", err)
+	}
+	if err := gitRepo.SetDefaultBranch(to); err != nil {
+		return fmt.Errorf("SetDefaultBranch: %v", err)
+	}
+	repo.DefaultBranch = to
+	return nil
+}
+
+// moveIntoSubdirectory moves the files of every branch into the prefix directory with a subtree merge commit,
+// i.e. a commit whose tree only contains the original tree under the prefix and whose parent is the original head
+func moveIntoSubdirectory(ctx context.Context, u *user_model.User, gitRepo *git.Repository, prefix string) error {
+	dirs := strings.Split(path.Clean(strings.Trim(prefix, "/")), "/")
+	branches, _, err := gitRepo.GetBranchNames(0, 0)
+	if err != nil {
+		return fmt.Errorf("GetBranchNames: %v", err)
+	}
+
+	sig := u.NewGitSig()
+	for _, branch := range branches {
+		commit, err := gitRepo.GetBranchCommit(branch)
+		if err != nil {
+			return fmt.Errorf("GetBranchCommit(%s): %v", branch, err)
+		}
+
+		// wrap the tree of the commit into a tree for each directory of the prefix, starting with the innermost one
+		treeID := commit.Tree.ID.String()
+		for i := len(dirs) - 1; i >= 0; i-- {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			if err := git.NewCommand(ctx, "mktree").RunWithContext(&git.RunContext{
+				Timeout: -1,
+				Dir:     gitRepo.Path,
+				Stdin:   strings.NewReader(fmt.Sprintf("040000 tree %s\t%s\n", treeID, dirs[i])),
+				Stdout:  stdout,
+				Stderr:  stderr,
+			}); err != nil {
+				return fmt.Errorf("git mktree: %v", git.ConcatenateError(err, stderr.String()))
+			}
+			treeID = strings.TrimSpace(stdout.String())
+		}
+		tree, err := gitRepo.GetTree(treeID)
+		if err != nil {
+			return fmt.Errorf("GetTree: %v", err)
+		}
+
+		mergeID, err := gitRepo.CommitTree(sig, sig, tree, git.CommitTreeOpts{
+			Parents:   []string{commit.ID.String()},
+			Message:   fmt.Sprintf("Move the files of %s into %s", branch, strings.Join(dirs, "/")),
+			NoGPGSign: true,
+		})
+		if err != nil {
+			return fmt.Errorf("CommitTree: %v", err)
+		}
+		if _, err := git.NewCommand(ctx, "update-ref", git.BranchPrefix+branch, mergeID.String(), commit.ID.String()).
+			SetDescription(fmt.Sprintf("moveIntoSubdirectory(git update-ref): %s", gitRepo.Path)).
+			RunInDir(gitRepo.Path); err != nil {
+			return fmt.Errorf("git update-ref: %v", err)
+		}
+	}
+	return nil
+}
+
+// cloneWithResume mirror-clones a repository and retries failed clones up to setting.Migrations.CloneMaxAttempts times,
+// unless the error can't be fixed by retrying, e.g. a failed authentication.
+// A partially cloned repository is resumed by fetching into it, it is only cloned again from scratch if that fails.
+func cloneWithResume(ctx context.Context, from, to string, opts git.CloneRepoOptions) error {
+	err := git.Clone(ctx, from, to, opts)
+	for attempt := 1; err != nil && attempt < setting.Migrations.CloneMaxAttempts; attempt++ {
+		if class := git.ClassifyGitError(err); !class.IsRetryable() {
+			log.Warn("Clone of %s failed with a %s error which is not retried: %v", util.NewStringURLSanitizer(from, true).Replace(from), class, err)
+			return err
+		}
+		log.Warn("Clone of %s failed (attempt %d of %d): %v", util.NewStringURLSanitizer(from, true).Replace(from), attempt, setting.Migrations.CloneMaxAttempts, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(setting.Migrations.RetryBackoff) * time.Second):
+		}
+
+		if isPartialClone(ctx, from, to) {
+			if err = resumeClone(ctx, from, to, opts); err == nil {
+				return nil
+			}
+			log.Warn("Resuming the clone in %s failed: %v", to, err)
+		}
+
+		if err := util.RemoveAll(to); err != nil {
+			return fmt.Errorf("Failed to remove %s: %v", to, err)
+		}
+		err = git.Clone(ctx, from, to, opts)
+	}
+	return err
+}
+
+// isShallowRepository checks if the repository at repoPath is a shallow clone, i.e. parts of its history are missing
+func isShallowRepository(repoPath string) bool {
+	isExist, err := util.IsExist(path.Join(repoPath, "shallow"))
+	if err != nil {
+		log.Error("Unable to check if %s is a shallow repository: %v", repoPath, err)
+	}
+	return isExist
+}
+
+// unshallowClone fetches the missing history of a shallow clone from the source, which fails if the source
+// is shallow too. The fetch removes the "shallow" file once the history is complete.
+func unshallowClone(ctx context.Context, from, path string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = -1
+	}
+
+	envs := os.Environ()
+	if u, err := url.Parse(from); err == nil && (strings.EqualFold(u.Scheme, "http") || strings.EqualFold(u.Scheme, "https")) {
+		if proxy.Match(u.Host) {
+			envs = append(envs, fmt.Sprintf("https_proxy=%s", proxy.GetProxyURL()))
+		}
+	}
+
+	args := make([]string, 0, 2)
+	if setting.Migrations.SkipTLSVerify {
+		args = append(args, "-c", "http.sslVerify=false")
+	}
+	if _, err := git.NewCommand(ctx, args...).AddArguments("fetch", "--unshallow", "--quiet", "origin").RunInDirTimeoutEnv(envs, timeout, path); err != nil {
+		return fmt.Errorf("fetch: %v", err)
+	}
+	return nil
+}
+
+// isPartialClone checks if path contains an interrupted clone of from which can be resumed
+func isPartialClone(ctx context.Context, from, path string) bool {
+	if isDir, err := util.IsDir(path); err != nil || !isDir {
+		return false
+	}
+	stdout, err := git.NewCommand(ctx, "config", "--get", "remote.origin.url").RunInDir(path)
+	return err == nil && strings.TrimSpace(stdout) == from
+}
+
+// resumeClone fetches the missing objects and refs of an interrupted mirror clone and points HEAD
+// to the default branch of the remote like a completed clone does.
+func resumeClone(ctx context.Context, from, path string, opts git.CloneRepoOptions) error {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = -1
+	}
+
+	envs := os.Environ()
+	if u, err := url.Parse(from); err == nil && (strings.EqualFold(u.Scheme, "http") || strings.EqualFold(u.Scheme, "https")) {
+		if proxy.Match(u.Host) {
+			envs = append(envs, fmt.Sprintf("https_proxy=%s", proxy.GetProxyURL()))
+		}
+	}
+
+	args := make([]string, 0, 2)
+	if opts.SkipTLSVerify {
+		args = append(args, "-c", "http.sslVerify=false")
+	}
+
+	fetchCmd := git.NewCommand(ctx, args...).AddArguments("fetch", "--prune", "--quiet")
+	if opts.Depth > 0 {
+		fetchCmd.AddArguments("--depth", strconv.Itoa(opts.Depth))
+	}
+	if _, err := fetchCmd.AddArguments("origin").RunInDirTimeoutEnv(envs, timeout, path); err != nil {
+		return fmt.Errorf("fetch: %v", err)
+	}
+
+	stdout, err := git.NewCommand(ctx, args...).AddArguments("ls-remote", "--symref", "origin", "HEAD").RunInDirTimeoutEnv(envs, timeout, path)
+	if err != nil {
+		return fmt.Errorf("ls-remote: %v", err)
+	}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+			if _, err := git.NewCommand(ctx, "symbolic-ref", "HEAD", fields[1]).RunInDir(path); err != nil {
+				return fmt.Errorf("symbolic-ref: %v", err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
 // CleanUpMigrateInfo finishes migrating repository and/or wiki with things that don't need to be done for mirrors.
 func CleanUpMigrateInfo(ctx context.Context, repo *repo_model.Repository) (*repo_model.Repository, error) {
 	repoPath := repo.RepoPath()
@@ -244,7 +648,8 @@ func CleanUpMigrateInfo(ctx context.Context, repo *repo_model.Repository) (*repo
 	return repo, models.UpdateRepository(repo, false)
 }
 
-// SyncReleasesWithTags synchronizes release table with repository tags
+// SyncReleasesWithTags synchronizes release table with repository tags.
+// The tags matching setting.Repository.Release.SyncIgnoreTags are not added.
 func SyncReleasesWithTags(repo *repo_model.Repository, gitRepo *git.Repository) error {
 	existingRelTags := make(map[string]struct{})
 	opts := models.FindReleasesOptions{
@@ -263,6 +668,8 @@ func SyncReleasesWithTags(repo *repo_model.Repository, gitRepo *git.Repository)
 		}
 		for _, rel := range rels {
 			if rel.IsDraft {
+				// a draft may be created before its tag is pushed, it must not be published by the sync
+				existingRelTags[strings.ToLower(rel.TagName)] = struct{}{}
 				continue
 			}
 			commitID, err := gitRepo.GetTagCommitID(rel.TagName)
@@ -282,7 +689,11 @@ func SyncReleasesWithTags(repo *repo_model.Repository, gitRepo *git.Repository)
 	if err != nil {
 		return fmt.Errorf("unable to GetTags in Repo[%d:%s/%s]: %w", repo.ID, repo.OwnerName, repo.Name, err)
 	}
+	ignoredTags := releaseSyncIgnoredTags()
 	for _, tagName := range tags {
+		if isReleaseSyncIgnoredTag(ignoredTags, tagName) {
+			continue
+		}
 		if _, ok := existingRelTags[strings.ToLower(tagName)]; !ok {
 			if err := PushUpdateAddTag(repo, gitRepo, tagName); err != nil {
 				return fmt.Errorf("unable to PushUpdateAddTag: %q to Repo[%d:%s/%s]: %w", tagName, repo.ID, repo.OwnerName, repo.Name, err)
@@ -292,6 +703,34 @@ func SyncReleasesWithTags(repo *repo_model.Repository, gitRepo *git.Repository)
 	return nil
 }
 
+// releaseSyncIgnoredTags compiles the patterns of setting.Repository.Release.SyncIgnoreTags, invalid patterns are skipped
+func releaseSyncIgnoredTags() []glob.Glob {
+	globs := make([]glob.Glob, 0, len(setting.Repository.Release.SyncIgnoreTags))
+	for _, pattern := range setting.Repository.Release.SyncIgnoreTags {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			log.Warn("Invalid SYNC_IGNORE_TAGS pattern %q (skipped): %v", pattern, err)
+			continue
+		}
+		globs = append(globs, g)
+	}
+	return globs
+}
+
+// isReleaseSyncIgnoredTag returns whether the tag matches one of the ignored patterns
+func isReleaseSyncIgnoredTag(ignoredTags []glob.Glob, tagName string) bool {
+	for _, g := range ignoredTags {
+		if g.Match(tagName) {
+			return true
+		}
+	}
+	return false
+}
+
 // PushUpdateAddTag must be called for any push actions to add tag
 func PushUpdateAddTag(repo *repo_model.Repository, gitRepo *git.Repository, tagName string) error {
 	tag, err := gitRepo.GetTag(tagName)
@@ -303,21 +742,15 @@ func PushUpdateAddTag(repo *repo_model.Repository, gitRepo *git.Repository, tagN
 		return fmt.Errorf("unable to get tag Commit: %w", err)
 	}
 
-	sig := tag.Tagger
-	if sig == nil {
-		sig = commit.Author
-	}
-	if sig == nil {
-		sig = commit.Committer
-	}
+	sig := tagSignature(tag, commit)
 
 	var author *user_model.User
 	createdAt := time.Unix(1, 0)
 
 	if sig != nil {
-		author, err = user_model.GetUserByEmail(sig.Email)
-		if err != nil && !user_model.IsErrUserNotExist(err) {
-			return fmt.Errorf("unable to GetUserByEmail for %q: %w", sig.Email, err)
+		author, err = GetPublisherByEmail(sig.Email)
+		if err != nil {
+			return err
 		}
 		createdAt = sig.When
 	}
@@ -335,6 +768,7 @@ func PushUpdateAddTag(repo *repo_model.Repository, gitRepo *git.Repository, tagN
 		NumCommits:   commitsCount,
 		CreatedUnix:  timeutil.TimeStamp(createdAt.Unix()),
 		IsTag:        true,
+		IsPrerelease: IsPrereleaseTag(tagName),
 	}
 	if author != nil {
 		rel.PublisherID = author.ID
@@ -343,35 +777,298 @@ func PushUpdateAddTag(repo *repo_model.Repository, gitRepo *git.Repository, tagN
 	return models.SaveOrUpdateTag(repo, &rel)
 }
 
-// StoreMissingLfsObjectsInRepository downloads missing LFS objects
-func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Repository, gitRepo *git.Repository, lfsClient lfs.Client) error {
+// GetPublisherByEmail returns the user a release published with the given email is attributed to,
+// or nil if there is no such user
+func GetPublisherByEmail(email string) (*user_model.User, error) {
+	if email == "" {
+		return nil, nil
+	}
+	u, err := user_model.GetUserByEmail(email)
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to GetUserByEmail for %q: %w", email, err)
+	}
+	return u, nil
+}
+
+// IsPrereleaseTag returns whether the tag name ends with one of setting.Repository.Release.PrereleaseTagSuffixes,
+// optionally followed by a version number, e.g. "v1.0.0-rc1" or "v2.0-beta.2" for the suffixes "-rc" and "-beta"
+func IsPrereleaseTag(tagName string) bool {
+	lowerName := strings.ToLower(tagName)
+	for _, suffix := range setting.Repository.Release.PrereleaseTagSuffixes {
+		suffix = strings.ToLower(strings.TrimSpace(suffix))
+		if len(suffix) == 0 {
+			continue
+		}
+		idx := strings.LastIndex(lowerName, suffix)
+		if idx < 0 {
+			continue
+		}
+		if strings.Trim(lowerName[idx+len(suffix):], "0123456789.") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTagTime returns the date of a tag as used for the release created from it: the date of the tagger
+// of an annotated tag, otherwise the one of the author or committer of the tagged commit
+func GetTagTime(gitRepo *git.Repository, tagName string) (time.Time, error) {
+	tag, err := gitRepo.GetTag(tagName)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to GetTag: %w", err)
+	}
+	commit, err := tag.Commit(gitRepo)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to get tag Commit: %w", err)
+	}
+	if sig := tagSignature(tag, commit); sig != nil {
+		return sig.When, nil
+	}
+	return time.Unix(1, 0), nil
+}
+
+func tagSignature(tag *git.Tag, commit *git.Commit) *git.Signature {
+	if tag.Tagger != nil {
+		return tag.Tagger
+	}
+	if commit.Author != nil {
+		return commit.Author
+	}
+	return commit.Committer
+}
+
+// migrationLFSMaxFileSize returns the maximum size of LFS objects downloaded by a migration, which may
+// override LFS_MAX_FILE_SIZE up to the ceiling of the migration settings
+func migrationLFSMaxFileSize(override int64) int64 {
+	if override <= 0 || setting.Migrations.LFSMaxFileSizeCeiling <= 0 {
+		return setting.LFS.MaxFileSize
+	}
+	if override > setting.Migrations.LFSMaxFileSizeCeiling {
+		log.Warn("The LFS maximum file size %d of the migration exceeds the ceiling of %d", override, setting.Migrations.LFSMaxFileSizeCeiling)
+		return setting.Migrations.LFSMaxFileSizeCeiling
+	}
+	return override
+}
+
+// migrationLFSEndpoint returns the LFS endpoint of a migrated repository. An explicit endpoint of the options
+// is preferred over the URL of a committed .lfsconfig, which is preferred over the endpoint of the clone address.
+func migrationLFSEndpoint(ctx context.Context, repoPath string, opts migration.MigrateOptions) *url.URL {
+	if len(opts.LFSEndpoint) > 0 {
+		return lfs.DetermineEndpoint(opts.CloneAddr, opts.LFSEndpoint)
+	}
+	if endpoint := lfsConfigEndpoint(ctx, repoPath, opts.CloneAddr); endpoint != nil {
+		return endpoint
+	}
+	return lfs.DetermineEndpoint(opts.CloneAddr, "")
+}
+
+// lfsConfigEndpoint returns the lfs.url of the .lfsconfig committed to HEAD or nil if there is none.
+// The file is provided by the migration source, so only HTTP(S) URLs are used. The credentials of
+// the clone address are used for an endpoint with the same scheme and host.
+func lfsConfigEndpoint(ctx context.Context, repoPath, cloneAddr string) *url.URL {
+	stdout, err := git.NewCommand(ctx, "config", "--blob", "HEAD:.lfsconfig", "--get", "lfs.url").RunInDir(repoPath)
+	if err != nil {
+		// there is no .lfsconfig or it doesn't configure an URL
+		return nil
+	}
+	endpoint, err := url.Parse(strings.TrimSpace(stdout))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+		log.Warn("Ignoring the LFS URL of the .lfsconfig in %s which is not a HTTP(S) URL", repoPath)
+		return nil
+	}
+	if cloneURL, err := url.Parse(cloneAddr); err == nil && cloneURL.User != nil && endpoint.User == nil &&
+		strings.EqualFold(cloneURL.Scheme, endpoint.Scheme) && strings.EqualFold(cloneURL.Host, endpoint.Host) {
+		endpoint.User = cloneURL.User
+	}
+	return endpoint
+}
+
+// lfsObjectPool serializes storing the same LFS object, concurrent migrations may download it for different repositories
+var lfsObjectPool = sync.NewExclusivePool()
+
+// LFSProgress is the progress of StoreMissingLfsObjectsInRepositoryWithProgress
+type LFSProgress struct {
+	// TotalObjects and TotalBytes are the number and size of all LFS objects of the repository,
+	// they are 0 unless LFSProgressOptions.CountTotal is set
+	TotalObjects int64
+	TotalBytes   int64
+	// ProcessedObjects and ProcessedBytes are the number and size of the objects which are stored or skipped
+	ProcessedObjects int64
+	ProcessedBytes   int64
+	Elapsed          time.Duration
+}
+
+// Percent returns the processed percentage of the total size, or -1 if the total is unknown
+func (p LFSProgress) Percent() float64 {
+	if p.TotalBytes <= 0 {
+		if p.TotalObjects <= 0 {
+			return -1
+		}
+		return float64(p.ProcessedObjects) * 100 / float64(p.TotalObjects)
+	}
+	return float64(p.ProcessedBytes) * 100 / float64(p.TotalBytes)
+}
+
+// ETA estimates the remaining time from the rate the objects have been processed so far, it is 0 if that is unknown
+func (p LFSProgress) ETA() time.Duration {
+	percent := p.Percent()
+	if percent <= 0 || percent >= 100 {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * (100 - percent) / percent)
+}
+
+// LFSProgressOptions configures the progress reporting of StoreMissingLfsObjectsInRepositoryWithProgress
+type LFSProgressOptions struct {
+	// CountTotal enumerates all LFS pointers before the download to know the total, this walks the tree twice
+	CountTotal bool
+	// Callback is called with the progress at most every Interval and once all objects have been processed
+	Callback func(LFSProgress)
+	Interval time.Duration
+}
+
+// lfsProgressReporter reports the progress of StoreMissingLfsObjectsInRepositoryWithProgress to the callback
+type lfsProgressReporter struct {
+	opts       LFSProgressOptions
+	progress   LFSProgress
+	start      time.Time
+	lastReport time.Time
+}
+
+func (r *lfsProgressReporter) processed(p lfs.Pointer) {
+	if r.opts.Callback == nil {
+		return
+	}
+	r.progress.ProcessedObjects++
+	r.progress.ProcessedBytes += p.Size
+	if now := time.Now(); now.Sub(r.lastReport) >= r.opts.Interval {
+		r.lastReport = now
+		r.report()
+	}
+}
+
+func (r *lfsProgressReporter) report() {
+	if r.opts.Callback == nil {
+		return
+	}
+	r.progress.Elapsed = time.Since(r.start)
+	r.opts.Callback(r.progress)
+}
+
+// lfsProgressLogInterval is the interval the progress of the LFS download of a migration is logged with
+const lfsProgressLogInterval = 30 * time.Second
+
+// logLFSProgress returns a progress callback logging the progress of the LFS download of the repository
+func logLFSProgress(repo *repo_model.Repository) func(LFSProgress) {
+	return func(p LFSProgress) {
+		if p.TotalObjects == 0 {
+			log.Info("Repo[%-v]: %d LFS objects processed", repo, p.ProcessedObjects)
+			return
+		}
+		log.Info("Repo[%-v]: %d of %d LFS objects processed (%.1f%%), ETA %v", repo, p.ProcessedObjects, p.TotalObjects, p.Percent(), p.ETA().Round(time.Second))
+	}
+}
+
+// countLFSPointers enumerates all LFS pointers of the repository and returns their number and total size
+func countLFSPointers(ctx context.Context, gitRepo *git.Repository) (int64, int64, error) {
+	pointerChan := make(chan lfs.PointerBlob, setting.LFSClient.PointerChannelBuffer)
+	errChan := make(chan error, 1)
+	go lfs.SearchPointerBlobs(ctx, gitRepo, pointerChan, errChan)
+
+	var objects, size int64
+	for pointerBlob := range pointerChan {
+		objects++
+		size += pointerBlob.Size
+	}
+	if err, has := <-errChan; has {
+		return 0, 0, err
+	}
+	return objects, size, nil
+}
+
+// StoreMissingLfsObjectsInRepository downloads missing LFS objects which are not larger than maxFileSize, 0 means no limit
+func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Repository, gitRepo *git.Repository, lfsClient lfs.Client, maxFileSize int64) error {
+	return StoreMissingLfsObjectsInRepositoryWithProgress(ctx, repo, gitRepo, lfsClient, maxFileSize, LFSProgressOptions{})
+}
+
+// StoreMissingLfsObjectsInRepositoryWithProgress works like StoreMissingLfsObjectsInRepository and additionally
+// reports the progress of the download to the callback of the options
+func StoreMissingLfsObjectsInRepositoryWithProgress(ctx context.Context, repo *repo_model.Repository, gitRepo *git.Repository, lfsClient lfs.Client, maxFileSize int64, progressOpts LFSProgressOptions) error {
 	contentStore := lfs.NewContentStore()
 
-	pointerChan := make(chan lfs.PointerBlob)
+	progress := &lfsProgressReporter{opts: progressOpts, start: time.Now()}
+	if progressOpts.Callback != nil && progressOpts.CountTotal {
+		var err error
+		if progress.progress.TotalObjects, progress.progress.TotalBytes, err = countLFSPointers(ctx, gitRepo); err != nil {
+			log.Error("Repo[%-v]: Error counting LFS objects: %v", repo, err)
+			return err
+		}
+		progress.report()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pointerChan := make(chan lfs.PointerBlob, setting.LFSClient.PointerChannelBuffer)
 	errChan := make(chan error, 1)
 	go lfs.SearchPointerBlobs(ctx, gitRepo, pointerChan, errChan)
+	defer func() {
+		// Stop the enumeration and wait for it to finish on every return path,
+		// otherwise an early return would leave it blocked on pointerChan
+		cancel()
+		for range pointerChan {
+		}
+		for range errChan {
+		}
+	}()
+
+	// failed collects the objects whose download is retried at the end if retries are enabled
+	var failed []lfs.Pointer
+	var lastErr error
+	retryFailed := setting.LFSClient.FailedObjectRetries > 0
 
+	var throttle lfs.BatchThrottle
 	downloadObjects := func(pointers []lfs.Pointer) error {
+		if err := throttle.Wait(ctx); err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(pointers))
 		err := lfsClient.Download(ctx, pointers, func(p lfs.Pointer, content io.ReadCloser, objectError error) error {
 			if objectError != nil {
+				if retryFailed {
+					log.Warn("Repo[%-v]: Error downloading LFS object %-v, retrying it later: %v", repo, p, objectError)
+					failed = append(failed, p)
+					lastErr = objectError
+					done[p.Oid] = true
+					return nil
+				}
 				return objectError
 			}
 
 			defer content.Close()
 
-			_, err := models.NewLFSMetaObject(&models.LFSMetaObject{Pointer: p, RepositoryID: repo.ID})
+			meta, err := models.NewLFSMetaObject(&models.LFSMetaObject{Pointer: p, RepositoryID: repo.ID})
 			if err != nil {
 				log.Error("Repo[%-v]: Error creating LFS meta object %-v: %v", repo, p, err)
 				return err
 			}
 
-			if err := contentStore.Put(p, content); err != nil {
+			lfsObjectPool.CheckIn(p.Oid)
+			err = contentStore.Put(p, content)
+			lfsObjectPool.CheckOut(p.Oid)
+			if err != nil {
 				log.Error("Repo[%-v]: Error storing content for LFS meta object %-v: %v", repo, p, err)
-				if _, err2 := models.RemoveLFSMetaObjectByOid(repo.ID, p.Oid); err2 != nil {
-					log.Error("Repo[%-v]: Error removing LFS meta object %-v: %v", repo, p, err2)
+				// Only remove the meta object if it has been created here, an existing one may still be in use
+				if !meta.Existing {
+					if _, err2 := models.RemoveLFSMetaObjectByOid(repo.ID, p.Oid); err2 != nil {
+						log.Error("Repo[%-v]: Error removing LFS meta object %-v: %v", repo, p, err2)
+					}
 				}
 				return err
 			}
+			done[p.Oid] = true
+			progress.processed(p)
 			return nil
 		})
 		if err != nil {
@@ -380,10 +1077,52 @@ func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Re
 				return nil
 			default:
 			}
+			if retryFailed {
+				// the whole batch failed, retry the objects which have not been handled yet
+				log.Warn("Repo[%-v]: Error downloading a batch of LFS objects, retrying it later: %v", repo, err)
+				for _, p := range pointers {
+					if !done[p.Oid] {
+						failed = append(failed, p)
+					}
+				}
+				lastErr = err
+				return nil
+			}
 		}
 		return err
 	}
 
+	downloadBatches := func(pointers []lfs.Pointer) error {
+		for len(pointers) > 0 {
+			n := lfsClient.BatchSize()
+			if n <= 0 || n > len(pointers) {
+				n = len(pointers)
+			}
+			if err := downloadObjects(pointers[:n]); err != nil {
+				return err
+			}
+			pointers = pointers[n:]
+		}
+		return nil
+	}
+
+	// the content of stored objects is shared by all repositories, for these only the meta objects are created
+	var stored []lfs.Pointer
+	createStoredMetaObjects := func() error {
+		if err := models.NewLFSMetaObjects(repo.ID, stored); err != nil {
+			log.Error("Repo[%-v]: Error creating %d LFS meta objects: %v", repo, len(stored), err)
+			return err
+		}
+		stored = nil
+		return nil
+	}
+	// the repository size counts the meta objects, so they are created for the stored objects even if a download fails
+	defer func() {
+		if len(stored) > 0 {
+			_ = createStoredMetaObjects()
+		}
+	}()
+
 	var batch []lfs.Pointer
 	for pointerBlob := range pointerChan {
 		meta, err := models.GetLFSMetaObjectByOid(repo.ID, pointerBlob.Oid)
@@ -393,6 +1132,7 @@ func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Re
 		}
 		if meta != nil {
 			log.Trace("Repo[%-v]: Skipping unknown LFS meta object %-v", repo, pointerBlob.Pointer)
+			progress.processed(pointerBlob.Pointer)
 			continue
 		}
 
@@ -406,14 +1146,17 @@ func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Re
 
 		if exist {
 			log.Trace("Repo[%-v]: LFS object %-v already present; creating meta object", repo, pointerBlob.Pointer)
-			_, err := models.NewLFSMetaObject(&models.LFSMetaObject{Pointer: pointerBlob.Pointer, RepositoryID: repo.ID})
-			if err != nil {
-				log.Error("Repo[%-v]: Error creating LFS meta object %-v: %v", repo, pointerBlob.Pointer, err)
-				return err
+			stored = append(stored, pointerBlob.Pointer)
+			progress.processed(pointerBlob.Pointer)
+			if len(stored) >= setting.Database.IterateBufferSize {
+				if err := createStoredMetaObjects(); err != nil {
+					return err
+				}
 			}
 		} else {
-			if setting.LFS.MaxFileSize > 0 && pointerBlob.Size > setting.LFS.MaxFileSize {
-				log.Info("Repo[%-v]: LFS object %-v download denied because of LFS_MAX_FILE_SIZE=%d < size %d", repo, pointerBlob.Pointer, setting.LFS.MaxFileSize, pointerBlob.Size)
+			if maxFileSize > 0 && pointerBlob.Size > maxFileSize {
+				log.Info("Repo[%-v]: LFS object %-v download denied because of the maximum file size %d < size %d", repo, pointerBlob.Pointer, maxFileSize, pointerBlob.Size)
+				progress.processed(pointerBlob.Pointer)
 				continue
 			}
 
@@ -431,6 +1174,9 @@ func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Re
 			return err
 		}
 	}
+	if err := createStoredMetaObjects(); err != nil {
+		return err
+	}
 
 	err, has := <-errChan
 	if has {
@@ -438,5 +1184,25 @@ func StoreMissingLfsObjectsInRepository(ctx context.Context, repo *repo_model.Re
 		return err
 	}
 
+	for attempt := 1; len(failed) > 0 && attempt <= setting.LFSClient.FailedObjectRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * setting.LFSClient.FailedObjectRetryBackoff):
+		}
+
+		log.Info("Repo[%-v]: Retrying the download of %d LFS objects (attempt %d of %d)", repo, len(failed), attempt, setting.LFSClient.FailedObjectRetries)
+		retry := failed
+		failed = nil
+		if err := downloadBatches(retry); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		log.Error("Repo[%-v]: Failed to download %d LFS objects: %v", repo, len(failed), lastErr)
+		return fmt.Errorf("failed to download %d LFS objects: %w", len(failed), lastErr)
+	}
+
+	progress.report()
 	return nil
 }
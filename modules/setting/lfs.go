@@ -26,6 +26,22 @@ var LFS = struct {
 	Storage
 }{}
 
+// LFSClient represents the configuration of the LFS client used by mirroring and migrations
+var LFSClient = struct {
+	PointerChannelBuffer     int           `ini:"POINTER_CHANNEL_BUFFER"`
+	CAFile                   string        `ini:"CA_FILE"`
+	FailedObjectRetries      int           `ini:"FAILED_OBJECT_RETRIES"`
+	FailedObjectRetryBackoff time.Duration `ini:"FAILED_OBJECT_RETRY_BACKOFF"`
+	SearchPointerWorkers     int           `ini:"SEARCH_POINTER_WORKERS"`
+	BatchDelay               time.Duration `ini:"BATCH_DELAY"`
+	// CountObjectsBeforeMigration counts the LFS objects of migrated repositories to log the progress with an ETA
+	CountObjectsBeforeMigration bool `ini:"COUNT_OBJECTS_BEFORE_MIGRATION"`
+}{
+	PointerChannelBuffer:     100,
+	FailedObjectRetryBackoff: 5 * time.Second,
+	SearchPointerWorkers:     1,
+}
+
 func newLFSService() {
 	sec := Cfg.Section("server")
 	if err := sec.MapTo(&LFS); err != nil {
@@ -50,6 +66,22 @@ func newLFSService() {
 
 	LFS.HTTPAuthExpiry = sec.Key("LFS_HTTP_AUTH_EXPIRY").MustDuration(20 * time.Minute)
 
+	if err := Cfg.Section("lfs_client").MapTo(&LFSClient); err != nil {
+		log.Fatal("Failed to map LFS client settings: %v", err)
+	}
+	if LFSClient.PointerChannelBuffer < 0 {
+		LFSClient.PointerChannelBuffer = 0
+	}
+	if LFSClient.FailedObjectRetries < 0 {
+		LFSClient.FailedObjectRetries = 0
+	}
+	if LFSClient.SearchPointerWorkers < 1 {
+		LFSClient.SearchPointerWorkers = 1
+	}
+	if LFSClient.BatchDelay < 0 {
+		LFSClient.BatchDelay = 0
+	}
+
 	if LFS.StartServer {
 		LFS.JWTSecretBytes = make([]byte, 32)
 		n, err := base64.RawURLEncoding.Decode(LFS.JWTSecretBytes, []byte(LFS.JWTSecretBase64))
@@ -11,10 +11,27 @@ var Migrations = struct {
 	AllowedDomains     string
 	BlockedDomains     string
 	AllowLocalNetworks bool
+	AllowedHostList    string
+	BlockedHostList    string
 	SkipTLSVerify      bool
+	WriteCommitGraph   bool
+	CloneMaxAttempts   int
+	// LFSMaxFileSizeCeiling is the largest LFS object size a migration may allow by overriding LFS.MaxFileSize
+	LFSMaxFileSizeCeiling int64
+	// BatchConcurrency is the number of repositories of a batch migration whose git data is migrated at the same time
+	BatchConcurrency int
+	// UnshallowSource fetches the missing history if the source of a migration is a shallow repository
+	UnshallowSource bool
+	// RequestsPerSecond caps the API requests of all running migrations to the source platforms, 0 disables the cap
+	RequestsPerSecond float64
+	// ArchiveMaxSize is the total size in MB the files of a repository archive may extract to, 0 disables the limit
+	ArchiveMaxSize int64
 }{
-	MaxAttempts:  3,
-	RetryBackoff: 3,
+	MaxAttempts:      3,
+	RetryBackoff:     3,
+	CloneMaxAttempts: 1,
+	BatchConcurrency: 1,
+	ArchiveMaxSize:   10240,
 }
 
 func newMigrationsService() {
@@ -25,5 +42,14 @@ func newMigrationsService() {
 	Migrations.AllowedDomains = sec.Key("ALLOWED_DOMAINS").MustString("")
 	Migrations.BlockedDomains = sec.Key("BLOCKED_DOMAINS").MustString("")
 	Migrations.AllowLocalNetworks = sec.Key("ALLOW_LOCALNETWORKS").MustBool(false)
+	Migrations.AllowedHostList = sec.Key("ALLOWED_HOST_LIST").MustString("")
+	Migrations.BlockedHostList = sec.Key("BLOCKED_HOST_LIST").MustString("")
 	Migrations.SkipTLSVerify = sec.Key("SKIP_TLS_VERIFY").MustBool(false)
+	Migrations.WriteCommitGraph = sec.Key("WRITE_COMMIT_GRAPH").MustBool(false)
+	Migrations.CloneMaxAttempts = sec.Key("CLONE_MAX_ATTEMPTS").MustInt(Migrations.CloneMaxAttempts)
+	Migrations.LFSMaxFileSizeCeiling = sec.Key("LFS_MAX_FILE_SIZE_CEILING").MustInt64(0)
+	Migrations.BatchConcurrency = sec.Key("BATCH_CONCURRENCY").MustInt(Migrations.BatchConcurrency)
+	Migrations.UnshallowSource = sec.Key("UNSHALLOW_SOURCE").MustBool(false)
+	Migrations.RequestsPerSecond = sec.Key("REQUESTS_PER_SECOND").MustFloat64(0)
+	Migrations.ArchiveMaxSize = sec.Key("ARCHIVE_MAX_SIZE").MustInt64(Migrations.ArchiveMaxSize)
 }
@@ -5,6 +5,9 @@
 package setting
 
 import (
+	"fmt"
+	"path"
+	"path/filepath"
 	"time"
 
 	"code.gitea.io/gitea/modules/log"
@@ -17,12 +20,33 @@ var Mirror = struct {
 	DisableNewPush  bool
 	DefaultInterval time.Duration
 	MinInterval     time.Duration
+	BundlePath      string
+
+	CheckUnrelatedPushRemote bool
+	PushSyncLogLength        int
+	PushAtomic               bool
+	// Maintenance pauses the synchronization of all pull and push mirrors
+	Maintenance bool
+	// PushNotifyWebhookURL is the chat webhook notified about push mirrors without their own webhook
+	PushNotifyWebhookURL string `ini:"-"`
+	// AutoGCInterval is the number of syncs of a mirror after which `git gc --auto` runs, 0 disables it
+	AutoGCInterval int `ini:"AUTO_GC_INTERVAL"`
+	// MaxPushTimeout is the largest timeout in seconds a push mirror may set for its syncs
+	MaxPushTimeout int64
+	// AllowSameInstancePush allows push mirrors to push to repositories of this instance
+	AllowSameInstancePush bool
+	// PushRemoteNames is the naming strategy of new push mirror remotes, "random" or "host"
+	PushRemoteNames string
 }{
-	Enabled:         true,
-	DisableNewPull:  false,
-	DisableNewPush:  false,
-	MinInterval:     10 * time.Minute,
-	DefaultInterval: 8 * time.Hour,
+	Enabled:           true,
+	DisableNewPull:    false,
+	DisableNewPush:    false,
+	MinInterval:       10 * time.Minute,
+	DefaultInterval:   8 * time.Hour,
+	PushSyncLogLength: 10,
+	PushAtomic:        true,
+	MaxPushTimeout:    3600,
+	PushRemoteNames:   "random",
 }
 
 func newMirror() {
@@ -38,11 +62,25 @@ func newMirror() {
 		log.Fatal("Failed to map Mirror settings: %v", err)
 	}
 
+	Mirror.BundlePath = Cfg.Section("mirror").Key("BUNDLE_PATH").MustString(path.Join(AppDataPath, "mirror-bundles"))
+	forcePathSeparator(Mirror.BundlePath)
+	Mirror.PushNotifyWebhookURL = Cfg.Section("mirror").Key("PUSH_NOTIFY_WEBHOOK_URL").MustString("")
+	if !filepath.IsAbs(Mirror.BundlePath) {
+		Mirror.BundlePath = filepath.Join(AppWorkPath, Mirror.BundlePath)
+	} else {
+		Mirror.BundlePath = filepath.Clean(Mirror.BundlePath)
+	}
+
 	if !Mirror.Enabled {
 		Mirror.DisableNewPull = true
 		Mirror.DisableNewPush = true
 	}
 
+	if Mirror.PushRemoteNames != "random" && Mirror.PushRemoteNames != "host" {
+		log.Warn("Unknown Mirror.PushRemoteNames %q, set to random", Mirror.PushRemoteNames)
+		Mirror.PushRemoteNames = "random"
+	}
+
 	if Mirror.MinInterval.Minutes() < 1 {
 		log.Warn("Mirror.MinInterval is too low, set to 1 minute")
 		Mirror.MinInterval = 1 * time.Minute
@@ -56,3 +94,22 @@ func newMirror() {
 		log.Warn("Mirror.DefaultInterval is less than Mirror.MinInterval, set to %s", Mirror.DefaultInterval.String())
 	}
 }
+
+// ParseMirrorInterval parses the update interval of a pull or push mirror. An empty string
+// results in the default interval and zero disables the periodic updates.
+func ParseMirrorInterval(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return Mirror.DefaultInterval, nil
+	}
+	interval, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("interval %s must not be negative", interval)
+	}
+	if interval != 0 && interval < Mirror.MinInterval {
+		return 0, fmt.Errorf("interval %s is set below the minimum interval of %s", interval, Mirror.MinInterval)
+	}
+	return interval, nil
+}
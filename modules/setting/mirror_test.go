@@ -0,0 +1,38 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMirrorInterval(t *testing.T) {
+	defer func(minInterval, defaultInterval time.Duration) {
+		Mirror.MinInterval = minInterval
+		Mirror.DefaultInterval = defaultInterval
+	}(Mirror.MinInterval, Mirror.DefaultInterval)
+	Mirror.MinInterval = 10 * time.Minute
+	Mirror.DefaultInterval = 8 * time.Hour
+
+	for s, expected := range map[string]time.Duration{
+		"":    8 * time.Hour,
+		"0":   0,
+		"0s":  0,
+		"10m": 10 * time.Minute,
+		"2h":  2 * time.Hour,
+	} {
+		interval, err := ParseMirrorInterval(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, interval, s)
+	}
+
+	for _, s := range []string{"5m", "1s", "-1h", "daily"} {
+		_, err := ParseMirrorInterval(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -86,8 +86,10 @@ var (
 		} `ini:"repository.issue"`
 
 		Release struct {
-			AllowedTypes     string
-			DefaultPagingNum int
+			AllowedTypes          string
+			DefaultPagingNum      int
+			PrereleaseTagSuffixes []string
+			SyncIgnoreTags        []string
 		} `ini:"repository.release"`
 
 		Signing struct {
@@ -222,11 +224,15 @@ var (
 		},
 
 		Release: struct {
-			AllowedTypes     string
-			DefaultPagingNum int
+			AllowedTypes          string
+			DefaultPagingNum      int
+			PrereleaseTagSuffixes []string
+			SyncIgnoreTags        []string
 		}{
-			AllowedTypes:     "",
-			DefaultPagingNum: 10,
+			AllowedTypes:          "",
+			DefaultPagingNum:      10,
+			PrereleaseTagSuffixes: []string{},
+			SyncIgnoreTags:        []string{},
 		},
 
 		// Signing settings
@@ -234,6 +234,8 @@ func handleMigrateError(ctx *context.APIContext, repoOwner *user_model.User, rem
 		ctx.Error(http.StatusUnprocessableEntity, "", err)
 	case base.IsErrNotSupported(err):
 		ctx.Error(http.StatusUnprocessableEntity, "", err)
+	case base.IsErrInvalidMigrateOptions(err):
+		ctx.Error(http.StatusUnprocessableEntity, "", err)
 	default:
 		err = util.NewStringURLSanitizedError(err, remoteAddr, true)
 		if strings.Contains(err.Error(), "Authentication failed") ||
@@ -21,6 +21,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
+	mirror_service "code.gitea.io/gitea/services/mirror"
 	repo_service "code.gitea.io/gitea/services/repository"
 )
 
@@ -112,6 +113,13 @@ func HookPostReceive(ctx *gitea_context.PrivateContext) {
 				Err: fmt.Sprintf("Failed to Update: %s/%s Error: %v", ownerName, repoName, err),
 			})
 		}
+
+		// the push mirrors of the repository must not push back to the repositories this push was mirrored from
+		if sources, ok := opts.GitPushOptions[private.GitPushOptionPushMirror]; ok && !opts.IsWiki {
+			if err := mirror_service.RecordPushMirrorSources(ctx, repo, sources); err != nil {
+				log.Error("Failed to record the push mirror sources of %s/%s: %v", ownerName, repoName, err)
+			}
+		}
 	}
 
 	results := make([]private.HookPostReceiveBranchResult, 0, len(opts.OldCommitIDs))
@@ -12,7 +12,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"code.gitea.io/gitea/models"
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
@@ -33,7 +32,6 @@ import (
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/typesniffer"
-	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/validation"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/utils"
@@ -65,6 +63,7 @@ func Settings(ctx *context.Context) {
 	ctx.Data["MirrorsEnabled"] = setting.Mirror.Enabled
 	ctx.Data["DisableNewPushMirrors"] = setting.Mirror.DisableNewPush
 	ctx.Data["DefaultMirrorInterval"] = setting.Mirror.DefaultInterval
+	ctx.Data["MaxPushMirrorTimeout"] = setting.Mirror.MaxPushTimeout
 
 	signing, _ := asymkey_service.SigningKey(ctx, ctx.Repo.Repository.RepoPath())
 	ctx.Data["SigningKeyAvailable"] = len(signing) > 0
@@ -187,8 +186,8 @@ func SettingsPost(ctx *context.Context) {
 		// as an error on the UI for this action
 		ctx.Data["Err_RepoName"] = nil
 
-		interval, err := time.ParseDuration(form.Interval)
-		if err != nil || (interval != 0 && interval < setting.Mirror.MinInterval) {
+		interval, err := setting.ParseMirrorInterval(form.Interval)
+		if err != nil {
 			ctx.Data["Err_Interval"] = true
 			ctx.RenderWithErr(ctx.Tr("repo.mirror_interval_invalid"), tplSettingsOptions, &form)
 		} else {
@@ -320,13 +319,25 @@ func SettingsPost(ctx *context.Context) {
 		// as an error on the UI for this action
 		ctx.Data["Err_RepoName"] = nil
 
-		interval, err := time.ParseDuration(form.PushMirrorInterval)
-		if err != nil || (interval != 0 && interval < setting.Mirror.MinInterval) {
+		interval, err := setting.ParseMirrorInterval(form.PushMirrorInterval)
+		if err != nil {
 			ctx.Data["Err_PushMirrorInterval"] = true
 			ctx.RenderWithErr(ctx.Tr("repo.mirror_interval_invalid"), tplSettingsOptions, &form)
 			return
 		}
 
+		if form.PushMirrorReuseCredentials && form.PushMirrorUsername == "" && form.PushMirrorPassword == "" {
+			user, err := mirror_service.PullMirrorCredentials(ctx, repo, form.PushMirrorAddress)
+			if err != nil {
+				ctx.ServerError("PullMirrorCredentials", err)
+				return
+			}
+			if user != nil {
+				form.PushMirrorUsername = user.Username()
+				form.PushMirrorPassword, _ = user.Password()
+			}
+		}
+
 		address, err := forms.ParseRemoteAddr(form.PushMirrorAddress, form.PushMirrorUsername, form.PushMirrorPassword)
 		if err == nil {
 			err = migrations.IsMigrateURLAllowed(address, ctx.Doer)
@@ -337,17 +348,63 @@ func SettingsPost(ctx *context.Context) {
 			return
 		}
 
-		remoteSuffix, err := util.CryptoRandomString(10)
+		if err := mirror_service.ValidatePushMirrorAddress(address); err != nil {
+			ctx.Data["Err_PushMirrorAddress"] = true
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.same_instance"), tplSettingsOptions, &form)
+			return
+		}
+
+		if err := mirror_service.ValidateTagFilter(form.PushMirrorTagFilter); err != nil {
+			ctx.Data["Err_PushMirrorTagFilter"] = true
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.tag_filter_invalid"), tplSettingsOptions, &form)
+			return
+		}
+
+		if _, err := mirror_service.ParseRefMapping(form.PushMirrorRefMapping); err != nil || (form.PushMirrorLFSOnly && strings.TrimSpace(form.PushMirrorRefMapping) != "") {
+			ctx.Data["Err_PushMirrorRefMapping"] = true
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.ref_mapping_invalid"), tplSettingsOptions, &form)
+			return
+		}
+
+		if err := mirror_service.ValidateNotifyWebhookURL(form.PushMirrorWebhookURL); err != nil {
+			ctx.Data["Err_PushMirrorWebhookURL"] = true
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.webhook_url_invalid"), tplSettingsOptions, &form)
+			return
+		}
+
+		if err := mirror_service.ValidatePushMirrorTimeout(form.PushMirrorTimeout); err != nil {
+			ctx.Data["Err_PushMirrorTimeout"] = true
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.timeout_invalid", setting.Mirror.MaxPushTimeout), tplSettingsOptions, &form)
+			return
+		}
+
+		if form.PushMirrorLFSOnly && !setting.LFS.StartServer {
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.lfs_only_disabled"), tplSettingsOptions, &form)
+			return
+		}
+		if form.PushMirrorLFSOnly && form.PushMirrorSkipLFS {
+			ctx.RenderWithErr(ctx.Tr("repo.settings.mirror_settings.push_mirror.skip_lfs_invalid"), tplSettingsOptions, &form)
+			return
+		}
+
+		remoteName, err := mirror_service.NewPushMirrorRemoteName(ctx, repo, address)
 		if err != nil {
-			ctx.ServerError("RandomString", err)
+			ctx.ServerError("NewPushMirrorRemoteName", err)
 			return
 		}
 
 		m := &repo_model.PushMirror{
 			RepoID:     repo.ID,
 			Repo:       repo,
-			RemoteName: fmt.Sprintf("remote_mirror_%s", remoteSuffix),
+			RemoteName: remoteName,
 			Interval:   interval,
+			TagFilter:  form.PushMirrorTagFilter,
+			LFSOnly:    form.PushMirrorLFSOnly,
+			SkipLFS:    form.PushMirrorSkipLFS,
+			RefMapping: form.PushMirrorRefMapping,
+
+			NotifyWebhookURL: form.PushMirrorWebhookURL,
+			TimeoutSeconds:   form.PushMirrorTimeout,
 		}
 		if err := repo_model.InsertPushMirror(m); err != nil {
 			ctx.ServerError("InsertPushMirror", err)
@@ -13,6 +13,7 @@ import (
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/updatechecker"
+	mirror_service "code.gitea.io/gitea/services/mirror"
 	repo_service "code.gitea.io/gitea/services/repository"
 	archiver_service "code.gitea.io/gitea/services/repository/archiver"
 	user_service "code.gitea.io/gitea/services/user"
@@ -62,6 +63,26 @@ func registerGarbageCollectRepositories() {
 	})
 }
 
+func registerPrefetchMirrors() {
+	type PrefetchMirrorsConfig struct {
+		BaseConfig
+		Timeout time.Duration
+		MinSize int64
+	}
+	RegisterTaskFatal("prefetch_mirrors", &PrefetchMirrorsConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    false,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+		},
+		Timeout: time.Duration(setting.Git.Timeout.Mirror) * time.Second,
+		MinSize: 0,
+	}, func(ctx context.Context, _ *user_model.User, config Config) error {
+		pmConfig := config.(*PrefetchMirrorsConfig)
+		return mirror_service.PrefetchMirrors(ctx, pmConfig.MinSize, pmConfig.Timeout)
+	})
+}
+
 func registerRewriteAllPublicKeys() {
 	RegisterTaskFatal("resync_all_sshkeys", &BaseConfig{
 		Enabled:    false,
@@ -158,6 +179,7 @@ func initExtendedTasks() {
 	registerDeleteInactiveUsers()
 	registerDeleteRepositoryArchives()
 	registerGarbageCollectRepositories()
+	registerPrefetchMirrors()
 	registerRewriteAllPublicKeys()
 	registerRewriteAllPrincipalKeys()
 	registerRepositoryUpdateHook()
@@ -113,23 +113,30 @@ func ParseRemoteAddr(remoteAddr, authUsername, authPassword string) (string, err
 
 // RepoSettingForm form for changing repository settings
 type RepoSettingForm struct {
-	RepoName           string `binding:"Required;AlphaDashDot;MaxSize(100)"`
-	Description        string `binding:"MaxSize(255)"`
-	Website            string `binding:"ValidUrl;MaxSize(255)"`
-	Interval           string
-	MirrorAddress      string
-	MirrorUsername     string
-	MirrorPassword     string
-	LFS                bool   `form:"mirror_lfs"`
-	LFSEndpoint        string `form:"mirror_lfs_endpoint"`
-	PushMirrorID       string
-	PushMirrorAddress  string
-	PushMirrorUsername string
-	PushMirrorPassword string
-	PushMirrorInterval string
-	Private            bool
-	Template           bool
-	EnablePrune        bool
+	RepoName                   string `binding:"Required;AlphaDashDot;MaxSize(100)"`
+	Description                string `binding:"MaxSize(255)"`
+	Website                    string `binding:"ValidUrl;MaxSize(255)"`
+	Interval                   string
+	MirrorAddress              string
+	MirrorUsername             string
+	MirrorPassword             string
+	LFS                        bool   `form:"mirror_lfs"`
+	LFSEndpoint                string `form:"mirror_lfs_endpoint"`
+	PushMirrorID               string
+	PushMirrorAddress          string
+	PushMirrorUsername         string
+	PushMirrorPassword         string
+	PushMirrorInterval         string
+	PushMirrorTagFilter        string
+	PushMirrorLFSOnly          bool `form:"push_mirror_lfs_only"`
+	PushMirrorSkipLFS          bool `form:"push_mirror_skip_lfs"`
+	PushMirrorReuseCredentials bool `form:"push_mirror_reuse_credentials"`
+	PushMirrorRefMapping       string
+	PushMirrorWebhookURL       string `form:"push_mirror_webhook_url"`
+	PushMirrorTimeout          int64
+	Private                    bool
+	Template                   bool
+	EnablePrune                bool
 
 	// Advanced settings
 	EnableWiki                            bool
@@ -94,14 +94,14 @@ func NewCodebaseDownloader(ctx context.Context, projectURL *url.URL, project, re
 		project:    project,
 		repoName:   repoName,
 		client: &http.Client{
-			Transport: &http.Transport{
+			Transport: newRateLimitedTransport(&http.Transport{
 				Proxy: func(req *http.Request) (*url.URL, error) {
 					if len(username) > 0 && len(password) > 0 {
 						req.SetBasicAuth(username, password)
 					}
 					return proxy.Proxy()(req)
 				},
-			},
+			}),
 		},
 		userMap:   make(map[int64]*codebaseUser),
 		commitMap: make(map[string]string),
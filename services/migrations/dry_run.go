@@ -0,0 +1,241 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+	"code.gitea.io/gitea/modules/log"
+	base "code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// dryRunPageSize is the number of issues, pull requests and comments requested by a dry run if the
+// downloader can't count them
+const dryRunPageSize = 100
+
+// MigrationEstimate contains the numbers of items a migration would import.
+// Items which are not selected by the migration options or not supported by the source are counted as 0.
+type MigrationEstimate struct {
+	Topics        int
+	Milestones    int
+	Labels        int
+	Releases      int
+	ReleaseAssets int
+	Issues        int
+	PullRequests  int
+	Comments      int
+	LFSPointers   int
+	// Incomplete is set if the source has more issues, pull requests or comments than counted,
+	// only the first page of them is counted if the downloader can't count them
+	Incomplete bool
+}
+
+// DryRunMigration reads what a migration with the given options would import from the source and returns
+// the numbers of items, nothing is written to the database or the repositories
+func DryRunMigration(ctx context.Context, doer *user_model.User, ownerName string, opts base.MigrateOptions) (*MigrationEstimate, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(opts.ArchivePath) == 0 || len(opts.CloneAddr) > 0 {
+		if err := IsMigrateURLAllowed(opts.CloneAddr, doer); err != nil {
+			return nil, err
+		}
+	}
+
+	downloader, err := newDownloader(ctx, ownerName, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return estimateMigration(ctx, downloader, opts)
+}
+
+// estimateMigration counts the items migrateRepository would import, using only the read methods of the downloader
+func estimateMigration(ctx context.Context, downloader base.Downloader, opts base.MigrateOptions) (*MigrationEstimate, error) {
+	estimate := &MigrationEstimate{}
+
+	// notSupported ignores the errors of items the downloader doesn't support
+	notSupported := func(err error, items string) error {
+		if !base.IsErrNotSupported(err) {
+			return err
+		}
+		log.Warn("migrating %s is not supported, ignored", items)
+		return nil
+	}
+
+	units := opts.MigrateUnits() & downloader.SupportedUnits()
+
+	repo, err := downloader.GetRepoInfo()
+	if err != nil {
+		if err := notSupported(err, "repo infos"); err != nil {
+			return nil, err
+		}
+		repo = &base.Repository{}
+	}
+
+	topics, err := downloader.GetTopics()
+	if err != nil {
+		if err := notSupported(err, "topics"); err != nil {
+			return nil, err
+		}
+	}
+	estimate.Topics = len(topics)
+
+	if units.Has(base.MigrateUnitMilestones) {
+		milestones, err := downloader.GetMilestones()
+		if err != nil {
+			if err := notSupported(err, "milestones"); err != nil {
+				return nil, err
+			}
+		}
+		estimate.Milestones = len(milestones)
+	}
+
+	if units.Has(base.MigrateUnitLabels) {
+		labels, err := downloader.GetLabels()
+		if err != nil {
+			if err := notSupported(err, "labels"); err != nil {
+				return nil, err
+			}
+		}
+		estimate.Labels = len(labels)
+	}
+
+	if units.Has(base.MigrateUnitReleases) && !opts.SkipReleaseSync {
+		releases, err := downloader.GetReleases()
+		if err != nil {
+			if err := notSupported(err, "releases"); err != nil {
+				return nil, err
+			}
+		}
+		estimate.Releases = len(releases)
+		if units.Has(base.MigrateUnitReleaseAssets) {
+			for _, release := range releases {
+				estimate.ReleaseAssets += len(release.Assets)
+			}
+		}
+	}
+
+	// countItems counts the items with the CountingDownloader methods if the downloader implements them,
+	// otherwise only the first page of the items is counted
+	counter, canCount := innerDownloader(downloader).(base.CountingDownloader)
+	countItems := func(items string, count func() (int, error), firstPage func() (int, bool, error)) (int, error) {
+		if canCount {
+			n, err := count()
+			if err == nil {
+				return n, nil
+			} else if !base.IsErrNotSupported(err) {
+				return 0, err
+			}
+		}
+		n, isEnd, err := firstPage()
+		if err != nil {
+			return 0, notSupported(err, items)
+		}
+		if !isEnd {
+			estimate.Incomplete = true
+		}
+		return n, nil
+	}
+
+	if units.Has(base.MigrateUnitIssues) {
+		if estimate.Issues, err = countItems("issues", func() (int, error) {
+			return counter.CountIssues()
+		}, func() (int, bool, error) {
+			issues, isEnd, err := downloader.GetIssues(1, dryRunPageSize)
+			return len(issues), isEnd, err
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if units.Has(base.MigrateUnitPullRequests) {
+		if estimate.PullRequests, err = countItems("pull requests", func() (int, error) {
+			return counter.CountPullRequests()
+		}, func() (int, bool, error) {
+			prs, isEnd, err := downloader.GetPullRequests(1, dryRunPageSize)
+			return len(prs), isEnd, err
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if units.Has(base.MigrateUnitComments) {
+		if estimate.Comments, err = countItems("comments", func() (int, error) {
+			return counter.CountComments()
+		}, func() (int, bool, error) {
+			if !downloader.SupportGetRepoComments() {
+				// the comments would have to be requested issue by issue
+				return 0, estimate.Issues+estimate.PullRequests == 0, nil
+			}
+			comments, isEnd, err := downloader.GetAllComments(1, dryRunPageSize)
+			return len(comments), isEnd, err
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.LFS && len(opts.ArchivePath) == 0 {
+		cloneURL, err := downloader.FormatCloneURL(opts, repo.CloneURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCloneURLAllowed(downloader, cloneURL); err != nil {
+			return nil, err
+		}
+		if estimate.LFSPointers, err = countLFSPointers(ctx, cloneURL); err != nil {
+			return nil, err
+		}
+	}
+
+	return estimate, nil
+}
+
+// countLFSPointers counts the LFS pointers of a remote repository in a temporary clone which is removed afterwards
+func countLFSPointers(ctx context.Context, cloneURL string) (int, error) {
+	tmpDir, err := models.CreateTemporaryPath("migrate-dry-run")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpDir); err != nil {
+			log.Error("Unable to remove temporary directory %s: %v", tmpDir, err)
+		}
+	}()
+
+	if err := git.Clone(ctx, cloneURL, tmpDir, git.CloneRepoOptions{
+		Timeout:       time.Duration(setting.Git.Timeout.Migrate) * time.Second,
+		Bare:          true,
+		Quiet:         true,
+		SkipTLSVerify: setting.Migrations.SkipTLSVerify,
+	}); err != nil {
+		return 0, fmt.Errorf("Clone: %v", err)
+	}
+
+	gitRepo, err := git.OpenRepositoryCtx(ctx, tmpDir)
+	if err != nil {
+		return 0, err
+	}
+	defer gitRepo.Close()
+
+	pointerChan := make(chan lfs.PointerBlob, setting.LFSClient.PointerChannelBuffer)
+	errChan := make(chan error, 1)
+	go lfs.SearchPointerBlobs(ctx, gitRepo, pointerChan, errChan)
+
+	count := 0
+	for range pointerChan {
+		count++
+	}
+	if err, has := <-errChan; has {
+		return 0, err
+	}
+	return count, nil
+}
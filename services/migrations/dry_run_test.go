@@ -0,0 +1,212 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+	base "code.gitea.io/gitea/modules/migration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingDownloader pages through generated issues and pull requests and records the pages requested
+type countingDownloader struct {
+	base.NullDownloader
+	cloneURL      string
+	issues        int
+	pullRequests  int
+	commentsPer   int
+	allComments   bool
+	requestedPage map[string]int
+}
+
+func (d *countingDownloader) GetRepoInfo() (*base.Repository, error) {
+	return &base.Repository{Name: "estimate", CloneURL: d.cloneURL}, nil
+}
+
+func (d *countingDownloader) GetMilestones() ([]*base.Milestone, error) {
+	return []*base.Milestone{{Title: "v1"}, {Title: "v2"}}, nil
+}
+
+func (d *countingDownloader) GetLabels() ([]*base.Label, error) {
+	return []*base.Label{{Name: "bug"}}, nil
+}
+
+func (d *countingDownloader) GetReleases() ([]*base.Release, error) {
+	return []*base.Release{
+		{TagName: "v1", Assets: []*base.ReleaseAsset{{Name: "a"}, {Name: "b"}}},
+		{TagName: "v2"},
+	}, nil
+}
+
+func paged(total, page, perPage int) (int, bool) {
+	start := (page - 1) * perPage
+	if start >= total {
+		return 0, true
+	}
+	if start+perPage >= total {
+		return total - start, true
+	}
+	return perPage, false
+}
+
+func (d *countingDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, error) {
+	d.requestedPage["issues"] = page
+	n, isEnd := paged(d.issues, page, perPage)
+	issues := make([]*base.Issue, n)
+	for i := range issues {
+		issues[i] = &base.Issue{Number: int64((page-1)*perPage + i + 1)}
+	}
+	return issues, isEnd, nil
+}
+
+func (d *countingDownloader) GetPullRequests(page, perPage int) ([]*base.PullRequest, bool, error) {
+	d.requestedPage["pulls"] = page
+	n, isEnd := paged(d.pullRequests, page, perPage)
+	prs := make([]*base.PullRequest, n)
+	for i := range prs {
+		prs[i] = &base.PullRequest{Number: int64(d.issues + (page-1)*perPage + i + 1)}
+	}
+	return prs, isEnd, nil
+}
+
+func (d *countingDownloader) GetComments(commentable base.Commentable) ([]*base.Comment, bool, error) {
+	return make([]*base.Comment, d.commentsPer), true, nil
+}
+
+func (d *countingDownloader) SupportGetRepoComments() bool {
+	return d.allComments
+}
+
+func (d *countingDownloader) GetAllComments(page, perPage int) ([]*base.Comment, bool, error) {
+	n, isEnd := paged((d.issues+d.pullRequests)*d.commentsPer, page, perPage)
+	return make([]*base.Comment, n), isEnd, nil
+}
+
+// totalCountingDownloader counts the generated issues, pull requests and comments without paging through them
+type totalCountingDownloader struct {
+	countingDownloader
+}
+
+func (d *totalCountingDownloader) CountIssues() (int, error) {
+	return d.issues, nil
+}
+
+func (d *totalCountingDownloader) CountPullRequests() (int, error) {
+	return d.pullRequests, nil
+}
+
+func (d *totalCountingDownloader) CountComments() (int, error) {
+	return 0, base.ErrNotSupported{Entity: "Comments"}
+}
+
+// localCountingDownloader is a countingDownloader which may return a local clone URL
+type localCountingDownloader struct {
+	countingDownloader
+}
+
+func (d *localCountingDownloader) allowsLocalCloneURL() {}
+
+func TestEstimateMigration(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	opts := base.MigrateOptions{
+		Milestones:    true,
+		Labels:        true,
+		Releases:      true,
+		ReleaseAssets: true,
+		Issues:        true,
+		PullRequests:  true,
+		Comments:      true,
+	}
+
+	for _, allComments := range []bool{false, true} {
+		t.Run(fmt.Sprintf("AllComments=%v", allComments), func(t *testing.T) {
+			// without GetAllComments the comments aren't requested issue by issue
+			comments := 0
+			if allComments {
+				comments = 100
+			}
+			downloader := &countingDownloader{issues: 250, pullRequests: 30, commentsPer: 2, allComments: allComments, requestedPage: map[string]int{}}
+			estimate, err := estimateMigration(context.Background(), downloader, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, &MigrationEstimate{
+				Milestones:    2,
+				Labels:        1,
+				Releases:      2,
+				ReleaseAssets: 2,
+				Issues:        100,
+				PullRequests:  30,
+				Comments:      comments,
+				Incomplete:    true,
+			}, estimate)
+			assert.Equal(t, 1, downloader.requestedPage["issues"])
+			assert.Equal(t, 1, downloader.requestedPage["pulls"])
+		})
+	}
+
+	t.Run("Count", func(t *testing.T) {
+		downloader := &totalCountingDownloader{countingDownloader{issues: 250, pullRequests: 30, commentsPer: 2, allComments: true, requestedPage: map[string]int{}}}
+		estimate, err := estimateMigration(context.Background(), base.NewRetryDownloader(context.Background(), downloader, 1, 0), base.MigrateOptions{Issues: true, PullRequests: true, Comments: true})
+		assert.NoError(t, err)
+		assert.Equal(t, &MigrationEstimate{Issues: 250, PullRequests: 30, Comments: 100, Incomplete: true}, estimate)
+		assert.NotContains(t, downloader.requestedPage, "issues")
+		assert.NotContains(t, downloader.requestedPage, "pulls")
+	})
+
+	t.Run("Unselected", func(t *testing.T) {
+		downloader := &countingDownloader{issues: 10, commentsPer: 1, requestedPage: map[string]int{}}
+		estimate, err := estimateMigration(context.Background(), downloader, base.MigrateOptions{Issues: true})
+		assert.NoError(t, err)
+		assert.Equal(t, &MigrationEstimate{Issues: 10}, estimate)
+		assert.NotContains(t, downloader.requestedPage, "pulls")
+	})
+
+	t.Run("Units", func(t *testing.T) {
+		downloader := &countingDownloader{issues: 10, pullRequests: 5, commentsPer: 1, requestedPage: map[string]int{}}
+		estimate, err := estimateMigration(context.Background(), downloader, base.MigrateOptions{Units: base.MigrateUnitIssues | base.MigrateUnitReleases})
+		assert.NoError(t, err)
+		assert.Equal(t, &MigrationEstimate{Releases: 2, Issues: 10}, estimate)
+		assert.NotContains(t, downloader.requestedPage, "pulls")
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		estimate, err := estimateMigration(context.Background(), &base.NullDownloader{}, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, &MigrationEstimate{}, estimate)
+	})
+
+	t.Run("LFSPointers", func(t *testing.T) {
+		source := t.TempDir()
+		assert.NoError(t, git.InitRepository(git.DefaultContext, source, false))
+		for i := 0; i < 3; i++ {
+			p, err := lfs.GeneratePointer(strings.NewReader(fmt.Sprintf("LFS object %d", i)))
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(filepath.Join(source, fmt.Sprintf("object%d.bin", i)), []byte(p.StringContent()), 0o644))
+		}
+		assert.NoError(t, git.AddChanges(source, true))
+		signature := git.Signature{Email: "test@example.com", Name: "test", When: time.Now()}
+		assert.NoError(t, git.CommitChanges(source, git.CommitChangesOptions{Committer: &signature, Author: &signature, Message: "Add LFS pointers"}))
+
+		downloader := &localCountingDownloader{countingDownloader{cloneURL: source, requestedPage: map[string]int{}}}
+		estimate, err := estimateMigration(context.Background(), downloader, base.MigrateOptions{LFS: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, estimate.LFSPointers)
+
+		// only the downloaders which may return a local clone URL are allowed to
+		_, err = estimateMigration(context.Background(), &countingDownloader{cloneURL: source, requestedPage: map[string]int{}}, base.MigrateOptions{LFS: true})
+		assert.Error(t, err)
+	})
+}
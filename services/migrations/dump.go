@@ -44,6 +44,10 @@ type RepositoryDumper struct {
 	commentFiles    map[int64]*os.File
 	pullrequestFile *os.File
 	reviewFiles     map[int64]*os.File
+	statusFile      *os.File
+	trackedTimeFile *os.File
+
+	issueSubscriberFile *os.File
 
 	gitRepo     *git.Repository
 	prHeadCache map[string]struct{}
@@ -119,6 +123,7 @@ func (g *RepositoryDumper) CreateRepo(repo *base.Repository, opts base.MigrateOp
 		"clone_addr":   opts.CloneAddr,
 		"original_url": repo.OriginalURL,
 		"is_private":   opts.Private,
+		"is_archived":  repo.IsArchived,
 		"service_type": opts.GitServiceType,
 		"wiki":         opts.Wiki,
 		"issues":       opts.Issues,
@@ -212,6 +217,15 @@ func (g *RepositoryDumper) Close() {
 	for _, f := range g.reviewFiles {
 		f.Close()
 	}
+	if g.statusFile != nil {
+		g.statusFile.Close()
+	}
+	if g.trackedTimeFile != nil {
+		g.trackedTimeFile.Close()
+	}
+	if g.issueSubscriberFile != nil {
+		g.issueSubscriberFile.Close()
+	}
 }
 
 // CreateTopics creates topics
@@ -236,6 +250,11 @@ func (g *RepositoryDumper) CreateTopics(topics ...string) error {
 	return nil
 }
 
+// CreateRepoAvatar saves the avatar of the repository
+func (g *RepositoryDumper) CreateRepoAvatar(data []byte) error {
+	return os.WriteFile(filepath.Join(g.baseDir, "avatar"), data, 0o644)
+}
+
 // CreateMilestones creates milestones
 func (g *RepositoryDumper) CreateMilestones(milestones ...*base.Milestone) error {
 	var err error
@@ -541,6 +560,81 @@ func (g *RepositoryDumper) CreateReviews(reviews ...*base.Review) error {
 	return g.createItems(g.reviewDir(), g.reviewFiles, reviewsMap)
 }
 
+// CreateCommitStatuses creates commit statuses
+func (g *RepositoryDumper) CreateCommitStatuses(statuses ...*base.CommitStatus) error {
+	var err error
+	if g.statusFile == nil {
+		g.statusFile, err = os.Create(filepath.Join(g.baseDir, "commit_status.yml"))
+		if err != nil {
+			return err
+		}
+	}
+
+	bs, err := yaml.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.statusFile.Write(bs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateTrackedTimes creates the tracked times of issues and pull requests
+func (g *RepositoryDumper) CreateTrackedTimes(times ...*base.TrackedTime) error {
+	var err error
+	if g.trackedTimeFile == nil {
+		g.trackedTimeFile, err = os.Create(filepath.Join(g.baseDir, "tracked_time.yml"))
+		if err != nil {
+			return err
+		}
+	}
+
+	bs, err := yaml.Marshal(times)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.trackedTimeFile.Write(bs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateIssueSubscribers creates the subscribers of issues and pull requests
+func (g *RepositoryDumper) CreateIssueSubscribers(subscribers ...*base.IssueSubscriber) error {
+	var err error
+	if g.issueSubscriberFile == nil {
+		g.issueSubscriberFile, err = os.Create(filepath.Join(g.baseDir, "issue_subscriber.yml"))
+		if err != nil {
+			return err
+		}
+	}
+
+	bs, err := yaml.Marshal(subscribers)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.issueSubscriberFile.Write(bs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateWebhooks saves the webhooks of the repository
+func (g *RepositoryDumper) CreateWebhooks(webhooks ...*base.Webhook) error {
+	bs, err := yaml.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.baseDir, "webhook.yml"), bs, 0o644)
+}
+
 // Rollback when migrating failed, this will rollback all the changes.
 func (g *RepositoryDumper) Rollback() error {
 	g.Close()
@@ -554,7 +648,7 @@ func (g *RepositoryDumper) Finish() error {
 
 // DumpRepository dump repository according MigrateOptions to a local directory
 func DumpRepository(ctx context.Context, baseDir, ownerName string, opts base.MigrateOptions) error {
-	downloader, err := newDownloader(ctx, ownerName, opts)
+	downloader, err := newDownloader(ctx, ownerName, &opts)
 	if err != nil {
 		return err
 	}
@@ -47,3 +47,11 @@ func (g *PlainGitDownloader) GetRepoInfo() (*base.Repository, error) {
 func (g PlainGitDownloader) GetTopics() ([]string, error) {
 	return []string{}, nil
 }
+
+// allowsLocalCloneURL implements localCloneURLDownloader, the remote URL was checked by IsMigrateURLAllowed
+func (g *PlainGitDownloader) allowsLocalCloneURL() {}
+
+// SupportedUnits returns the units migrated from a plain git repository, only the wiki is cloned
+func (g *PlainGitDownloader) SupportedUnits() base.MigrateUnits {
+	return base.MigrateUnitWiki
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -75,6 +76,8 @@ type GiteaDownloader struct {
 	repoName   string
 	pagination bool
 	maxPerPage int
+	// httpClient downloads the release assets
+	httpClient *http.Client
 }
 
 // NewGiteaDownloader creates a gitea Downloader via gitea API
@@ -120,6 +123,7 @@ func NewGiteaDownloader(ctx context.Context, baseURL, repoPath, username, passwo
 		repoName:   path[1],
 		pagination: paginationSupport,
 		maxPerPage: maxPerPage,
+		httpClient: NewMigrationHTTPClient(),
 	}, nil
 }
 
@@ -139,6 +143,18 @@ func (g *GiteaDownloader) GetRepoInfo() (*base.Repository, error) {
 		return nil, err
 	}
 
+	var mergeSettings *base.MergeSettings
+	// older versions of Gitea don't return the allowed merge styles
+	if repo.HasPullRequests && (repo.AllowMerge || repo.AllowRebase || repo.AllowRebaseMerge || repo.AllowSquash) {
+		mergeSettings = &base.MergeSettings{
+			AllowMerge:        repo.AllowMerge,
+			AllowRebase:       repo.AllowRebase,
+			AllowRebaseMerge:  repo.AllowRebaseMerge,
+			AllowSquash:       repo.AllowSquash,
+			DefaultMergeStyle: string(repo.DefaultMergeStyle),
+		}
+	}
+
 	return &base.Repository{
 		Name:          repo.Name,
 		Owner:         repo.Owner.UserName,
@@ -147,6 +163,8 @@ func (g *GiteaDownloader) GetRepoInfo() (*base.Repository, error) {
 		CloneURL:      repo.CloneURL,
 		OriginalURL:   repo.HTMLURL,
 		DefaultBranch: repo.DefaultBranch,
+		IsArchived:    repo.Archived,
+		MergeSettings: mergeSettings,
 	}, nil
 }
 
@@ -156,6 +174,58 @@ func (g *GiteaDownloader) GetTopics() ([]string, error) {
 	return topics, err
 }
 
+// GetRepoAvatar returns the avatar of the repository or nil if it has none
+func (g *GiteaDownloader) GetRepoAvatar() (io.ReadCloser, error) {
+	repo, _, err := g.client.GetRepo(g.repoOwner, g.repoName)
+	if err != nil {
+		return nil, err
+	}
+	if repo.AvatarURL == "" {
+		return nil, nil
+	}
+	return downloadRepoAvatar(g.ctx, g.httpClient, repo.AvatarURL)
+}
+
+// GetWebhooks returns the webhooks of the repository
+func (g *GiteaDownloader) GetWebhooks() ([]*base.Webhook, error) {
+	webhooks := make([]*base.Webhook, 0, g.maxPerPage)
+
+	for i := 1; ; i++ {
+		// make sure gitea can shutdown gracefully
+		select {
+		case <-g.ctx.Done():
+			return nil, nil
+		default:
+		}
+
+		hooks, _, err := g.client.ListRepoHooks(g.repoOwner, g.repoName, gitea_sdk.ListHooksOptions{
+			ListOptions: gitea_sdk.ListOptions{
+				PageSize: g.maxPerPage,
+				Page:     i,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hook := range hooks {
+			webhooks = append(webhooks, &base.Webhook{
+				ID:           hook.ID,
+				URL:          hook.Config["url"],
+				Type:         hook.Type,
+				ContentType:  hook.Config["content_type"],
+				Events:       hook.Events,
+				BranchFilter: hook.Config["branch_filter"],
+				Active:       hook.Active,
+			})
+		}
+		if len(hooks) < g.maxPerPage {
+			break
+		}
+	}
+	return webhooks, nil
+}
+
 // GetMilestones returns milestones
 func (g *GiteaDownloader) GetMilestones() ([]*base.Milestone, error) {
 	milestones := make([]*base.Milestone, 0, g.maxPerPage)
@@ -266,9 +336,8 @@ func (g *GiteaDownloader) convertGiteaRelease(rel *gitea_sdk.Release) *base.Rele
 		Created:         rel.CreatedAt,
 	}
 
-	httpClient := NewMigrationHTTPClient()
-
 	for _, asset := range rel.Attachments {
+		assetID := asset.ID // Don't optimize this, for closure we need a local variable
 		size := int(asset.Size)
 		dlCount := int(asset.DownloadCount)
 		r.Assets = append(r.Assets, &base.ReleaseAsset{
@@ -279,7 +348,7 @@ func (g *GiteaDownloader) convertGiteaRelease(rel *gitea_sdk.Release) *base.Rele
 			Created:       asset.Created,
 			DownloadURL:   &asset.DownloadURL,
 			DownloadFunc: func() (io.ReadCloser, error) {
-				asset, _, err := g.client.GetReleaseAttachment(g.repoOwner, g.repoName, rel.ID, asset.ID)
+				asset, _, err := g.client.GetReleaseAttachment(g.repoOwner, g.repoName, rel.ID, assetID)
 				if err != nil {
 					return nil, err
 				}
@@ -288,7 +357,7 @@ func (g *GiteaDownloader) convertGiteaRelease(rel *gitea_sdk.Release) *base.Rele
 				if err != nil {
 					return nil, err
 				}
-				resp, err := httpClient.Do(req)
+				resp, err := g.httpClient.Do(req)
 				if err != nil {
 					return nil, err
 				}
@@ -373,6 +442,44 @@ func (g *GiteaDownloader) getCommentReactions(commentID int64) ([]*base.Reaction
 	return reactions, nil
 }
 
+// totalCount returns the total count of the listed items the API returns with a page
+func totalCount(resp *gitea_sdk.Response, entity string) (int, error) {
+	if resp == nil || resp.Response == nil || resp.Header.Get("X-Total-Count") == "" {
+		return 0, base.ErrNotSupported{Entity: entity}
+	}
+	return strconv.Atoi(resp.Header.Get("X-Total-Count"))
+}
+
+// CountIssues returns the count of the issues
+func (g *GiteaDownloader) CountIssues() (int, error) {
+	_, resp, err := g.client.ListRepoIssues(g.repoOwner, g.repoName, gitea_sdk.ListIssueOption{
+		ListOptions: gitea_sdk.ListOptions{Page: 1, PageSize: 1},
+		State:       gitea_sdk.StateAll,
+		Type:        gitea_sdk.IssueTypeIssue,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error while counting issues: %v", err)
+	}
+	return totalCount(resp, "Issues")
+}
+
+// CountPullRequests returns the count of the pull requests
+func (g *GiteaDownloader) CountPullRequests() (int, error) {
+	_, resp, err := g.client.ListRepoPullRequests(g.repoOwner, g.repoName, gitea_sdk.ListPullRequestsOptions{
+		ListOptions: gitea_sdk.ListOptions{Page: 1, PageSize: 1},
+		State:       gitea_sdk.StateAll,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error while counting pull requests: %v", err)
+	}
+	return totalCount(resp, "PullRequests")
+}
+
+// CountComments returns ErrNotSupported, the comments can only be listed issue by issue
+func (g *GiteaDownloader) CountComments() (int, error) {
+	return 0, base.ErrNotSupported{Entity: "Comments"}
+}
+
 // GetIssues returns issues according start and limit
 func (g *GiteaDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, error) {
 	if perPage > g.maxPerPage {
@@ -431,6 +538,7 @@ func (g *GiteaDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, err
 			Assignees:    assignees,
 			IsLocked:     issue.IsLocked,
 			ForeignIndex: issue.Index,
+			OriginalURL:  issue.HTMLURL,
 		})
 	}
 
@@ -481,6 +589,7 @@ func (g *GiteaDownloader) GetComments(commentable base.Commentable) ([]*base.Com
 				Created:     comment.Created,
 				Updated:     comment.Updated,
 				Reactions:   reactions,
+				OriginalURL: comment.HTMLURL,
 			})
 		}
 
@@ -603,6 +712,7 @@ func (g *GiteaDownloader) GetPullRequests(page, perPage int) ([]*base.PullReques
 				OwnerName: g.repoOwner,
 			},
 			ForeignIndex: pr.Index,
+			OriginalURL:  pr.HTMLURL,
 		})
 	}
 
@@ -651,7 +761,7 @@ func (g *GiteaDownloader) GetReviews(reviewable base.Reviewable) ([]*base.Review
 					line = int(rcl[i].OldLineNum) * -1
 				}
 
-				reviewComments = append(reviewComments, &base.ReviewComment{
+				comment := &base.ReviewComment{
 					ID:        rcl[i].ID,
 					Content:   rcl[i].Body,
 					TreePath:  rcl[i].Path,
@@ -661,7 +771,13 @@ func (g *GiteaDownloader) GetReviews(reviewable base.Reviewable) ([]*base.Review
 					PosterID:  rcl[i].Reviewer.ID,
 					CreatedAt: rcl[i].Created,
 					UpdatedAt: rcl[i].Updated,
-				})
+				}
+				if rcl[i].Resolver != nil {
+					comment.Resolved = true
+					comment.ResolverID = rcl[i].Resolver.ID
+					comment.ResolverName = rcl[i].Resolver.UserName
+				}
+				reviewComments = append(reviewComments, comment)
 			}
 
 			allReviews = append(allReviews, &base.Review{
@@ -684,3 +800,106 @@ func (g *GiteaDownloader) GetReviews(reviewable base.Reviewable) ([]*base.Review
 	}
 	return allReviews, nil
 }
+
+// GetTrackedTimes returns the time spent on an issue or a pull request
+func (g *GiteaDownloader) GetTrackedTimes(commentable base.Commentable) ([]*base.TrackedTime, error) {
+	allTimes := make([]*base.TrackedTime, 0, g.maxPerPage)
+
+	for i := 1; ; i++ {
+		// make sure gitea can shutdown gracefully
+		select {
+		case <-g.ctx.Done():
+			return nil, nil
+		default:
+		}
+
+		times, resp, err := g.client.ListIssueTrackedTimes(g.repoOwner, g.repoName, commentable.GetForeignIndex(), gitea_sdk.ListTrackedTimesOptions{ListOptions: gitea_sdk.ListOptions{
+			Page:     i,
+			PageSize: g.maxPerPage,
+		}})
+		if err != nil {
+			// the time tracker of the source repository is disabled
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, base.ErrNotSupported{Entity: "TrackedTimes"}
+			}
+			return nil, fmt.Errorf("error while listing tracked times for issue #%d. Error: %v", commentable.GetForeignIndex(), err)
+		}
+
+		for _, t := range times {
+			allTimes = append(allTimes, &base.TrackedTime{
+				IssueIndex: commentable.GetLocalIndex(),
+				UserID:     t.UserID,
+				UserName:   t.UserName,
+				Time:       t.Time,
+				Created:    t.Created,
+			})
+		}
+
+		if !g.pagination || len(times) < g.maxPerPage {
+			break
+		}
+	}
+	return allTimes, nil
+}
+
+// GetIssueSubscribers returns the users subscribed to an issue or a pull request
+func (g *GiteaDownloader) GetIssueSubscribers(commentable base.Commentable) ([]*base.IssueSubscriber, error) {
+	users, _, err := g.client.GetIssueSubscribers(g.repoOwner, g.repoName, commentable.GetForeignIndex())
+	if err != nil {
+		return nil, fmt.Errorf("error while listing subscribers of issue #%d. Error: %v", commentable.GetForeignIndex(), err)
+	}
+
+	subscribers := make([]*base.IssueSubscriber, 0, len(users))
+	for _, u := range users {
+		subscribers = append(subscribers, &base.IssueSubscriber{
+			IssueIndex: commentable.GetLocalIndex(),
+			UserID:     u.ID,
+			UserName:   u.UserName,
+		})
+	}
+	return subscribers, nil
+}
+
+// GetCommitStatuses returns the statuses of a commit
+func (g *GiteaDownloader) GetCommitStatuses(sha string) ([]*base.CommitStatus, error) {
+	allStatuses := make([]*base.CommitStatus, 0, g.maxPerPage)
+
+	for i := 1; ; i++ {
+		// make sure gitea can shutdown gracefully
+		select {
+		case <-g.ctx.Done():
+			return nil, nil
+		default:
+		}
+
+		statuses, _, err := g.client.ListStatuses(g.repoOwner, g.repoName, sha, gitea_sdk.ListStatusesOption{ListOptions: gitea_sdk.ListOptions{
+			Page:     i,
+			PageSize: g.maxPerPage,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("error while listing commit statuses: %v", err)
+		}
+
+		for _, status := range statuses {
+			s := &base.CommitStatus{
+				SHA:         sha,
+				State:       string(status.State),
+				TargetURL:   status.TargetURL,
+				Description: status.Description,
+				Context:     status.Context,
+				Created:     status.Created,
+				Updated:     status.Updated,
+			}
+			if status.Creator != nil {
+				s.CreatorID = status.Creator.ID
+				s.CreatorName = status.Creator.UserName
+			}
+			allStatuses = append(allStatuses, s)
+		}
+
+		if len(statuses) < g.maxPerPage {
+			break
+		}
+	}
+	return allStatuses, nil
+}
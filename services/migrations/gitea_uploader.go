@@ -11,31 +11,43 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"code.gitea.io/gitea/models"
+	admin_model "code.gitea.io/gitea/models/admin"
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/foreignreference"
+	issues_model "code.gitea.io/gitea/models/issues"
 	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
+	webhook_model "code.gitea.io/gitea/models/webhook"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
 	base "code.gitea.io/gitea/modules/migration"
+	"code.gitea.io/gitea/modules/references"
 	repo_module "code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/storage"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/uri"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/services/pull"
+	repo_service "code.gitea.io/gitea/services/repository"
+	webhook_service "code.gitea.io/gitea/services/webhook"
 
 	gouuid "github.com/google/uuid"
 )
 
 var _ base.Uploader = &GiteaLocalUploader{}
 
+// maxTopics is the maximum number of topics of a repository
+const maxTopics = 25
+
 // GiteaLocalUploader implements an Uploader to gitea sites
 type GiteaLocalUploader struct {
 	ctx            context.Context
@@ -49,9 +61,16 @@ type GiteaLocalUploader struct {
 	gitRepo        *git.Repository
 	prHeadCache    map[string]struct{}
 	sameApp        bool
-	userMap        map[int64]int64 // external user id mapping to user id
+	userMap        map[int64]int64   // external user id mapping to user id
+	userNames      map[string]string // lower case external user name mapping to the name of the remapped user
 	prCache        map[int64]*models.PullRequest
 	gitServiceType structs.GitServiceType
+	mergeMode      bool
+	existingIssues map[int64]struct{} // source indexes of issues which had been imported before (merge mode only)
+	updatedIssues  map[int64]struct{} // source indexes of existing issues whose content has been updated (merge mode only)
+	mergedComments []*models.Comment  // comments of existing issues which have been created or updated (merge mode only)
+	archiveRepo    bool               // archive the repository when finished, the source repository is archived
+	lockIssues     bool               // import issues and pull requests locked, the source repository is archived
 }
 
 // NewGiteaLocalUploader creates an gitea Uploader via gitea API v1
@@ -66,7 +85,11 @@ func NewGiteaLocalUploader(ctx context.Context, doer *user_model.User, repoOwner
 		issues:      make(map[int64]*models.Issue),
 		prHeadCache: make(map[string]struct{}),
 		userMap:     make(map[int64]int64),
+		userNames:   make(map[string]string),
 		prCache:     make(map[int64]*models.PullRequest),
+
+		existingIssues: make(map[int64]struct{}),
+		updatedIssues:  make(map[int64]struct{}),
 	}
 }
 
@@ -91,11 +114,28 @@ func (g *GiteaLocalUploader) MaxBatchInsertSize(tp string) int {
 
 // CreateRepo creates a repository
 func (g *GiteaLocalUploader) CreateRepo(repo *base.Repository, opts base.MigrateOptions) error {
+	// an existing repository keeps its owner and name
+	if opts.MigrateToRepoID <= 0 {
+		if len(opts.TargetOwnerName) > 0 {
+			g.repoOwner = opts.TargetOwnerName
+		}
+		if len(opts.TargetRepoName) > 0 {
+			g.repoName = opts.TargetRepoName
+		}
+	}
+
 	owner, err := user_model.GetUserByName(g.repoOwner)
 	if err != nil {
 		return err
 	}
 
+	g.archiveRepo = repo.IsArchived && opts.ArchiveIfSourceArchived
+	g.lockIssues = repo.IsArchived && opts.LockIssuesIfSourceArchived
+
+	if opts.MergeIntoExisting {
+		return g.openExistingRepo(repo, opts)
+	}
+
 	var r *repo_model.Repository
 	if opts.MigrateToRepoID <= 0 {
 		r, err = repo_module.CreateRepository(g.doer, owner, models.CreateRepoOptions{
@@ -129,6 +169,13 @@ func (g *GiteaLocalUploader) CreateRepo(repo *base.Repository, opts base.Migrate
 		Wiki:           opts.Wiki,
 		Releases:       opts.Releases, // if didn't get releases, then sync them from tags
 		MirrorInterval: opts.MirrorInterval,
+		CloneDepth:     opts.CloneDepth,
+		LFSMaxFileSize: opts.LFSMaxFileSize,
+		ArchivePath:    opts.ArchivePath,
+
+		RenameDefaultBranch: opts.RenameDefaultBranch,
+		SkipReleaseSync:     opts.SkipReleaseSync,
+		SubdirectoryPrefix:  opts.SubdirectoryPrefix,
 	}, NewMigrationHTTPTransport())
 
 	g.sameApp = strings.HasPrefix(repo.OriginalURL, setting.AppURL)
@@ -136,6 +183,69 @@ func (g *GiteaLocalUploader) CreateRepo(repo *base.Repository, opts base.Migrate
 	if err != nil {
 		return err
 	}
+	if err := g.updateMergeSettings(repo.MergeSettings); err != nil {
+		return err
+	}
+	g.gitRepo, err = git.OpenRepositoryCtx(g.ctx, r.RepoPath())
+	return err
+}
+
+// updateMergeSettings applies the merge settings of the source to the pull requests unit of the repository
+func (g *GiteaLocalUploader) updateMergeSettings(settings *base.MergeSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if !settings.AllowMerge && !settings.AllowRebase && !settings.AllowRebaseMerge && !settings.AllowSquash {
+		log.Warn("Merge settings of %s/%s allow no merge style, keeping the defaults", g.repoOwner, g.repoName)
+		return nil
+	}
+
+	u, err := g.repo.GetUnit(unit.TypePullRequests)
+	if repo_model.IsErrUnitTypeNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	cfg := u.PullRequestsConfig()
+	cfg.AllowMerge = settings.AllowMerge
+	cfg.AllowRebase = settings.AllowRebase
+	cfg.AllowRebaseMerge = settings.AllowRebaseMerge
+	cfg.AllowSquash = settings.AllowSquash
+	cfg.DefaultDeleteBranchAfterMerge = settings.DeleteBranchAfterMerge
+
+	cfg.DefaultMergeStyle = repo_model.MergeStyle(settings.DefaultMergeStyle)
+	if !cfg.IsMergeStyleAllowed(cfg.DefaultMergeStyle) {
+		// fall back to the first allowed style in the order of the repository settings
+		for _, style := range []repo_model.MergeStyle{repo_model.MergeStyleMerge, repo_model.MergeStyleRebase, repo_model.MergeStyleRebaseMerge, repo_model.MergeStyleSquash} {
+			if cfg.IsMergeStyleAllowed(style) {
+				cfg.DefaultMergeStyle = style
+				break
+			}
+		}
+	}
+
+	return repo_model.UpdateRepoUnit(u)
+}
+
+// openExistingRepo prepares the import of issues, pull requests and releases into
+// an existing repository. The git data of the repository is left untouched.
+func (g *GiteaLocalUploader) openExistingRepo(repo *base.Repository, opts base.MigrateOptions) error {
+	if opts.MigrateToRepoID <= 0 {
+		return fmt.Errorf("merging into an existing repository requires MigrateToRepoID")
+	}
+	r, err := repo_model.GetRepositoryByID(opts.MigrateToRepoID)
+	if err != nil {
+		return err
+	}
+	// make sure newly allocated indexes don't collide with the existing issues
+	if err := models.RecalculateIssueIndexForRepo(r.ID); err != nil {
+		return err
+	}
+
+	g.mergeMode = true
+	g.sameApp = strings.HasPrefix(repo.OriginalURL, setting.AppURL)
+	g.repo = r
 	g.gitRepo, err = git.OpenRepositoryCtx(g.ctx, r.RepoPath())
 	return err
 }
@@ -149,22 +259,46 @@ func (g *GiteaLocalUploader) Close() {
 
 // CreateTopics creates topics
 func (g *GiteaLocalUploader) CreateTopics(topics ...string) error {
-	// ignore topics to long for the db
-	c := 0
-	for i := range topics {
-		if len(topics[i]) <= 50 {
-			topics[c] = topics[i]
-			c++
+	// other platforms allow topics which are not valid in Gitea, ignore them
+	topics, invalidTopics := repo_model.SanitizeAndValidateTopics(topics)
+	if len(invalidTopics) > 0 {
+		log.Warn("Ignoring invalid topics of migrated repository %s/%s: %v", g.repoOwner, g.repoName, invalidTopics)
+	}
+	if len(topics) > maxTopics {
+		log.Warn("Ignoring %d topics of migrated repository %s/%s exceeding the limit of %d topics", len(topics)-maxTopics, g.repoOwner, g.repoName, maxTopics)
+		topics = topics[:maxTopics]
+	}
+	if g.mergeMode {
+		// SaveTopics replaces the topics, so only add the missing ones
+		for _, topic := range topics {
+			if _, err := repo_model.AddTopic(g.repo.ID, topic); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
-	topics = topics[:c]
 	return repo_model.SaveTopics(g.repo.ID, topics...)
 }
 
+// CreateRepoAvatar sets the avatar of the repository
+func (g *GiteaLocalUploader) CreateRepoAvatar(data []byte) error {
+	return repo_service.UploadAvatar(g.repo, data)
+}
+
 // CreateMilestones creates milestones
 func (g *GiteaLocalUploader) CreateMilestones(milestones ...*base.Milestone) error {
 	mss := make([]*models.Milestone, 0, len(milestones))
 	for _, milestone := range milestones {
+		if g.mergeMode {
+			ms, err := models.GetMilestoneByRepoIDANDName(g.repo.ID, milestone.Title)
+			if err == nil {
+				g.milestones[ms.Name] = ms.ID
+				continue
+			} else if !models.IsErrMilestoneNotExist(err) {
+				return err
+			}
+		}
+
 		var deadline timeutil.TimeStamp
 		if milestone.Deadline != nil {
 			deadline = timeutil.TimeStamp(milestone.Deadline.Unix())
@@ -195,8 +329,13 @@ func (g *GiteaLocalUploader) CreateMilestones(milestones ...*base.Milestone) err
 			UpdatedUnix:  timeutil.TimeStamp(milestone.Updated.Unix()),
 			DeadlineUnix: deadline,
 		}
-		if ms.IsClosed && milestone.Closed != nil {
-			ms.ClosedDateUnix = timeutil.TimeStamp(milestone.Closed.Unix())
+		if ms.IsClosed {
+			// not all sources know when the milestone has been closed
+			closed := milestone.Updated
+			if milestone.Closed != nil && !milestone.Closed.IsZero() {
+				closed = milestone.Closed
+			}
+			ms.ClosedDateUnix = timeutil.TimeStamp(closed.Unix())
 		}
 		mss = append(mss, &ms)
 	}
@@ -216,11 +355,22 @@ func (g *GiteaLocalUploader) CreateMilestones(milestones ...*base.Milestone) err
 func (g *GiteaLocalUploader) CreateLabels(labels ...*base.Label) error {
 	lbs := make([]*models.Label, 0, len(labels))
 	for _, label := range labels {
+		if g.mergeMode {
+			lb, err := models.GetLabelInRepoByName(g.repo.ID, label.Name)
+			if err == nil {
+				g.labels[lb.Name] = lb
+				continue
+			} else if !models.IsErrRepoLabelNotExist(err) {
+				return err
+			}
+		}
+
 		lbs = append(lbs, &models.Label{
 			RepoID:      g.repo.ID,
 			Name:        label.Name,
 			Description: label.Description,
 			Color:       fmt.Sprintf("#%s", label.Color),
+			Exclusive:   label.Exclusive,
 		})
 	}
 
@@ -238,14 +388,28 @@ func (g *GiteaLocalUploader) CreateLabels(labels ...*base.Label) error {
 func (g *GiteaLocalUploader) CreateReleases(releases ...*base.Release) error {
 	rels := make([]*models.Release, 0, len(releases))
 	for _, release := range releases {
-		if release.Created.IsZero() {
-			if !release.Published.IsZero() {
-				release.Created = release.Published
-			} else {
-				release.Created = time.Now()
+		if g.mergeMode {
+			existing, err := models.GetRelease(g.repo.ID, release.TagName)
+			if err == nil {
+				// the release had been imported before, only its description is updated
+				existing.Title = release.Name
+				existing.Note = release.Body
+				existing.IsPrerelease = release.Prerelease
+				if err := models.UpdateRelease(g.ctx, existing); err != nil {
+					return err
+				}
+				continue
+			} else if !models.IsErrReleaseNotExist(err) {
+				return err
 			}
 		}
 
+		created, err := g.releaseTime(release)
+		if err != nil {
+			return err
+		}
+		release.Created = created
+
 		rel := models.Release{
 			RepoID:       g.repo.ID,
 			TagName:      release.TagName,
@@ -263,16 +427,34 @@ func (g *GiteaLocalUploader) CreateReleases(releases ...*base.Release) error {
 		if err := g.remapUser(release, &rel); err != nil {
 			return err
 		}
+		if rel.OriginalAuthorID != 0 || rel.OriginalAuthor != "" {
+			// the publisher is no linked user, attribute the release the same way as a pushed tag
+			publisher, err := repo_module.GetPublisherByEmail(release.PublisherEmail)
+			if err != nil {
+				return err
+			}
+			if publisher != nil {
+				g.rememberUserName(release.PublisherName, publisher.ID)
+				if err := rel.RemapExternalUser("", 0, publisher.ID); err != nil {
+					return err
+				}
+			}
+		}
 
 		// calc NumCommits if no draft
 		if !release.Draft {
 			commit, err := g.gitRepo.GetTagCommit(rel.TagName)
 			if err != nil {
-				return fmt.Errorf("GetTagCommit[%v]: %v", rel.TagName, err)
-			}
-			rel.NumCommits, err = commit.CommitsCount()
-			if err != nil {
-				return fmt.Errorf("CommitsCount: %v", err)
+				if !g.mergeMode || !git.IsErrNotExist(err) {
+					return fmt.Errorf("GetTagCommit[%v]: %v", rel.TagName, err)
+				}
+				// the git data has not been migrated, so the tag may not exist here
+				log.Warn("Tag %s of release %q doesn't exist in %s, NumCommits will be 0", rel.TagName, rel.Title, g.repo.FullName())
+			} else {
+				rel.NumCommits, err = commit.CommitsCount()
+				if err != nil {
+					return fmt.Errorf("CommitsCount: %v", err)
+				}
 			}
 		}
 
@@ -285,39 +467,21 @@ func (g *GiteaLocalUploader) CreateReleases(releases ...*base.Release) error {
 				}
 			}
 			attach := repo_model.Attachment{
-				UUID:          gouuid.New().String(),
-				Name:          asset.Name,
-				DownloadCount: int64(*asset.DownloadCount),
-				Size:          int64(*asset.Size),
-				CreatedUnix:   timeutil.TimeStamp(asset.Created.Unix()),
-			}
-
-			// download attachment
-			err := func() error {
-				// asset.DownloadURL maybe a local file
-				var rc io.ReadCloser
-				var err error
-				if asset.DownloadFunc != nil {
-					rc, err = asset.DownloadFunc()
-					if err != nil {
-						return err
-					}
-				} else if asset.DownloadURL != nil {
-					rc, err = uri.Open(*asset.DownloadURL)
-					if err != nil {
-						return err
-					}
-				}
-				if rc == nil {
-					return nil
-				}
-				_, err = storage.Attachments.Save(attach.RelativePath(), rc, int64(*asset.Size))
-				rc.Close()
-				return err
-			}()
+				UUID:        gouuid.New().String(),
+				Name:        asset.Name,
+				CreatedUnix: timeutil.TimeStamp(asset.Created.Unix()),
+			}
+			if asset.DownloadCount != nil {
+				attach.DownloadCount = int64(*asset.DownloadCount)
+			}
+
+			saved, err := g.saveReleaseAsset(release, asset, &attach)
 			if err != nil {
 				return err
 			}
+			if !saved {
+				continue
+			}
 
 			rel.Attachments = append(rel.Attachments, &attach)
 		}
@@ -325,9 +489,78 @@ func (g *GiteaLocalUploader) CreateReleases(releases ...*base.Release) error {
 		rels = append(rels, &rel)
 	}
 
+	if len(rels) == 0 {
+		return nil
+	}
 	return models.InsertReleases(rels...)
 }
 
+// releaseTime returns the date of a migrated release: the date it was published, or created for drafts.
+// If the source doesn't provide it, the release is dated like the ones created from tags.
+func (g *GiteaLocalUploader) releaseTime(release *base.Release) (time.Time, error) {
+	if !release.Draft && !release.Published.IsZero() {
+		return release.Published, nil
+	}
+	if !release.Created.IsZero() {
+		return release.Created, nil
+	}
+	if g.gitRepo.IsTagExist(release.TagName) {
+		return repo_module.GetTagTime(g.gitRepo, release.TagName)
+	}
+	return time.Now(), nil
+}
+
+// saveReleaseAsset downloads a release asset into the attachment storage. It returns false if the asset
+// is skipped because it can't be downloaded from the source or exceeds the maximum attachment size.
+func (g *GiteaLocalUploader) saveReleaseAsset(release *base.Release, asset *base.ReleaseAsset, attach *repo_model.Attachment) (bool, error) {
+	maxSize := setting.Attachment.MaxSize * 1024 * 1024
+	// some sources don't know the size of their assets
+	size := int64(-1)
+	if asset.Size != nil && *asset.Size > 0 {
+		size = int64(*asset.Size)
+	}
+	if size > maxSize {
+		log.Warn("Skipping the asset %q of release %s in %s: its size %d exceeds the maximum attachment size %d", asset.Name, release.TagName, g.repo.FullName(), size, maxSize)
+		return false, nil
+	}
+
+	// asset.DownloadURL maybe a local file
+	var rc io.ReadCloser
+	var err error
+	if asset.DownloadFunc != nil {
+		rc, err = asset.DownloadFunc()
+	} else if asset.DownloadURL != nil {
+		rc, err = uri.Open(*asset.DownloadURL)
+	}
+	if err != nil {
+		if !base.IsErrNotSupported(err) {
+			return false, err
+		}
+		log.Warn("Downloading the asset %q of release %s is not supported, ignored", asset.Name, release.TagName)
+		return false, nil
+	}
+	if rc == nil {
+		log.Warn("Skipping the asset %q of release %s in %s: no download location", asset.Name, release.TagName, g.repo.FullName())
+		return false, nil
+	}
+	defer rc.Close()
+
+	// the declared size of the asset may be wrong, never store more than the limit
+	written, err := storage.Attachments.Save(attach.RelativePath(), io.LimitReader(rc, maxSize+1), size)
+	if err != nil {
+		return false, err
+	}
+	if written > maxSize {
+		log.Warn("Skipping the asset %q of release %s in %s: it exceeds the maximum attachment size %d", asset.Name, release.TagName, g.repo.FullName(), maxSize)
+		if err := storage.Attachments.Delete(attach.RelativePath()); err != nil {
+			log.Error("Delete attachment %s: %v", attach.RelativePath(), err)
+		}
+		return false, nil
+	}
+	attach.Size = written
+	return true, nil
+}
+
 // SyncTags syncs releases with tags in the database
 func (g *GiteaLocalUploader) SyncTags() error {
 	return repo_module.SyncReleasesWithTags(g.repo, g.gitRepo)
@@ -336,7 +569,27 @@ func (g *GiteaLocalUploader) SyncTags() error {
 // CreateIssues creates issues
 func (g *GiteaLocalUploader) CreateIssues(issues ...*base.Issue) error {
 	iss := make([]*models.Issue, 0, len(issues))
+	sourceIndexes := make([]int64, 0, len(issues))
+	lockReasons := make([]string, 0, len(issues))
 	for _, issue := range issues {
+		index := issue.Number
+		if g.mergeMode {
+			existing, err := models.GetIssueByForeignIndex(g.ctx, g.repo.ID, issue.GetForeignIndex())
+			if err == nil {
+				if err := g.updateMergedIssue(existing, issue); err != nil {
+					return err
+				}
+				g.issues[issue.Number] = existing
+				g.existingIssues[issue.Number] = struct{}{}
+				continue
+			} else if !foreignreference.IsErrLocalIndexNotExist(err) {
+				return err
+			}
+			if index, err = db.GetNextResourceIndex("issue_index", g.repo.ID); err != nil {
+				return err
+			}
+		}
+
 		var labels []*models.Label
 		for _, label := range issue.Labels {
 			lb, ok := g.labels[label.Name]
@@ -365,18 +618,19 @@ func (g *GiteaLocalUploader) CreateIssues(issues ...*base.Issue) error {
 		is := models.Issue{
 			RepoID:      g.repo.ID,
 			Repo:        g.repo,
-			Index:       issue.Number,
+			Index:       index,
 			Title:       issue.Title,
 			Content:     issue.Content,
 			Ref:         issue.Ref,
+			OriginalURL: issue.OriginalURL,
 			IsClosed:    issue.State == "closed",
-			IsLocked:    issue.IsLocked,
+			IsLocked:    issue.IsLocked || g.lockIssues,
 			MilestoneID: milestoneID,
 			Labels:      labels,
 			CreatedUnix: timeutil.TimeStamp(issue.Created.Unix()),
 			UpdatedUnix: timeutil.TimeStamp(issue.Updated.Unix()),
 			ForeignReference: &foreignreference.ForeignReference{
-				LocalIndex:   issue.GetLocalIndex(),
+				LocalIndex:   index,
 				ForeignIndex: strconv.FormatInt(issue.GetForeignIndex(), 10),
 				RepoID:       g.repo.ID,
 				Type:         foreignreference.TypeIssue,
@@ -402,6 +656,8 @@ func (g *GiteaLocalUploader) CreateIssues(issues ...*base.Issue) error {
 			is.Reactions = append(is.Reactions, &res)
 		}
 		iss = append(iss, &is)
+		sourceIndexes = append(sourceIndexes, issue.Number)
+		lockReasons = append(lockReasons, issue.LockReason)
 	}
 
 	if len(iss) > 0 {
@@ -409,17 +665,84 @@ func (g *GiteaLocalUploader) CreateIssues(issues ...*base.Issue) error {
 			return err
 		}
 
-		for _, is := range iss {
-			g.issues[is.Index] = is
+		lockComments := make([]*models.Comment, 0, len(iss))
+		for i, is := range iss {
+			g.issues[sourceIndexes[i]] = is
+			if cm := g.newLockComment(is, lockReasons[i]); cm != nil {
+				lockComments = append(lockComments, cm)
+			}
 		}
+		return models.InsertIssueComments(lockComments)
 	}
 
 	return nil
 }
 
-// CreateComments creates comments of issues
+// updateMergedIssue updates an issue which had been imported before with the title, content and state of the source
+func (g *GiteaLocalUploader) updateMergedIssue(existing *models.Issue, issue *base.Issue) error {
+	existing.Title = issue.Title
+	existing.Content = issue.Content
+	existing.IsClosed = issue.State == "closed"
+	if !existing.IsClosed {
+		existing.ClosedUnix = 0
+	} else if issue.Closed != nil {
+		existing.ClosedUnix = timeutil.TimeStamp(issue.Closed.Unix())
+	}
+	if !issue.Updated.IsZero() {
+		existing.UpdatedUnix = timeutil.TimeStamp(issue.Updated.Unix())
+	}
+	g.updatedIssues[issue.Number] = struct{}{}
+	return models.UpdateMigratedIssue(g.ctx, existing)
+}
+
+// mergeComment updates the comment of an existing issue which had been imported before from the same source comment.
+// It returns false if the comment has not been imported yet.
+func (g *GiteaLocalUploader) mergeComment(issue *models.Issue, comment *base.Comment, imported map[int64][]*models.Comment) (bool, error) {
+	existing, err := models.GetCommentByForeignIndex(g.ctx, g.repo.ID, comment.Index)
+	if err != nil && !foreignreference.IsErrLocalIndexNotExist(err) {
+		return false, err
+	}
+	if err == nil && existing.IssueID != issue.ID {
+		// the source reuses the index for a comment of another issue, which is imported on its own
+		return false, nil
+	}
+	if err != nil {
+		// comments imported before their foreign indexes have been recorded are recognized by their creation time
+		if _, ok := imported[issue.ID]; !ok {
+			if imported[issue.ID], err = models.FindComments(&models.FindCommentsOptions{IssueID: issue.ID, Type: models.CommentTypeComment}); err != nil {
+				return false, err
+			}
+		}
+		for _, cm := range imported[issue.ID] {
+			if cm.CreatedUnix == timeutil.TimeStamp(comment.Created.Unix()) {
+				existing = cm
+				break
+			}
+		}
+		if existing == nil {
+			return false, nil
+		}
+		if err := models.InsertCommentForeignReference(g.ctx, g.repo.ID, existing.ID, comment.Index); err != nil {
+			return false, err
+		}
+	}
+
+	existing.Content = comment.Content
+	existing.UpdatedUnix = timeutil.TimeStamp(comment.Updated.Unix())
+	if err := models.UpdateMigratedComment(g.ctx, existing); err != nil {
+		return false, err
+	}
+	g.mergedComments = append(g.mergedComments, existing)
+	return true, nil
+}
+
+// CreateComments creates comments of issues. When merging into an existing repository, the comments of
+// issues which had been imported before are updated, or created if they are new.
 func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 	cms := make([]*models.Comment, 0, len(comments))
+	histories := make(map[*models.Comment]*base.Comment)
+	imported := make(map[int64][]*models.Comment)
+	var merged []*models.Comment
 	for _, comment := range comments {
 		var issue *models.Issue
 		issue, ok := g.issues[comment.IssueIndex]
@@ -434,10 +757,25 @@ func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 			comment.Updated = comment.Created
 		}
 
+		_, existingIssue := g.existingIssues[comment.IssueIndex]
+		if existingIssue {
+			if comment.Index <= 0 {
+				// without a foreign index the comment can't be told apart from the comments imported before
+				continue
+			}
+			ok, err := g.mergeComment(issue, comment, imported)
+			if err != nil {
+				return err
+			} else if ok {
+				continue
+			}
+		}
+
 		cm := models.Comment{
 			IssueID:     issue.ID,
 			Type:        models.CommentTypeComment,
 			Content:     comment.Content,
+			OriginalURL: comment.OriginalURL,
 			CreatedUnix: timeutil.TimeStamp(comment.Created.Unix()),
 			UpdatedUnix: timeutil.TimeStamp(comment.Updated.Unix()),
 		}
@@ -446,6 +784,15 @@ func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 			return err
 		}
 
+		if comment.Index > 0 {
+			// recorded so that importing the comment again updates it instead of duplicating it
+			cm.ForeignReference = &foreignreference.ForeignReference{
+				RepoID:       g.repo.ID,
+				ForeignIndex: strconv.FormatInt(comment.Index, 10),
+				Type:         foreignreference.TypeComment,
+			}
+		}
+
 		// add reactions
 		for _, reaction := range comment.Reactions {
 			res := models.Reaction{
@@ -459,18 +806,62 @@ func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 		}
 
 		cms = append(cms, &cm)
+		if existingIssue {
+			merged = append(merged, &cm)
+		}
+		if len(comment.History) > 0 {
+			histories[&cm] = comment
+		}
 	}
 
 	if len(cms) == 0 {
 		return nil
 	}
-	return models.InsertIssueComments(cms)
+	if err := models.InsertIssueComments(cms); err != nil {
+		return err
+	}
+	g.mergedComments = append(g.mergedComments, merged...)
+
+	for cm, comment := range histories {
+		if err := g.insertContentHistory(cm, comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertContentHistory saves the previous versions and the current content of a migrated comment as its content history
+func (g *GiteaLocalUploader) insertContentHistory(cm *models.Comment, comment *base.Comment) error {
+	e := db.GetEngine(g.ctx)
+	for i, version := range comment.History {
+		editTime := timeutil.TimeStamp(version.Edited.Unix())
+		if version.Edited.IsZero() {
+			editTime = cm.CreatedUnix
+		}
+		if err := issues_model.SaveIssueContentHistory(e, cm.PosterID, cm.IssueID, cm.ID, editTime, version.Content, i == 0); err != nil {
+			return err
+		}
+	}
+	return issues_model.SaveIssueContentHistory(e, cm.PosterID, cm.IssueID, cm.ID, cm.UpdatedUnix, cm.Content, false)
 }
 
 // CreatePullRequests creates pull requests
 func (g *GiteaLocalUploader) CreatePullRequests(prs ...*base.PullRequest) error {
 	gprs := make([]*models.PullRequest, 0, len(prs))
+	sourceIndexes := make([]int64, 0, len(prs))
+	lockReasons := make([]string, 0, len(prs))
 	for _, pr := range prs {
+		if g.mergeMode && pr.ForeignIndex > 0 {
+			existing, err := models.GetIssueByForeignIndexAndType(g.ctx, g.repo.ID, pr.ForeignIndex, foreignreference.TypePullRequest)
+			if err == nil {
+				g.issues[pr.Number] = existing
+				g.existingIssues[pr.Number] = struct{}{}
+				continue
+			} else if !foreignreference.IsErrLocalIndexNotExist(err) {
+				return err
+			}
+		}
+
 		gpr, err := g.newPullRequest(pr)
 		if err != nil {
 			return err
@@ -481,15 +872,40 @@ func (g *GiteaLocalUploader) CreatePullRequests(prs ...*base.PullRequest) error
 		}
 
 		gprs = append(gprs, gpr)
+		sourceIndexes = append(sourceIndexes, pr.Number)
+		lockReasons = append(lockReasons, pr.LockReason)
+	}
+	if len(gprs) == 0 {
+		return nil
 	}
 	if err := models.InsertPullRequests(gprs...); err != nil {
 		return err
 	}
-	for _, pr := range gprs {
-		g.issues[pr.Issue.Index] = pr.Issue
+	lockComments := make([]*models.Comment, 0, len(gprs))
+	for i, pr := range gprs {
+		g.issues[sourceIndexes[i]] = pr.Issue
+		if cm := g.newLockComment(pr.Issue, lockReasons[i]); cm != nil {
+			lockComments = append(lockComments, cm)
+		}
 		pull.AddToTaskQueue(pr)
 	}
-	return nil
+	return models.InsertIssueComments(lockComments)
+}
+
+// newLockComment returns the comment recording why a migrated issue or pull request has been locked,
+// or nil when there is nothing to record.
+func (g *GiteaLocalUploader) newLockComment(issue *models.Issue, reason string) *models.Comment {
+	if !issue.IsLocked || reason == "" {
+		return nil
+	}
+	return &models.Comment{
+		Type:        models.CommentTypeLock,
+		PosterID:    g.doer.ID,
+		IssueID:     issue.ID,
+		Content:     reason,
+		CreatedUnix: issue.UpdatedUnix,
+		UpdatedUnix: issue.UpdatedUnix,
+	}
 }
 
 func (g *GiteaLocalUploader) updateGitForPullRequest(pr *base.PullRequest) (head string, err error) {
@@ -579,6 +995,15 @@ func (g *GiteaLocalUploader) updateGitForPullRequest(pr *base.PullRequest) (head
 		_, err = git.NewCommand(g.ctx, "rev-list", "--quiet", "-1", pr.Head.SHA).RunInDir(g.repo.RepoPath())
 		if err != nil {
 			if pr.Head.SHA != "" {
+				// The head branch is gone, try to recreate its content from the patch so that the diff is kept
+				commitID, patchErr := g.headCommitFromPatch(pr)
+				if patchErr != nil {
+					log.Warn("Cannot recreate the head of pull request %d from its patch: %v", pr.Number, patchErr)
+				} else if commitID != "" {
+					log.Info("Missing local head %v, recreated as %v from the patch", pr.Head.SHA, commitID)
+					return head, os.WriteFile(filepath.Join(pullHead, "head"), []byte(commitID), 0o644)
+				}
+
 				// Git update-ref remove bad references with a relative path
 				log.Warn("Deprecated local head, removing : %v", pr.Head.SHA)
 				err = g.gitRepo.RemoveReference(pr.GetGitRefName())
@@ -596,6 +1021,59 @@ func (g *GiteaLocalUploader) updateGitForPullRequest(pr *base.PullRequest) (head
 	return head, nil
 }
 
+// headCommitFromPatch applies the downloaded patch of a pull request on top of its base commit
+// and returns the ID of the resulting commit, or an empty string if there is no patch or base to use.
+func (g *GiteaLocalUploader) headCommitFromPatch(pr *base.PullRequest) (string, error) {
+	patchPath := filepath.Join(g.repo.RepoPath(), "pulls", fmt.Sprintf("%d.patch", pr.Number))
+	if pr.Base.SHA == "" {
+		return "", nil
+	}
+	if exist, err := util.IsFile(patchPath); err != nil || !exist {
+		return "", err
+	}
+	if _, err := git.NewCommand(g.ctx, "rev-list", "--quiet", "-1", pr.Base.SHA).RunInDir(g.repo.RepoPath()); err != nil {
+		return "", nil
+	}
+
+	tmpDir, err := os.MkdirTemp(os.TempDir(), "gitea-migrate-patch-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := util.RemoveAll(tmpDir); err != nil {
+			log.Error("Unable to remove temporary directory: %s: Error: %v", tmpDir, err)
+		}
+	}()
+
+	// apply the patch into a temporary index so that the bare repository is left untouched
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(tmpDir, "index"))
+	if _, err := git.NewCommand(g.ctx, "read-tree", pr.Base.SHA).RunInDirWithEnv(g.repo.RepoPath(), env); err != nil {
+		return "", fmt.Errorf("git read-tree: %v", err)
+	}
+	if _, err := git.NewCommand(g.ctx, "apply", "--cached", patchPath).RunInDirWithEnv(g.repo.RepoPath(), env); err != nil {
+		return "", fmt.Errorf("git apply: %v", err)
+	}
+	treeID, err := git.NewCommand(g.ctx, "write-tree").RunInDirWithEnv(g.repo.RepoPath(), env)
+	if err != nil {
+		return "", fmt.Errorf("git write-tree: %v", err)
+	}
+	tree, err := g.gitRepo.GetTree(strings.TrimSpace(treeID))
+	if err != nil {
+		return "", fmt.Errorf("GetTree: %v", err)
+	}
+
+	sig := g.doer.NewGitSig()
+	commitID, err := g.gitRepo.CommitTree(sig, sig, tree, git.CommitTreeOpts{
+		Parents:   []string{pr.Base.SHA},
+		Message:   fmt.Sprintf("Recreated head of pull request #%d from its patch", pr.Number),
+		NoGPGSign: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("CommitTree: %v", err)
+	}
+	return commitID.String(), nil
+}
+
 func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullRequest, error) {
 	var labels []*models.Label
 	for _, label := range pr.Labels {
@@ -607,7 +1085,21 @@ func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullR
 
 	milestoneID := g.milestones[pr.Milestone]
 
-	head, err := g.updateGitForPullRequest(pr)
+	index := pr.Number
+	gitPR := pr
+	if g.mergeMode {
+		var err error
+		if index, err = db.GetNextResourceIndex("issue_index", g.repo.ID); err != nil {
+			return nil, err
+		}
+		// the refs of the pull request have to use the newly allocated index,
+		// but the source index is still needed to look up its comments and reviews
+		renumbered := *pr
+		renumbered.Number = index
+		gitPR = &renumbered
+	}
+
+	head, err := g.updateGitForPullRequest(gitPR)
 	if err != nil {
 		return nil, fmt.Errorf("updateGitForPullRequest: %w", err)
 	}
@@ -629,16 +1121,25 @@ func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullR
 		RepoID:      g.repo.ID,
 		Repo:        g.repo,
 		Title:       pr.Title,
-		Index:       pr.Number,
+		Index:       index,
 		Content:     pr.Content,
+		OriginalURL: pr.OriginalURL,
 		MilestoneID: milestoneID,
 		IsPull:      true,
 		IsClosed:    pr.State == "closed",
-		IsLocked:    pr.IsLocked,
+		IsLocked:    pr.IsLocked || g.lockIssues,
 		Labels:      labels,
 		CreatedUnix: timeutil.TimeStamp(pr.Created.Unix()),
 		UpdatedUnix: timeutil.TimeStamp(pr.Updated.Unix()),
 	}
+	if pr.ForeignIndex > 0 {
+		issue.ForeignReference = &foreignreference.ForeignReference{
+			LocalIndex:   index,
+			ForeignIndex: strconv.FormatInt(pr.ForeignIndex, 10),
+			RepoID:       g.repo.ID,
+			Type:         foreignreference.TypePullRequest,
+		}
+	}
 
 	if err := g.remapUser(pr, &issue); err != nil {
 		return nil, err
@@ -662,7 +1163,7 @@ func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullR
 		BaseRepoID: g.repo.ID,
 		BaseBranch: pr.Base.Ref,
 		MergeBase:  pr.Base.SHA,
-		Index:      pr.Number,
+		Index:      index,
 		HasMerged:  pr.Merged,
 
 		Issue: &issue,
@@ -706,6 +1207,9 @@ func (g *GiteaLocalUploader) CreateReviews(reviews ...*base.Review) error {
 		if !ok {
 			return fmt.Errorf("review references non existent IssueIndex %d", review.IssueIndex)
 		}
+		if _, ok := g.existingIssues[review.IssueIndex]; ok {
+			continue
+		}
 		if review.CreatedAt.IsZero() {
 			review.CreatedAt = time.Unix(int64(issue.CreatedUnix), 0)
 		}
@@ -786,6 +1290,14 @@ func (g *GiteaLocalUploader) CreateReviews(reviews ...*base.Review) error {
 				return err
 			}
 
+			if comment.Resolved {
+				resolverID, err := g.getReviewCommentResolverID(comment)
+				if err != nil {
+					return err
+				}
+				c.ResolveDoerID = resolverID
+			}
+
 			cm.Comments = append(cm.Comments, &c)
 		}
 
@@ -795,10 +1307,299 @@ func (g *GiteaLocalUploader) CreateReviews(reviews ...*base.Review) error {
 	return models.InsertReviews(cms)
 }
 
+// getReviewCommentResolverID returns the local user who resolved the thread of the review comment,
+// the doer of the migration if the resolver is unknown
+func (g *GiteaLocalUploader) getReviewCommentResolverID(comment *base.ReviewComment) (int64, error) {
+	if comment.ResolverID == 0 {
+		return g.doer.ID, nil
+	}
+	var userid int64
+	var err error
+	resolver := &base.ReviewCommentResolver{Comment: comment}
+	if g.sameApp {
+		userid, err = g.remapLocalUser(resolver, nil)
+	} else {
+		userid, err = g.remapExternalUser(resolver, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if userid == 0 {
+		return g.doer.ID, nil
+	}
+	return userid, nil
+}
+
+// CreateCommitStatuses creates commit statuses
+func (g *GiteaLocalUploader) CreateCommitStatuses(statuses ...*base.CommitStatus) error {
+	// the statuses have to be inserted in the order they have been reported
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Created.Before(statuses[j].Created)
+	})
+
+	existingContexts := make(map[string]map[string]struct{})
+	for _, status := range statuses {
+		if !g.gitRepo.IsObjectExist(status.SHA) {
+			log.Warn("Skipping the status %q of the missing commit %s in %s", status.Context, status.SHA, g.repo.FullName())
+			continue
+		}
+
+		if g.mergeMode {
+			contexts, ok := existingContexts[status.SHA]
+			if !ok {
+				latest, _, err := models.GetLatestCommitStatus(g.repo.ID, status.SHA, db.ListOptions{})
+				if err != nil {
+					return err
+				}
+				contexts = make(map[string]struct{}, len(latest))
+				for _, s := range latest {
+					contexts[s.Context] = struct{}{}
+				}
+				existingContexts[status.SHA] = contexts
+			}
+			if _, ok := contexts[status.Context]; ok {
+				continue
+			}
+		}
+
+		creator, err := g.getCommitStatusCreator(status)
+		if err != nil {
+			return err
+		}
+
+		if err := models.NewCommitStatus(models.NewCommitStatusOptions{
+			Repo:    g.repo,
+			Creator: creator,
+			SHA:     status.SHA,
+			CommitStatus: &models.CommitStatus{
+				State:       structs.CommitStatusState(status.State),
+				TargetURL:   status.TargetURL,
+				Description: status.Description,
+				Context:     status.Context,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getCommitStatusCreator returns the local user the status is attributed to, falling back to the doer
+func (g *GiteaLocalUploader) getCommitStatusCreator(status *base.CommitStatus) (*user_model.User, error) {
+	var userid int64
+	var err error
+	if g.sameApp {
+		userid, err = g.remapLocalUser(status, nil)
+	} else {
+		userid, err = g.remapExternalUser(status, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userid == 0 {
+		return g.doer, nil
+	}
+	return user_model.GetUserByID(userid)
+}
+
+// CreateTrackedTimes creates the tracked times of issues and pull requests
+func (g *GiteaLocalUploader) CreateTrackedTimes(times ...*base.TrackedTime) error {
+	tts := make([]*models.TrackedTime, 0, len(times))
+	for _, t := range times {
+		issue, ok := g.issues[t.IssueIndex]
+		if !ok {
+			return fmt.Errorf("tracked time references non existent IssueIndex %d", t.IssueIndex)
+		}
+		if _, ok := g.existingIssues[t.IssueIndex]; ok {
+			continue
+		}
+		if t.Time <= 0 {
+			continue
+		}
+
+		if t.Created.IsZero() {
+			t.Created = time.Unix(int64(issue.CreatedUnix), 0)
+		}
+
+		userID := g.doer.ID
+		if t.UserID != 0 {
+			var remappedID int64
+			var err error
+			if g.sameApp {
+				remappedID, err = g.remapLocalUser(t, nil)
+			} else {
+				remappedID, err = g.remapExternalUser(t, nil)
+			}
+			if err != nil {
+				return err
+			}
+			if remappedID != 0 {
+				userID = remappedID
+			}
+		}
+
+		tts = append(tts, &models.TrackedTime{
+			IssueID:     issue.ID,
+			UserID:      userID,
+			CreatedUnix: t.Created.Unix(),
+			Time:        t.Time,
+		})
+	}
+	return models.InsertTrackedTimes(tts)
+}
+
+// CreateIssueSubscribers subscribes the local users the subscribers are mapped to to their issues and pull requests,
+// subscribers without a local user are skipped
+func (g *GiteaLocalUploader) CreateIssueSubscribers(subscribers ...*base.IssueSubscriber) error {
+	for _, s := range subscribers {
+		issue, ok := g.issues[s.IssueIndex]
+		if !ok {
+			return fmt.Errorf("issue subscriber references non existent IssueIndex %d", s.IssueIndex)
+		}
+		if _, ok := g.existingIssues[s.IssueIndex]; ok {
+			continue
+		}
+
+		var userID int64
+		var err error
+		if g.sameApp {
+			userID, err = g.remapLocalUser(s, nil)
+		} else {
+			userID, err = g.remapExternalUser(s, nil)
+		}
+		if err != nil {
+			return err
+		}
+		if userID == 0 {
+			continue
+		}
+
+		if err := models.CreateOrUpdateIssueWatch(userID, issue.ID, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWebhooks recreates the webhooks of the source with new secrets. Webhooks of types which need further
+// configuration and webhooks whose URL is not allowed by setting.Webhook.AllowedHostList are skipped.
+func (g *GiteaLocalUploader) CreateWebhooks(webhooks ...*base.Webhook) error {
+	existingURLs := make(map[string]bool)
+	if g.mergeMode {
+		existing, err := webhook_model.ListWebhooksByOpts(&webhook_model.ListWebhookOptions{RepoID: g.repo.ID})
+		if err != nil {
+			return err
+		}
+		for _, w := range existing {
+			existingURLs[w.URL] = true
+		}
+	}
+
+	for _, hook := range webhooks {
+		if existingURLs[hook.URL] {
+			continue
+		}
+		hookType := hook.Type
+		if hookType == "" {
+			hookType = webhook_model.GITEA
+		}
+		if hookType != webhook_model.GITEA && hookType != webhook_model.GOGS {
+			log.Warn("Skipping %s webhook %d of migrated repository %s/%s, only gitea and gogs webhooks are migrated", hookType, hook.ID, g.repoOwner, g.repoName)
+			continue
+		}
+		if !webhook_service.IsWebhookURLAllowed(hook.URL) {
+			log.Warn("Skipping webhook %d of migrated repository %s/%s, its URL is not allowed", hook.ID, g.repoOwner, g.repoName)
+			continue
+		}
+
+		secret, err := util.CryptoRandomString(32)
+		if err != nil {
+			return err
+		}
+		w := &webhook_model.Webhook{
+			RepoID:      g.repo.ID,
+			URL:         hook.URL,
+			HTTPMethod:  "POST",
+			ContentType: webhook_model.ToHookContentType(hook.ContentType),
+			Secret:      secret,
+			HookEvent:   migratedHookEvent(hook),
+			IsActive:    hook.Active,
+			Type:        hookType,
+		}
+		if err := w.UpdateEvent(); err != nil {
+			return err
+		}
+		if err := webhook_model.CreateWebhook(g.ctx, w); err != nil {
+			return err
+		}
+		existingURLs[hook.URL] = true
+	}
+	return nil
+}
+
+// migratedHookEvent converts the events of a migrated webhook like the API does for new webhooks
+func migratedHookEvent(hook *base.Webhook) *webhook_model.HookEvent {
+	if util.IsStringInSlice("*", hook.Events) {
+		return &webhook_model.HookEvent{SendEverything: true, BranchFilter: hook.BranchFilter}
+	}
+	events := hook.Events
+	if len(events) == 0 {
+		events = []string{string(webhook_model.HookEventPush)}
+	}
+	has := func(event webhook_model.HookEventType) bool {
+		return util.IsStringInSlice(string(event), events, true)
+	}
+	issues := func(event webhook_model.HookEventType) bool {
+		return has(event) || has(webhook_model.HookEventIssues)
+	}
+	pulls := func(event webhook_model.HookEventType) bool {
+		return has(event) || has(webhook_model.HookEventPullRequest)
+	}
+	review := pulls("pull_request_review") || has(webhook_model.HookEventPullRequestReviewApproved) ||
+		has(webhook_model.HookEventPullRequestReviewRejected)
+	return &webhook_model.HookEvent{
+		ChooseEvents: true,
+		HookEvents: webhook_model.HookEvents{
+			Create:               has(webhook_model.HookEventCreate),
+			Delete:               has(webhook_model.HookEventDelete),
+			Fork:                 has(webhook_model.HookEventFork),
+			Issues:               issues(webhook_model.HookEventIssues),
+			IssueAssign:          issues(webhook_model.HookEventIssueAssign),
+			IssueLabel:           issues(webhook_model.HookEventIssueLabel),
+			IssueMilestone:       issues(webhook_model.HookEventIssueMilestone),
+			IssueComment:         issues(webhook_model.HookEventIssueComment),
+			Push:                 has(webhook_model.HookEventPush),
+			PullRequest:          pulls(webhook_model.HookEventPullRequest),
+			PullRequestAssign:    pulls(webhook_model.HookEventPullRequestAssign),
+			PullRequestLabel:     pulls(webhook_model.HookEventPullRequestLabel),
+			PullRequestMilestone: pulls(webhook_model.HookEventPullRequestMilestone),
+			PullRequestComment:   pulls(webhook_model.HookEventPullRequestComment),
+			PullRequestReview:    review,
+			PullRequestSync:      pulls(webhook_model.HookEventPullRequestSync),
+			Repository:           has(webhook_model.HookEventRepository),
+			Release:              has(webhook_model.HookEventRelease),
+		},
+		BranchFilter: hook.BranchFilter,
+	}
+}
+
 // Rollback when migrating failed, this will rollback all the changes.
 func (g *GiteaLocalUploader) Rollback() error {
+	if g.mergeMode {
+		// never delete the repository we have been merging into
+		log.Warn("Migration into the existing repository %s failed, the already imported data is kept", g.repo.FullName())
+		return nil
+	}
 	if g.repo != nil && g.repo.ID > 0 {
 		g.gitRepo.Close()
+		// remove the LFS objects stored by the migration first, so they don't linger if the repository can't be deleted
+		lfsPaths, err := models.RemoveLFSMetaObjectsByRepoID(g.repo.ID)
+		if err != nil {
+			return err
+		}
+		for _, lfsPath := range lfsPaths {
+			admin_model.RemoveStorageWithNotice(g.ctx, storage.LFS, "Delete orphaned LFS file", lfsPath)
+		}
 		if err := models.DeleteRepository(g.doer, g.repo.OwnerID, g.repo.ID); err != nil {
 			return err
 		}
@@ -812,6 +1613,10 @@ func (g *GiteaLocalUploader) Finish() error {
 		return ErrRepoNotCreated
 	}
 
+	if err := g.rewriteReferences(); err != nil {
+		return err
+	}
+
 	// update issue_index
 	if err := models.RecalculateIssueIndexForRepo(g.repo.ID); err != nil {
 		return err
@@ -821,10 +1626,139 @@ func (g *GiteaLocalUploader) Finish() error {
 		return err
 	}
 
+	if g.archiveRepo {
+		if err := repo_model.SetArchiveRepoState(g.repo, true); err != nil {
+			return err
+		}
+	}
+
 	g.repo.Status = repo_model.RepositoryReady
 	return repo_model.UpdateRepositoryCols(g.repo, "status")
 }
 
+// rewriteReferences rewrites the references to issues and the mentions of users in the migrated issues and comments.
+// Issues may have been renumbered when merging into an existing repository and users may have been remapped to
+// local users with different names. References and mentions which can't be mapped are kept.
+func (g *GiteaLocalUploader) rewriteReferences() error {
+	issueIndexes := make(map[int64]int64)
+	for number, issue := range g.issues {
+		if issue.Index != number {
+			issueIndexes[number] = issue.Index
+		}
+	}
+	userNames := make(map[string]string)
+	for externalName, name := range g.userNames {
+		if !strings.EqualFold(externalName, name) {
+			userNames[externalName] = name
+		}
+	}
+	if len(issueIndexes) == 0 && len(userNames) == 0 {
+		return nil
+	}
+
+	var issues []*models.Issue
+	var comments []*models.Comment
+	for number, issue := range g.issues {
+		if _, ok := g.existingIssues[number]; ok {
+			// the comments imported before have been rewritten already, only the updated contents are rewritten
+			if _, ok := g.updatedIssues[number]; ok {
+				if content := rewriteContentReferences(issue.Content, issueIndexes, userNames); content != issue.Content {
+					issue.Content = content
+					issues = append(issues, issue)
+				}
+			}
+			continue
+		}
+		if content := rewriteContentReferences(issue.Content, issueIndexes, userNames); content != issue.Content {
+			issue.Content = content
+			issues = append(issues, issue)
+		}
+
+		issueComments, err := models.FindComments(&models.FindCommentsOptions{IssueID: issue.ID, Type: models.CommentTypeUnknown})
+		if err != nil {
+			return err
+		}
+		for _, comment := range issueComments {
+			if content := rewriteContentReferences(comment.Content, issueIndexes, userNames); content != comment.Content {
+				comment.Content = content
+				comments = append(comments, comment)
+			}
+		}
+	}
+	for _, comment := range g.mergedComments {
+		if content := rewriteContentReferences(comment.Content, issueIndexes, userNames); content != comment.Content {
+			comment.Content = content
+			comments = append(comments, comment)
+		}
+	}
+	return models.UpdateMigratedContents(issues, comments)
+}
+
+// rewriteContentReferences replaces the numbers of issue references and the names of user mentions in content.
+// References to issues missing in issueIndexes and mentions of users missing in userNames are kept.
+func rewriteContentReferences(content string, issueIndexes map[int64]int64, userNames map[string]string) string {
+	if content == "" {
+		return content
+	}
+
+	type replacement struct {
+		span references.RefSpan
+		text string
+	}
+	var replacements []replacement
+	bcontent := []byte(content)
+	for _, span := range references.FindAllLocalIssueReferencesBytes(bcontent) {
+		number, err := strconv.ParseInt(content[span.Start+1:span.End], 10, 64)
+		if err != nil {
+			continue
+		}
+		if index, ok := issueIndexes[number]; ok {
+			replacements = append(replacements, replacement{span, content[span.Start:span.Start+1] + strconv.FormatInt(index, 10)})
+		}
+	}
+	for _, span := range references.FindAllMentionsBytes(bcontent) {
+		if name, ok := userNames[strings.ToLower(content[span.Start+1:span.End])]; ok {
+			replacements = append(replacements, replacement{span, "@" + name})
+		}
+	}
+	if len(replacements) == 0 {
+		return content
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].span.Start < replacements[j].span.Start
+	})
+	var sb strings.Builder
+	pos := 0
+	for _, r := range replacements {
+		if r.span.Start < pos {
+			continue
+		}
+		sb.WriteString(content[pos:r.span.Start])
+		sb.WriteString(r.text)
+		pos = r.span.End
+	}
+	sb.WriteString(content[pos:])
+	return sb.String()
+}
+
+// rememberUserName remembers the name of the local user an external user has been remapped to
+func (g *GiteaLocalUploader) rememberUserName(externalName string, userID int64) {
+	key := strings.ToLower(externalName)
+	if key == "" {
+		return
+	}
+	if _, ok := g.userNames[key]; ok {
+		return
+	}
+	name, err := user_model.GetUserNameByID(g.ctx, userID)
+	if err != nil {
+		log.Warn("Unable to get the name of user %d, mentions of %s are kept: %v", userID, externalName, err)
+		name = externalName
+	}
+	g.userNames[key] = name
+}
+
 func (g *GiteaLocalUploader) remapUser(source user_model.ExternalUserMigrated, target user_model.ExternalUserRemappable) error {
 	var userid int64
 	var err error
@@ -839,6 +1773,7 @@ func (g *GiteaLocalUploader) remapUser(source user_model.ExternalUserMigrated, t
 	}
 
 	if userid > 0 {
+		g.rememberUserName(source.GetExternalName(), userid)
 		return target.RemapExternalUser("", 0, userid)
 	}
 	return target.RemapExternalUser(source.GetExternalName(), source.GetExternalID(), g.doer.ID)
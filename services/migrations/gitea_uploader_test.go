@@ -6,8 +6,12 @@
 package migrations
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -17,13 +21,21 @@ import (
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/foreignreference"
+	issues_model "code.gitea.io/gitea/models/issues"
 	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	webhook_model "code.gitea.io/gitea/models/webhook"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/graceful"
+	"code.gitea.io/gitea/modules/lfs"
 	"code.gitea.io/gitea/modules/log"
 	base "code.gitea.io/gitea/modules/migration"
+	repo_module "code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/util"
 
@@ -523,3 +535,1337 @@ func TestGiteaUploadUpdateGitForPullRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestGiteaUploadUpdateGitForPullRequestFromPatch(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	assert.NoError(t, repo.GetOwner(db.DefaultContext))
+
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	baseSHA, err := gitRepo.GetBranchCommitID(repo.DefaultBranch)
+	assert.NoError(t, err)
+
+	// create the patch of a head branch which is not part of the migrated repository
+	tmpDir := t.TempDir()
+	workDir := filepath.Join(tmpDir, "work")
+	assert.NoError(t, git.Clone(git.DefaultContext, repo.RepoPath(), workDir, git.CloneRepoOptions{Branch: repo.DefaultBranch}))
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, "patched.txt"), []byte("patched\n"), 0o644))
+	assert.NoError(t, git.AddChanges(workDir, true))
+	signature := git.Signature{Email: "test@example.com", Name: "test", When: time.Now()}
+	assert.NoError(t, git.CommitChanges(workDir, git.CommitChangesOptions{
+		Committer: &signature,
+		Author:    &signature,
+		Message:   "Add patched.txt",
+	}))
+	patch, err := git.NewCommand(git.DefaultContext, "format-patch", "--stdout", "-1").RunInDir(workDir)
+	assert.NoError(t, err)
+	patchPath := filepath.Join(tmpDir, "1.patch")
+	assert.NoError(t, os.WriteFile(patchPath, []byte(patch), 0o644))
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://example.com/remote/repo"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+
+	pr := base.PullRequest{
+		PatchURL: "file://" + patchPath,
+		Number:   100,
+		State:    "closed",
+		Base: base.PullRequestBranch{
+			Ref:       repo.DefaultBranch,
+			SHA:       baseSHA,
+			RepoName:  repo.Name,
+			OwnerName: repo.OwnerName,
+		},
+		Head: base.PullRequestBranch{
+			Ref:       "deleted-branch",
+			SHA:       "2697b352310fcd01cbd1f3dbd43b894080027f68",
+			RepoName:  repo.Name,
+			OwnerName: repo.OwnerName,
+		},
+	}
+	head, err := uploader.updateGitForPullRequest(&pr)
+	assert.NoError(t, err)
+	assert.Equal(t, "deleted-branch", head)
+
+	headSHA, err := git.NewCommand(git.DefaultContext, "rev-parse", "--verify", pr.GetGitRefName()).RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+	commit, err := gitRepo.GetCommit(strings.TrimSpace(headSHA))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, commit.ParentCount())
+	parentID, err := commit.ParentID(0)
+	assert.NoError(t, err)
+	assert.Equal(t, baseSHA, parentID.String())
+	blob, err := commit.GetBlobByPath("patched.txt")
+	assert.NoError(t, err)
+	content, err := blob.GetBlobContent()
+	assert.NoError(t, err)
+	assert.Equal(t, "patched\n", content)
+
+	// without a patch the missing head is removed as before
+	pr.Number = 101
+	pr.PatchURL = ""
+	_, err = uploader.updateGitForPullRequest(&pr)
+	assert.NoError(t, err)
+	_, err = git.NewCommand(git.DefaultContext, "rev-parse", "--verify", pr.GetGitRefName()).RunInDir(repo.RepoPath())
+	assert.Error(t, err)
+}
+
+func TestGiteaUploadResolvedReviewThread(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	assert.NoError(t, repo.GetOwner(db.DefaultContext))
+	issue := unittest.AssertExistsAndLoadBean(t, &models.Issue{RepoID: repo.ID, Index: 2, IsPull: true}).(*models.Issue)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://example.com/remote/repo"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+	// the pull request has been migrated by this run
+	uploader.issues[100] = issue
+
+	assert.NoError(t, uploader.CreateReviews(&base.Review{
+		IssueIndex:   100,
+		ReviewerName: "remote",
+		State:        base.ReviewStateCommented,
+		CreatedAt:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Comments: []*base.ReviewComment{
+			{Content: "resolved thread", TreePath: "README.md", Line: 1, Resolved: true, ResolverID: 1000, ResolverName: "remote"},
+			{Content: "open thread", TreePath: "README.md", Line: 2},
+		},
+	}))
+
+	// the resolver has no local user, the thread is resolved by the doer of the migration
+	resolved := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeCode, Content: "resolved thread"}).(*models.Comment)
+	assert.True(t, resolved.IsResolved())
+	assert.EqualValues(t, doer.ID, resolved.ResolveDoerID)
+	open := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeCode, Content: "open thread"}).(*models.Comment)
+	assert.False(t, open.IsResolved())
+}
+
+func TestGiteaUploadMergeIntoExisting(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	assert.NoError(t, repo.GetOwner(db.DefaultContext))
+
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	headBefore, err := gitRepo.GetBranchCommitID(repo.DefaultBranch)
+	assert.NoError(t, err)
+
+	existingIssues := unittest.GetCount(t, &models.Issue{RepoID: repo.ID})
+	var maxIndex int64
+	_, err = db.GetEngine(db.DefaultContext).Table("issue").Where("repo_id = ?", repo.ID).Select("MAX(`index`)").Get(&maxIndex)
+	assert.NoError(t, err)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	issues := []*base.Issue{
+		{Number: 1, ForeignIndex: 1, Title: "imported 1", PosterName: "remote", State: "open", Created: created, Labels: []*base.Label{{Name: "label1"}}},
+		{Number: 2, ForeignIndex: 2, Title: "imported 2", PosterName: "remote", State: "closed", Created: created},
+	}
+	comments := []*base.Comment{
+		{IssueIndex: 1, PosterName: "remote", Content: "imported comment", Created: created},
+	}
+
+	merge := func() {
+		uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+		defer uploader.Close()
+		assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://example.com/remote/tracker"}, base.MigrateOptions{
+			MigrateToRepoID:   repo.ID,
+			MergeIntoExisting: true,
+		}))
+		assert.NoError(t, uploader.CreateLabels(&base.Label{Name: "label1", Color: "abcdef"}, &base.Label{Name: "imported", Color: "000000"}))
+		assert.NoError(t, uploader.CreateIssues(issues...))
+		assert.NoError(t, uploader.CreateComments(comments...))
+		assert.NoError(t, uploader.Finish())
+	}
+
+	merge()
+	assert.EqualValues(t, existingIssues+2, unittest.GetCount(t, &models.Issue{RepoID: repo.ID}))
+	assert.EqualValues(t, 1, unittest.GetCount(t, &models.Label{RepoID: repo.ID, Name: "label1"}))
+	assert.EqualValues(t, 1, unittest.GetCount(t, &models.Label{RepoID: repo.ID, Name: "imported"}))
+
+	imported, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "imported 1", imported.Title)
+	assert.Greater(t, imported.Index, maxIndex)
+	assert.EqualValues(t, 1, unittest.GetCount(t, &models.Comment{IssueID: imported.ID, Content: "imported comment"}))
+
+	// importing the same tracker again must not duplicate anything
+	merge()
+	assert.EqualValues(t, existingIssues+2, unittest.GetCount(t, &models.Issue{RepoID: repo.ID}))
+	assert.EqualValues(t, 1, unittest.GetCount(t, &models.Comment{IssueID: imported.ID, Content: "imported comment"}))
+
+	// the git data has not been touched
+	headAfter, err := gitRepo.GetBranchCommitID(repo.DefaultBranch)
+	assert.NoError(t, err)
+	assert.Equal(t, headBefore, headAfter)
+}
+
+func TestGiteaUploadMergeUpdatesExisting(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	assert.NoError(t, repo.GetOwner(db.DefaultContext))
+
+	existingIssues := unittest.GetCount(t, &models.Issue{RepoID: repo.ID})
+	existingReleases := unittest.GetCount(t, &models.Release{RepoID: repo.ID})
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	merge := func(issue *base.Issue, release *base.Release, comments ...*base.Comment) {
+		uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+		defer uploader.Close()
+		assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://example.com/remote/tracker"}, base.MigrateOptions{
+			MigrateToRepoID:   repo.ID,
+			MergeIntoExisting: true,
+		}))
+		assert.NoError(t, uploader.CreateReleases(release))
+		assert.NoError(t, uploader.CreateIssues(issue))
+		assert.NoError(t, uploader.CreateComments(comments...))
+		assert.NoError(t, uploader.Finish())
+	}
+
+	merge(
+		&base.Issue{Number: 1, ForeignIndex: 1, Title: "first title", Content: "first content", PosterName: "remote", State: "open", Created: created},
+		&base.Release{TagName: "v1.1", Name: "first release", Body: "first note"},
+		&base.Comment{IssueIndex: 1, Index: 10, PosterName: "remote", Content: "first comment", Created: created},
+		&base.Comment{IssueIndex: 1, Index: 11, PosterName: "remote", Content: "second comment", Created: created.Add(time.Hour)},
+	)
+	imported, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, unittest.GetCount(t, &models.Comment{IssueID: imported.ID, Type: models.CommentTypeComment}))
+
+	// comments imported before their foreign indexes were recorded are recognized by their creation time
+	_, err = db.GetEngine(db.DefaultContext).Where("repo_id = ? AND foreign_index = ? AND type = ?", repo.ID, "11", foreignreference.TypeComment).Delete(&foreignreference.ForeignReference{})
+	assert.NoError(t, err)
+
+	closed := created.Add(2 * time.Hour)
+	merge(
+		&base.Issue{Number: 1, ForeignIndex: 1, Title: "edited title", Content: "edited content", PosterName: "remote", State: "closed", Created: created, Closed: &closed, Updated: closed},
+		&base.Release{TagName: "v1.1", Name: "edited release", Body: "edited note", Prerelease: true},
+		&base.Comment{IssueIndex: 1, Index: 10, PosterName: "remote", Content: "edited first comment", Created: created},
+		&base.Comment{IssueIndex: 1, Index: 11, PosterName: "remote", Content: "edited second comment", Created: created.Add(time.Hour)},
+		&base.Comment{IssueIndex: 1, Index: 12, PosterName: "remote", Content: "new comment", Created: closed},
+	)
+
+	assert.EqualValues(t, existingIssues+1, unittest.GetCount(t, &models.Issue{RepoID: repo.ID}))
+	issue := unittest.AssertExistsAndLoadBean(t, &models.Issue{ID: imported.ID}).(*models.Issue)
+	assert.Equal(t, "edited title", issue.Title)
+	assert.Equal(t, "edited content", issue.Content)
+	assert.True(t, issue.IsClosed)
+	assert.EqualValues(t, closed.Unix(), issue.ClosedUnix)
+
+	assert.EqualValues(t, 3, unittest.GetCount(t, &models.Comment{IssueID: imported.ID, Type: models.CommentTypeComment}))
+	for index, content := range map[int64]string{10: "edited first comment", 11: "edited second comment", 12: "new comment"} {
+		comment, err := models.GetCommentByForeignIndex(db.DefaultContext, repo.ID, index)
+		if assert.NoError(t, err) {
+			assert.Equal(t, imported.ID, comment.IssueID)
+			assert.Equal(t, content, comment.Content)
+		}
+	}
+
+	assert.EqualValues(t, existingReleases, unittest.GetCount(t, &models.Release{RepoID: repo.ID}))
+	release, err := models.GetRelease(repo.ID, "v1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited release", release.Title)
+	assert.Equal(t, "edited note", release.Note)
+	assert.True(t, release.IsPrerelease)
+}
+
+type mockDownloader struct {
+	base.NullDownloader
+	repo     *base.Repository
+	issues   []*base.Issue
+	statuses map[string][]*base.CommitStatus
+	topics   []string
+	times    map[int64][]*base.TrackedTime
+	labels   []*base.Label
+	releases []*base.Release
+	avatar   []byte
+
+	discussions []*base.Discussion
+	webhooks    []*base.Webhook
+	subscribers map[int64][]*base.IssueSubscriber
+}
+
+// allowsLocalCloneURL implements localCloneURLDownloader, the test repositories are cloned from local paths
+func (d *mockDownloader) allowsLocalCloneURL() {}
+
+func (d *mockDownloader) GetRepoInfo() (*base.Repository, error) {
+	return d.repo, nil
+}
+
+func (d *mockDownloader) GetReleases() ([]*base.Release, error) {
+	return d.releases, nil
+}
+
+func (d *mockDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, error) {
+	return d.issues, true, nil
+}
+
+func (d *mockDownloader) GetDiscussions(page, perPage int) ([]*base.Discussion, bool, error) {
+	return d.discussions, true, nil
+}
+
+func (d *mockDownloader) GetWebhooks() ([]*base.Webhook, error) {
+	return d.webhooks, nil
+}
+
+func (d *mockDownloader) GetCommitStatuses(sha string) ([]*base.CommitStatus, error) {
+	return d.statuses[sha], nil
+}
+
+func (d *mockDownloader) GetTopics() ([]string, error) {
+	if d.topics == nil {
+		return d.NullDownloader.GetTopics()
+	}
+	return d.topics, nil
+}
+
+func (d *mockDownloader) GetRepoAvatar() (io.ReadCloser, error) {
+	if d.avatar == nil {
+		return nil, nil
+	}
+	return io.NopCloser(bytes.NewReader(d.avatar)), nil
+}
+
+func (d *mockDownloader) GetLabels() ([]*base.Label, error) {
+	return d.labels, nil
+}
+
+func (d *mockDownloader) GetTrackedTimes(commentable base.Commentable) ([]*base.TrackedTime, error) {
+	if d.times == nil {
+		return d.NullDownloader.GetTrackedTimes(commentable)
+	}
+	return d.times[commentable.GetForeignIndex()], nil
+}
+
+func (d *mockDownloader) GetIssueSubscribers(commentable base.Commentable) ([]*base.IssueSubscriber, error) {
+	if d.subscribers == nil {
+		return d.NullDownloader.GetIssueSubscribers(commentable)
+	}
+	return d.subscribers[commentable.GetForeignIndex()], nil
+}
+
+func TestGiteaUploadTaskList(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	// the progress of a task list is computed from the content, bodies of the API of GitHub use CRLF line endings
+	content := "- [x] done\r\n- [ ] open\r\n  * [X] nested done\r\n\r\nnot a task: [x]\r\n- [ ] another open"
+	downloader := &mockDownloader{
+		repo: &base.Repository{Name: "tasks", OriginalURL: "https://example.com/remote/tasks"},
+		issues: []*base.Issue{
+			{Number: 1, ForeignIndex: 1, Title: "tasks", Content: content, PosterName: "remote", State: "open", Created: time.Now()},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:            true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, content, issue.Content)
+	assert.Equal(t, 4, issue.GetTasks())
+	assert.Equal(t, 2, issue.GetTasksDone())
+}
+
+func TestGiteaUploadLockedIssue(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloader := &mockDownloader{
+		repo: &base.Repository{Name: "tracker", OriginalURL: "https://example.com/remote/tracker"},
+		issues: []*base.Issue{
+			{Number: 1, ForeignIndex: 1, Title: "locked", PosterName: "remote", State: "open", Created: created, IsLocked: true, LockReason: "too heated"},
+			{Number: 2, ForeignIndex: 2, Title: "locked without reason", PosterName: "remote", State: "open", Created: created, IsLocked: true},
+			{Number: 3, ForeignIndex: 3, Title: "unlocked", PosterName: "remote", State: "open", Created: created, LockReason: "ignored"},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:            true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	for _, expected := range downloader.issues {
+		issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, expected.ForeignIndex)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.IsLocked, issue.IsLocked)
+
+		lockComments := unittest.GetCount(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeLock})
+		if expected.IsLocked && expected.LockReason != "" {
+			assert.EqualValues(t, 1, lockComments)
+			unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeLock, Content: expected.LockReason})
+		} else {
+			assert.EqualValues(t, 0, lockComments)
+		}
+	}
+}
+
+func TestGiteaUploadCommitStatuses(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repo.RepoPath())
+	assert.NoError(t, err)
+	headSHA, err := gitRepo.GetBranchCommitID("master")
+	gitRepo.Close()
+	assert.NoError(t, err)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloader := &mockDownloader{
+		statuses: map[string][]*base.CommitStatus{
+			// newest first, as the APIs return them
+			headSHA: {
+				{SHA: headSHA, State: "success", Context: "ci/build", TargetURL: "https://ci.example.com/2", Description: "passed", CreatorName: "ci-bot", Created: created.Add(time.Hour)},
+				{SHA: headSHA, State: "pending", Context: "ci/build", TargetURL: "https://ci.example.com/1", Description: "running", CreatorName: "ci-bot", Created: created},
+			},
+		},
+	}
+
+	upload := func() {
+		uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+		defer uploader.Close()
+		assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "ci", OriginalURL: "https://example.com/remote/ci"}, base.MigrateOptions{
+			MigrateToRepoID:   repo.ID,
+			MergeIntoExisting: true,
+		}))
+		statuses, err := downloader.GetCommitStatuses(headSHA)
+		assert.NoError(t, err)
+		assert.NoError(t, uploader.CreateCommitStatuses(statuses...))
+	}
+
+	upload()
+	statuses, _, err := models.GetCommitStatuses(repo, headSHA, &models.CommitStatusOptions{ListOptions: db.ListOptions{Page: 1, PageSize: 10}, SortType: "highestindex"})
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.EqualValues(t, "pending", statuses[0].State)
+		assert.Equal(t, "https://ci.example.com/1", statuses[0].TargetURL)
+		assert.EqualValues(t, "success", statuses[1].State)
+		assert.Equal(t, "passed", statuses[1].Description)
+		assert.Equal(t, doer.ID, statuses[1].CreatorID)
+	}
+
+	// merging again doesn't duplicate the statuses
+	upload()
+	_, count, err := models.GetCommitStatuses(repo, headSHA, &models.CommitStatusOptions{ListOptions: db.ListOptions{Page: 1, PageSize: 10}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestGiteaUploadArchivedSource(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Enabled=%t", enabled), func(t *testing.T) {
+			unittest.PrepareTestEnv(t)
+
+			doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+			repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+			assert.False(t, repo.IsArchived)
+
+			downloader := &mockDownloader{
+				repo: &base.Repository{Name: "archived", OriginalURL: "https://example.com/remote/archived", IsArchived: true},
+				issues: []*base.Issue{
+					{Number: 1, ForeignIndex: 1, Title: "open issue", PosterName: "remote", State: "open", Created: time.Now()},
+				},
+			}
+			uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+			assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+				Issues:                     true,
+				MigrateToRepoID:            repo.ID,
+				MergeIntoExisting:          true,
+				ArchiveIfSourceArchived:    enabled,
+				LockIssuesIfSourceArchived: enabled,
+			}, nil))
+
+			repo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+			assert.Equal(t, enabled, repo.IsArchived)
+
+			issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, enabled, issue.IsLocked)
+		})
+	}
+}
+
+type mockHistoryDownloader struct {
+	mockDownloader
+	comments map[int64][]*base.Comment
+	history  map[int64][]*base.CommentVersion
+}
+
+func (d *mockHistoryDownloader) GetComments(commentable base.Commentable) ([]*base.Comment, bool, error) {
+	return d.comments[commentable.GetForeignIndex()], true, nil
+}
+
+func (d *mockHistoryDownloader) GetCommentHistory(comment *base.Comment) ([]*base.CommentVersion, error) {
+	return d.history[comment.Index], nil
+}
+
+func TestGiteaUploadCommentHistory(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloader := &mockHistoryDownloader{
+		mockDownloader: mockDownloader{
+			repo: &base.Repository{Name: "history", OriginalURL: "https://example.com/remote/history"},
+		},
+		comments: make(map[int64][]*base.Comment),
+		history: map[int64][]*base.CommentVersion{
+			10: {
+				{Content: "first version", Edited: created},
+				{Content: "second version", Edited: created.Add(time.Hour)},
+			},
+		},
+	}
+
+	migrateIssue := func(foreignIndex int64, commentHistory bool) *models.Issue {
+		downloader.issues = []*base.Issue{
+			{Number: foreignIndex, ForeignIndex: foreignIndex, Title: "edited", PosterName: "remote", State: "open", Created: created},
+		}
+		downloader.comments[foreignIndex] = []*base.Comment{
+			{IssueIndex: foreignIndex, Index: 10, PosterName: "remote", Content: "third version", Created: created, Updated: created.Add(2 * time.Hour)},
+			{IssueIndex: foreignIndex, Index: 11, PosterName: "remote", Content: "never edited", Created: created},
+		}
+
+		uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+		assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+			Issues:            true,
+			Comments:          true,
+			CommentHistory:    commentHistory,
+			MigrateToRepoID:   repo.ID,
+			MergeIntoExisting: true,
+		}, nil))
+
+		issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, foreignIndex)
+		assert.NoError(t, err)
+		return issue
+	}
+
+	// the history is opt-in
+	issue := migrateIssue(1, false)
+	unittest.AssertNotExistsBean(t, &issues_model.ContentHistory{IssueID: issue.ID})
+
+	issue = migrateIssue(2, true)
+	edited := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Content: "third version"}).(*models.Comment)
+	unedited := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Content: "never edited"}).(*models.Comment)
+
+	history, err := issues_model.FetchIssueContentHistoryList(db.DefaultContext, issue.ID, edited.ID)
+	assert.NoError(t, err)
+	contents := make([]string, 0, len(history))
+	for _, h := range history {
+		ch := unittest.AssertExistsAndLoadBean(t, &issues_model.ContentHistory{ID: h.HistoryID}).(*issues_model.ContentHistory)
+		contents = append(contents, ch.ContentText)
+	}
+	// newest first
+	assert.Equal(t, []string{"third version", "second version", "first version"}, contents)
+	if assert.Len(t, history, 3) {
+		assert.True(t, history[2].IsFirstCreated)
+		assert.EqualValues(t, created.Add(2*time.Hour).Unix(), history[0].EditedUnix)
+	}
+
+	unittest.AssertNotExistsBean(t, &issues_model.ContentHistory{CommentID: unedited.ID})
+}
+
+func TestGiteaUploadClosedMilestone(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	deadline := created.Add(7 * 24 * time.Hour)
+	closed := created.Add(24 * time.Hour)
+
+	// round-trip the milestones through a dump
+	dumpDir := t.TempDir()
+	dumper, err := NewRepositoryDumper(context.Background(), dumpDir, "remote", "milestones", base.MigrateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, dumper.CreateMilestones(
+		&base.Milestone{Title: "closed with deadline", Deadline: &deadline, Created: created, Updated: &updated, Closed: &closed, State: "closed"},
+		&base.Milestone{Title: "closed without date", Created: created, Updated: &updated, State: "closed"},
+		&base.Milestone{Title: "open", Deadline: &deadline, Created: created, Updated: &updated, State: "open"},
+	))
+	dumper.Close()
+
+	restorer, err := NewRepositoryRestorer(context.Background(), filepath.Join(dumpDir, "remote", "milestones"), "remote", "milestones", false)
+	assert.NoError(t, err)
+	milestones, err := restorer.GetMilestones()
+	assert.NoError(t, err)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "milestones", OriginalURL: "https://example.com/remote/milestones"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+	assert.NoError(t, uploader.CreateMilestones(milestones...))
+
+	ms := unittest.AssertExistsAndLoadBean(t, &models.Milestone{RepoID: repo.ID, Name: "closed with deadline"}).(*models.Milestone)
+	assert.True(t, ms.IsClosed)
+	assert.EqualValues(t, deadline.Unix(), ms.DeadlineUnix)
+	assert.EqualValues(t, closed.Unix(), ms.ClosedDateUnix)
+
+	ms = unittest.AssertExistsAndLoadBean(t, &models.Milestone{RepoID: repo.ID, Name: "closed without date"}).(*models.Milestone)
+	assert.True(t, ms.IsClosed)
+	assert.EqualValues(t, updated.Unix(), ms.ClosedDateUnix)
+
+	ms = unittest.AssertExistsAndLoadBean(t, &models.Milestone{RepoID: repo.ID, Name: "open"}).(*models.Milestone)
+	assert.False(t, ms.IsClosed)
+	assert.EqualValues(t, deadline.Unix(), ms.DeadlineUnix)
+	assert.Zero(t, ms.ClosedDateUnix)
+
+	unittest.CheckConsistencyFor(t, &repo_model.Repository{ID: repo.ID})
+}
+
+func TestGiteaUploadTopics(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	migrate := func(topics []string) {
+		downloader := &mockDownloader{
+			repo:   &base.Repository{Name: "topics", OriginalURL: "https://example.com/remote/topics"},
+			topics: topics,
+		}
+		uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+		assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+			MigrateToRepoID:   repo.ID,
+			MergeIntoExisting: true,
+		}, nil))
+	}
+	repoTopics := func() []string {
+		topics, _, err := repo_model.FindTopics(&repo_model.FindTopicOptions{RepoID: repo.ID})
+		assert.NoError(t, err)
+		names := make([]string, 0, len(topics))
+		for _, topic := range topics {
+			names = append(names, topic.Name)
+		}
+		return names
+	}
+	existing := repoTopics()
+
+	// sources without topics are ignored
+	migrate(nil)
+	assert.ElementsMatch(t, existing, repoTopics())
+
+	migrate([]string{"Migration", " gitea ", "gitea", "not a topic", "-invalid", strings.Repeat("a", 36), ""})
+	assert.ElementsMatch(t, append(existing, "migration", "gitea"), repoTopics())
+}
+
+func TestGiteaUploadTrackedTimes(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	spent := created.Add(time.Hour)
+	downloader := &mockDownloader{
+		// the same instance, so the users are remapped by their ID and name
+		repo: &base.Repository{Name: "tracker", OriginalURL: setting.AppURL + "remote/tracker"},
+		issues: []*base.Issue{
+			{Number: 1, ForeignIndex: 1, Title: "tracked", PosterName: "remote", State: "open", Created: created},
+			{Number: 2, ForeignIndex: 2, Title: "untracked", PosterName: "remote", State: "open", Created: created},
+		},
+		times: map[int64][]*base.TrackedTime{
+			1: {
+				{IssueIndex: 1, UserID: 2, UserName: "user2", Time: 3600, Created: spent},
+				{IssueIndex: 1, UserID: 4, UserName: "renamed", Time: 60, Created: spent},
+				{IssueIndex: 1, Time: 120},
+			},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:            true,
+		TrackedTimes:      true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	tracked, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	unittest.AssertExistsAndLoadBean(t, &models.TrackedTime{IssueID: tracked.ID, UserID: 2, Time: 3600, CreatedUnix: spent.Unix()})
+	// unknown users fall back to the doer
+	unittest.AssertExistsAndLoadBean(t, &models.TrackedTime{IssueID: tracked.ID, UserID: doer.ID, Time: 60, CreatedUnix: spent.Unix()})
+	// entries without a creation time get the one of the issue
+	unittest.AssertExistsAndLoadBean(t, &models.TrackedTime{IssueID: tracked.ID, UserID: doer.ID, Time: 120, CreatedUnix: created.Unix()})
+	assert.EqualValues(t, 3, unittest.GetCount(t, &models.TrackedTime{IssueID: tracked.ID}))
+
+	untracked, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 2)
+	assert.NoError(t, err)
+	unittest.AssertNotExistsBean(t, &models.TrackedTime{IssueID: untracked.ID})
+}
+
+func TestGiteaUploadIssueSubscribers(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloader := &mockDownloader{
+		// the same instance, so the users are remapped by their ID and name
+		repo: &base.Repository{Name: "subscribers", OriginalURL: setting.AppURL + "remote/subscribers"},
+		issues: []*base.Issue{
+			{Number: 1, ForeignIndex: 1, Title: "subscribed", PosterName: "remote", State: "open", Created: created},
+		},
+		subscribers: map[int64][]*base.IssueSubscriber{
+			1: {
+				{IssueIndex: 1, UserID: 2, UserName: "user2"},
+				{IssueIndex: 1, UserID: 4, UserName: "user4"},
+			},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:            true,
+		IssueSubscribers:  true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	watchers, err := models.GetIssueWatchersIDs(issue.ID, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int64{2, 4}, watchers)
+}
+
+func TestGiteaUploadReleaseAssets(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(maxSize int64) {
+		setting.Attachment.MaxSize = maxSize
+	}(setting.Attachment.MaxSize)
+	setting.Attachment.MaxSize = 1
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "assets", OriginalURL: "https://example.com/remote/assets"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+
+	download := func(content string) func() (io.ReadCloser, error) {
+		return func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		}
+	}
+	intPtr := func(i int) *int { return &i }
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, uploader.CreateReleases(&base.Release{
+		TagName: "v-assets",
+		Name:    "assets",
+		Created: created,
+		Assets: []*base.ReleaseAsset{
+			{Name: "binary", Size: intPtr(7), DownloadCount: intPtr(3), DownloadFunc: download("content")},
+			{Name: "unknown-size", DownloadFunc: download("unknown")},
+			{Name: "too-big", Size: intPtr(2 * 1024 * 1024), DownloadFunc: download("never downloaded")},
+			{Name: "wrong-size", Size: intPtr(1), DownloadFunc: download(strings.Repeat("x", 1024*1024+1))},
+			{Name: "unsupported", DownloadFunc: func() (io.ReadCloser, error) {
+				return nil, base.ErrNotSupported{Entity: "ReleaseAssets"}
+			}},
+		},
+	}))
+
+	release, err := models.GetRelease(repo.ID, "v-assets")
+	assert.NoError(t, err)
+	assert.NoError(t, models.GetReleaseAttachments(release))
+	if assert.Len(t, release.Attachments, 2) {
+		for i, expected := range []struct {
+			name          string
+			content       string
+			downloadCount int64
+		}{
+			{name: "binary", content: "content", downloadCount: 3},
+			{name: "unknown-size", content: "unknown"},
+		} {
+			attach := release.Attachments[i]
+			assert.Equal(t, expected.name, attach.Name)
+			assert.EqualValues(t, len(expected.content), attach.Size)
+			assert.Equal(t, expected.downloadCount, attach.DownloadCount)
+			assert.EqualValues(t, created.Unix(), attach.CreatedUnix)
+
+			f, err := storage.Attachments.Open(attach.RelativePath())
+			if assert.NoError(t, err) {
+				content, err := io.ReadAll(f)
+				f.Close()
+				assert.NoError(t, err)
+				assert.Equal(t, expected.content, string(content))
+			}
+		}
+	}
+}
+
+func TestGiteaUploadReleaseTime(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	_, err := git.NewCommand(git.DefaultContext, "tag", "v-undated", "master").RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repo.RepoPath())
+	assert.NoError(t, err)
+	commit, err := gitRepo.GetBranchCommit("master")
+	gitRepo.Close()
+	assert.NoError(t, err)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "releases", OriginalURL: "https://example.com/remote/releases"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+
+	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	published := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, uploader.CreateReleases(
+		&base.Release{TagName: "v-published", Name: "published", Created: created, Published: published},
+		&base.Release{TagName: "v-created", Name: "created", Created: created},
+		&base.Release{TagName: "v-draft", Name: "draft", Draft: true, Created: created, Published: published},
+		&base.Release{TagName: "v-undated", Name: "undated"},
+	))
+
+	for tagName, expected := range map[string]time.Time{
+		"v-published": published,
+		"v-created":   created,
+		"v-draft":     created,
+		// releases without dates are dated like the ones created from tags
+		"v-undated": commit.Author.When,
+	} {
+		release, err := models.GetRelease(repo.ID, tagName)
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, expected.Unix(), release.CreatedUnix, tagName)
+		}
+	}
+}
+
+func TestGiteaUploadReleasePublisher(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2}).(*user_model.User)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "releases", OriginalURL: "https://example.com/remote/releases"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+
+	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, uploader.CreateReleases(
+		&base.Release{TagName: "v-known", Name: "known", Draft: true, Created: created, PublisherID: 1001, PublisherName: "remote-user2", PublisherEmail: user2.Email},
+		&base.Release{TagName: "v-unknown", Name: "unknown", Draft: true, Created: created, PublisherID: 1002, PublisherName: "remote-other", PublisherEmail: "other@example.com"},
+		&base.Release{TagName: "v-no-email", Name: "no email", Draft: true, Created: created, PublisherID: 1003, PublisherName: "remote-silent"},
+	))
+
+	known, err := models.GetRelease(repo.ID, "v-known")
+	assert.NoError(t, err)
+	assert.EqualValues(t, user2.ID, known.PublisherID)
+	assert.Empty(t, known.OriginalAuthor)
+	assert.Zero(t, known.OriginalAuthorID)
+	// mentions of the publisher are rewritten to the resolved user
+	assert.Equal(t, user2.Name, uploader.userNames["remote-user2"])
+
+	for tagName, author := range map[string]string{"v-unknown": "remote-other", "v-no-email": "remote-silent"} {
+		release, err := models.GetRelease(repo.ID, tagName)
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, doer.ID, release.PublisherID, tagName)
+			assert.Equal(t, author, release.OriginalAuthor, tagName)
+		}
+	}
+}
+
+func TestGiteaUploadScopedLabels(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	scoped := &base.Label{Name: "kind/bug", Color: "ee0701", Exclusive: true}
+	downloader := &mockDownloader{
+		repo:   &base.Repository{Name: "scoped", OriginalURL: "https://example.com/remote/scoped"},
+		labels: []*base.Label{scoped, {Name: "docs", Color: "0052cc"}},
+		issues: []*base.Issue{
+			{Number: 1, ForeignIndex: 1, Title: "scoped", PosterName: "remote", State: "open", Created: time.Now(), Labels: []*base.Label{scoped}},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Labels:            true,
+		Issues:            true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	label := unittest.AssertExistsAndLoadBean(t, &models.Label{RepoID: repo.ID, Name: "kind/bug"}).(*models.Label)
+	assert.True(t, label.Exclusive)
+	assert.Equal(t, "kind", label.ExclusiveScope())
+	unittest.AssertExistsAndLoadBean(t, &models.IssueLabel{LabelID: label.ID})
+
+	label = unittest.AssertExistsAndLoadBean(t, &models.Label{RepoID: repo.ID, Name: "docs"}).(*models.Label)
+	assert.False(t, label.Exclusive)
+	assert.Empty(t, label.ExclusiveScope())
+}
+
+func TestGiteaUploadRollbackLFS(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repoName := "rollback"
+
+	repo, err := repo_module.CreateRepository(doer, doer, models.CreateRepoOptions{Name: repoName})
+	assert.NoError(t, err)
+
+	contentStore := lfs.NewContentStore()
+	storeObject := func(repoID int64, content string) lfs.Pointer {
+		p, err := lfs.GeneratePointer(strings.NewReader(content))
+		assert.NoError(t, err)
+		assert.NoError(t, contentStore.Put(p, strings.NewReader(content)))
+		_, err = models.NewLFSMetaObject(&models.LFSMetaObject{Pointer: p, RepositoryID: repoID})
+		assert.NoError(t, err)
+		return p
+	}
+	migrated := storeObject(repo.ID, "only migrated")
+	shared := storeObject(repo.ID, "shared with another repository")
+	storeObject(1, "shared with another repository")
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, repoName)
+	uploader.repo = repo
+	assert.NoError(t, uploader.Rollback())
+
+	unittest.AssertNotExistsBean(t, &repo_model.Repository{ID: repo.ID})
+	unittest.AssertNotExistsBean(t, &models.LFSMetaObject{RepositoryID: repo.ID})
+	unittest.AssertExistsAndLoadBean(t, &models.LFSMetaObject{Pointer: lfs.Pointer{Oid: shared.Oid}, RepositoryID: 1})
+
+	exist, err := contentStore.Exists(migrated)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	// the content referenced by another repository is kept
+	exist, err = contentStore.Exists(shared)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestGiteaUploadReleaseFlags(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{Name: "releases", OriginalURL: "https://example.com/remote/releases"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+
+	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, uploader.CreateReleases(
+		&base.Release{TagName: "v-draft", Name: "draft", Draft: true, Created: created},
+		&base.Release{TagName: "v-prerelease", Name: "prerelease", Prerelease: true, Created: created},
+	))
+
+	// the tag of the draft is pushed later, synchronizing the tags must not publish the draft
+	for _, tagName := range []string{"v-draft", "v-prerelease"} {
+		_, err := git.NewCommand(git.DefaultContext, "tag", tagName, "master").RunInDir(repo.RepoPath())
+		assert.NoError(t, err)
+	}
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	assert.NoError(t, repo_module.SyncReleasesWithTags(repo, gitRepo))
+
+	draft, err := models.GetRelease(repo.ID, "v-draft")
+	assert.NoError(t, err)
+	assert.True(t, draft.IsDraft)
+	assert.False(t, draft.IsTag)
+	prerelease, err := models.GetRelease(repo.ID, "v-prerelease")
+	assert.NoError(t, err)
+	assert.True(t, prerelease.IsPrerelease)
+	assert.False(t, prerelease.IsDraft)
+}
+
+func TestGiteaUploadRewriteReferences(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	linkedUser := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2}).(*user_model.User)
+
+	externalID := int64(7654321)
+	assert.NoError(t, user_model.LinkExternalToUser(linkedUser, &user_model.ExternalLoginUser{
+		ExternalID: strconv.FormatInt(externalID, 10),
+		UserID:     linkedUser.ID,
+		Provider:   structs.GithubService.Name(),
+	}))
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	uploader.gitServiceType = structs.GithubService
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://github.com/remote/tracker"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+	assert.NoError(t, uploader.CreateIssues(
+		&base.Issue{Number: 1, ForeignIndex: 1, Title: "original", PosterID: externalID, PosterName: "octocat", State: "open", Created: created},
+		&base.Issue{Number: 2, ForeignIndex: 2, Title: "duplicate", PosterName: "remote", State: "open", Created: created, Content: "Duplicate of #1 reported by @octocat, see also #99 and @unknown"},
+	))
+	assert.NoError(t, uploader.CreateComments(&base.Comment{IssueIndex: 1, PosterID: externalID, PosterName: "octocat", Created: created, Content: "Thanks @Octocat, continued in #2."}))
+	assert.NoError(t, uploader.Finish())
+
+	original, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	duplicate, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 2)
+	assert.NoError(t, err)
+	assert.NotEqualValues(t, 1, original.Index, "merging into the repository renumbers the issues")
+
+	assert.Equal(t, fmt.Sprintf("Duplicate of #%d reported by @%s, see also #99 and @unknown", original.Index, linkedUser.Name), duplicate.Content)
+	comment := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: original.ID, Type: models.CommentTypeComment}).(*models.Comment)
+	assert.Equal(t, fmt.Sprintf("Thanks @%s, continued in #%d.", linkedUser.Name, duplicate.Index), comment.Content)
+}
+
+func TestRewriteContentReferences(t *testing.T) {
+	issueIndexes := map[int64]int64{1: 11, 2: 12}
+	userNames := map[string]string{"octocat": "user2"}
+	for content, expected := range map[string]string{
+		"":                            "",
+		"#1 and #2":                   "#11 and #12",
+		"!2, (#1) and #3":             "!12, (#11) and #3",
+		"@octocat @OctoCat @other":    "@user2 @user2 @other",
+		"issue#1 mail@octocat.com #x": "issue#1 mail@octocat.com #x",
+		"@octocat/team":               "@octocat/team",
+	} {
+		assert.Equal(t, expected, rewriteContentReferences(content, issueIndexes, userNames), content)
+	}
+}
+
+func TestGiteaUploadOriginalURL(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{OriginalURL: "https://example.com/remote/tracker"}, base.MigrateOptions{
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}))
+	assert.NoError(t, uploader.CreateIssues(&base.Issue{
+		Number:       1,
+		ForeignIndex: 1,
+		Title:        "imported",
+		PosterName:   "remote",
+		State:        "open",
+		Created:      created,
+		OriginalURL:  "https://example.com/remote/tracker/issues/1",
+	}))
+	assert.NoError(t, uploader.CreateComments(&base.Comment{
+		IssueIndex:  1,
+		PosterName:  "remote",
+		Content:     "imported comment",
+		Created:     created,
+		OriginalURL: "https://example.com/remote/tracker/issues/1#issuecomment-7",
+	}))
+	pull, err := uploader.newPullRequest(&base.PullRequest{
+		Number:       2,
+		ForeignIndex: 2,
+		Title:        "imported pull",
+		PosterName:   "remote",
+		State:        "closed",
+		Created:      created,
+		Head:         base.PullRequestBranch{Ref: "master", SHA: "65f1bf27bc3bf70f64657658635e66094edbcb4d", RepoName: repo.Name, OwnerName: repo.OwnerName},
+		Base:         base.PullRequestBranch{Ref: "master", SHA: "65f1bf27bc3bf70f64657658635e66094edbcb4d", RepoName: repo.Name, OwnerName: repo.OwnerName},
+		OriginalURL:  "https://example.com/remote/tracker/pulls/2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/remote/tracker/pulls/2", pull.Issue.OriginalURL)
+	assert.NoError(t, uploader.Finish())
+
+	issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/remote/tracker/issues/1", issue.OriginalURL)
+	comment := unittest.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeComment}).(*models.Comment)
+	assert.Equal(t, "https://example.com/remote/tracker/issues/1#issuecomment-7", comment.OriginalURL)
+}
+
+func TestGiteaUploadSkipReleaseSync(t *testing.T) {
+	for _, tc := range []struct {
+		releases, skip bool
+	}{
+		{releases: true},
+		{releases: false},
+		{releases: true, skip: true},
+		{releases: false, skip: true},
+	} {
+		t.Run(fmt.Sprintf("Releases=%t,Skip=%t", tc.releases, tc.skip), func(t *testing.T) {
+			unittest.PrepareTestEnv(t)
+
+			doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+			source := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+			repoName := fmt.Sprintf("skip-release-sync-%t-%t", tc.releases, tc.skip)
+			downloader := &mockDownloader{
+				repo: &base.Repository{Name: repoName, CloneURL: source.RepoPath(), OriginalURL: "https://example.com/remote/" + repoName},
+				releases: []*base.Release{
+					{TagName: "v1.1", Name: "imported", TargetCommitish: "master", Created: time.Now()},
+				},
+			}
+			uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, repoName)
+			assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+				RepoName:        repoName,
+				CloneAddr:       source.RepoPath(),
+				Releases:        tc.releases,
+				SkipReleaseSync: tc.skip,
+			}, nil))
+
+			repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{OwnerID: doer.ID, Name: repoName}).(*repo_model.Repository)
+			count, err := models.GetReleaseCountByRepoID(repo.ID, models.FindReleasesOptions{IncludeTags: true})
+			assert.NoError(t, err)
+			if tc.skip {
+				assert.Zero(t, count)
+				return
+			}
+
+			// either the imported release or the synchronized tag v1.1 of the source
+			assert.EqualValues(t, 1, count)
+			release, err := models.GetRelease(repo.ID, "v1.1")
+			assert.NoError(t, err)
+			assert.Equal(t, !tc.releases, release.IsTag)
+		})
+	}
+}
+
+func TestGiteaUploadMergeSettings(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	source := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	repoName := "merge-settings"
+	downloader := &mockDownloader{
+		repo: &base.Repository{
+			Name:        repoName,
+			CloneURL:    source.RepoPath(),
+			OriginalURL: "https://example.com/remote/" + repoName,
+			MergeSettings: &base.MergeSettings{
+				AllowRebaseMerge:       true,
+				AllowSquash:            true,
+				DefaultMergeStyle:      "squash",
+				DeleteBranchAfterMerge: true,
+			},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, repoName)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		RepoName:  repoName,
+		CloneAddr: source.RepoPath(),
+	}, nil))
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{OwnerID: doer.ID, Name: repoName}).(*repo_model.Repository)
+	u, err := repo.GetUnit(unit.TypePullRequests)
+	assert.NoError(t, err)
+	cfg := u.PullRequestsConfig()
+	assert.False(t, cfg.AllowMerge)
+	assert.False(t, cfg.AllowRebase)
+	assert.True(t, cfg.AllowRebaseMerge)
+	assert.True(t, cfg.AllowSquash)
+	assert.Equal(t, repo_model.MergeStyleSquash, cfg.DefaultMergeStyle)
+	assert.True(t, cfg.DefaultDeleteBranchAfterMerge)
+}
+
+func TestGiteaUploadRepoAvatar(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 16))))
+	pngAvatar := buf.Bytes()
+
+	defer func(maxFileSize int64) {
+		setting.Avatar.MaxFileSize = maxFileSize
+	}(setting.Avatar.MaxFileSize)
+	setting.Avatar.MaxFileSize = int64(len(pngAvatar))
+
+	for _, tc := range []struct {
+		name   string
+		avatar []byte
+		valid  bool
+	}{
+		{name: "png", avatar: pngAvatar, valid: true},
+		{name: "none"},
+		{name: "svg", avatar: []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16"></svg>`)},
+		{name: "text", avatar: []byte("not an image")},
+		{name: "too-big", avatar: append(append([]byte{}, pngAvatar...), 0)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			unittest.PrepareTestEnv(t)
+
+			doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+			source := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+			repoName := "avatar-" + tc.name
+			downloader := &mockDownloader{
+				repo:   &base.Repository{Name: repoName, CloneURL: source.RepoPath(), OriginalURL: "https://example.com/remote/" + repoName},
+				avatar: tc.avatar,
+			}
+			uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, repoName)
+			assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+				RepoName:  repoName,
+				CloneAddr: source.RepoPath(),
+			}, nil))
+
+			repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{OwnerID: doer.ID, Name: repoName}).(*repo_model.Repository)
+			if !tc.valid {
+				assert.Empty(t, repo.Avatar)
+				return
+			}
+			assert.NotEmpty(t, repo.Avatar)
+			f, err := storage.RepoAvatars.Open(repo.CustomAvatarRelativePath())
+			assert.NoError(t, err)
+			defer f.Close()
+			_, err = png.Decode(f)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGiteaUploadDiscussions(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloader := &mockDownloader{
+		repo: &base.Repository{Name: "discussions", OriginalURL: "https://example.com/remote/discussions"},
+		discussions: []*base.Discussion{
+			{
+				Number:     100,
+				Category:   "Q&A",
+				PosterID:   7,
+				PosterName: "asker",
+				Title:      "How to configure it?",
+				Content:    "Is there a setting for this?",
+				State:      "open",
+				Created:    created,
+				Updated:    created,
+				Replies: []*base.Comment{
+					{IssueIndex: 100, PosterID: 8, PosterName: "helper", Content: "Yes, see the docs.", Created: created.Add(time.Hour)},
+					{IssueIndex: 100, PosterID: 7, PosterName: "asker", Content: "Thanks!", Created: created.Add(2 * time.Hour)},
+				},
+			},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:            true,
+		Comments:          true,
+		Discussions:       true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	issue, err := models.GetIssueByForeignIndex(db.DefaultContext, repo.ID, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "How to configure it?", issue.Title)
+	assert.Equal(t, "asker", issue.OriginalAuthor)
+	assert.EqualValues(t, 7, issue.OriginalAuthorID)
+	assert.False(t, issue.IsPull)
+
+	assert.NoError(t, issue.LoadLabels())
+	if assert.Len(t, issue.Labels, 1) {
+		assert.Equal(t, "Discussion/Q&A", issue.Labels[0].Name)
+	}
+
+	comments, err := models.FindComments(&models.FindCommentsOptions{IssueID: issue.ID, Type: models.CommentTypeComment})
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, "helper", comments[0].OriginalAuthor)
+		assert.Equal(t, "Yes, see the docs.", comments[0].Content)
+		assert.Equal(t, "asker", comments[1].OriginalAuthor)
+		assert.Equal(t, "Thanks!", comments[1].Content)
+	}
+}
+
+func TestGiteaUploadWebhooks(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(allowedHostList string) {
+		setting.Webhook.AllowedHostList = allowedHostList
+	}(setting.Webhook.AllowedHostList)
+	setting.Webhook.AllowedHostList = "ci.example.com"
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	existing, err := webhook_model.ListWebhooksByOpts(&webhook_model.ListWebhookOptions{RepoID: repo.ID})
+	assert.NoError(t, err)
+
+	downloader := &mockDownloader{
+		repo: &base.Repository{Name: "webhooks", OriginalURL: "https://example.com/remote/webhooks"},
+		webhooks: []*base.Webhook{
+			{ID: 1, URL: "https://ci.example.com/hook", Type: "gitea", ContentType: "json", Events: []string{"push", "pull_request"}, BranchFilter: "main", Active: true},
+			// not allowed by the allowed host list
+			{ID: 2, URL: "http://127.0.0.1:3000/hook", Type: "gitea", ContentType: "json", Events: []string{"push"}, Active: true},
+		},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Webhooks:          true,
+		MigrateToRepoID:   repo.ID,
+		MergeIntoExisting: true,
+	}, nil))
+
+	webhooks, err := webhook_model.ListWebhooksByOpts(&webhook_model.ListWebhookOptions{RepoID: repo.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, webhooks, len(existing)+1) {
+		w := webhooks[len(webhooks)-1]
+		assert.Equal(t, "https://ci.example.com/hook", w.URL)
+		assert.Equal(t, webhook_model.GITEA, w.Type)
+		assert.Equal(t, webhook_model.ContentTypeJSON, w.ContentType)
+		assert.True(t, w.IsActive)
+		assert.True(t, w.HasPushEvent())
+		assert.True(t, w.HasPullRequestEvent())
+		assert.False(t, w.HasIssuesEvent())
+		assert.Equal(t, "main", w.BranchFilter)
+		assert.Len(t, w.Secret, 32)
+	}
+}
@@ -75,6 +75,8 @@ type GithubDownloaderV3 struct {
 	curClientIdx  int
 	maxPerPage    int
 	SkipReactions bool
+	// httpClient downloads the release assets redirected to other hosts
+	httpClient *http.Client
 }
 
 // NewGithubDownloaderV3 creates a github Downloader via github v3 API
@@ -86,6 +88,7 @@ func NewGithubDownloaderV3(ctx context.Context, baseURL, userName, password, tok
 		repoOwner:  repoOwner,
 		repoName:   repoName,
 		maxPerPage: 100,
+		httpClient: NewMigrationHTTPClient(),
 	}
 
 	if token != "" {
@@ -96,10 +99,10 @@ func NewGithubDownloaderV3(ctx context.Context, baseURL, userName, password, tok
 				&oauth2.Token{AccessToken: token},
 			)
 			client := &http.Client{
-				Transport: &oauth2.Transport{
+				Transport: newRateLimitedTransport(&oauth2.Transport{
 					Base:   NewMigrationHTTPTransport(),
 					Source: oauth2.ReuseTokenSource(nil, ts),
-				},
+				}),
 			}
 
 			downloader.addClient(client, baseURL)
@@ -111,7 +114,7 @@ func NewGithubDownloaderV3(ctx context.Context, baseURL, userName, password, tok
 			return proxy.Proxy()(req)
 		}
 		client := &http.Client{
-			Transport: transport,
+			Transport: newRateLimitedTransport(transport),
 		}
 		downloader.addClient(client, baseURL)
 	}
@@ -201,9 +204,35 @@ func (g *GithubDownloaderV3) GetRepoInfo() (*base.Repository, error) {
 		OriginalURL:   gr.GetHTMLURL(),
 		CloneURL:      gr.GetCloneURL(),
 		DefaultBranch: gr.GetDefaultBranch(),
+		IsArchived:    gr.GetArchived(),
+		MergeSettings: convertGithubMergeSettings(gr),
 	}, nil
 }
 
+// convertGithubMergeSettings converts the merge settings of a github repository, GitHub only returns them
+// to users with push access. The rebase merge of GitHub rebases without a merge commit like Gitea's rebase.
+func convertGithubMergeSettings(gr *github.Repository) *base.MergeSettings {
+	if gr.AllowMergeCommit == nil && gr.AllowRebaseMerge == nil && gr.AllowSquashMerge == nil {
+		return nil
+	}
+	settings := &base.MergeSettings{
+		AllowMerge:             gr.GetAllowMergeCommit(),
+		AllowRebase:            gr.GetAllowRebaseMerge(),
+		AllowSquash:            gr.GetAllowSquashMerge(),
+		DeleteBranchAfterMerge: gr.GetDeleteBranchOnMerge(),
+	}
+	// GitHub has no default merge style, it preselects the first allowed one
+	switch {
+	case settings.AllowMerge:
+		settings.DefaultMergeStyle = "merge"
+	case settings.AllowSquash:
+		settings.DefaultMergeStyle = "squash"
+	case settings.AllowRebase:
+		settings.DefaultMergeStyle = "rebase"
+	}
+	return settings
+}
+
 // GetTopics return github topics
 func (g *GithubDownloaderV3) GetTopics() ([]string, error) {
 	g.waitAndPickClient()
@@ -215,6 +244,66 @@ func (g *GithubDownloaderV3) GetTopics() ([]string, error) {
 	return r.Topics, nil
 }
 
+// githubWebhookEvents maps the GitHub webhook events to the Gitea ones, other events have no Gitea counterpart
+var githubWebhookEvents = map[string]string{
+	"*":                   "*",
+	"push":                "push",
+	"create":              "create",
+	"delete":              "delete",
+	"fork":                "fork",
+	"issues":              "issues",
+	"issue_comment":       "issue_comment",
+	"pull_request":        "pull_request",
+	"pull_request_review": "pull_request_review",
+	"release":             "release",
+	"repository":          "repository",
+}
+
+// GetWebhooks returns the webhooks of the repository, they are migrated as Gitea webhooks
+func (g *GithubDownloaderV3) GetWebhooks() ([]*base.Webhook, error) {
+	perPage := g.maxPerPage
+	webhooks := make([]*base.Webhook, 0, perPage)
+	for i := 1; ; i++ {
+		g.waitAndPickClient()
+		hooks, resp, err := g.getClient().Repositories.ListHooks(g.ctx, g.repoOwner, g.repoName,
+			&github.ListOptions{
+				Page:    i,
+				PerPage: perPage,
+			})
+		if err != nil {
+			return nil, err
+		}
+		g.setRate(&resp.Rate)
+
+		for _, hook := range hooks {
+			hookURL, _ := hook.Config["url"].(string)
+			contentType, _ := hook.Config["content_type"].(string)
+			events := make([]string, 0, len(hook.Events))
+			for _, event := range hook.Events {
+				if e, ok := githubWebhookEvents[event]; ok {
+					events = append(events, e)
+				}
+			}
+			if len(events) == 0 {
+				log.Warn("Skipping GitHub webhook %d of %s/%s, none of its events exist in Gitea", hook.GetID(), g.repoOwner, g.repoName)
+				continue
+			}
+			webhooks = append(webhooks, &base.Webhook{
+				ID:          hook.GetID(),
+				URL:         hookURL,
+				Type:        "gitea",
+				ContentType: contentType,
+				Events:      events,
+				Active:      hook.GetActive(),
+			})
+		}
+		if len(hooks) < perPage {
+			break
+		}
+	}
+	return webhooks, nil
+}
+
 // GetMilestones returns milestones
 func (g *GithubDownloaderV3) GetMilestones() ([]*base.Milestone, error) {
 	perPage := g.maxPerPage
@@ -308,8 +397,6 @@ func (g *GithubDownloaderV3) convertGithubRelease(rel *github.RepositoryRelease)
 		r.Published = rel.PublishedAt.Time
 	}
 
-	httpClient := NewMigrationHTTPClient()
-
 	for _, asset := range rel.Assets {
 		assetID := *asset.ID // Don't optimize this, for closure we need a local variable
 		r.Assets = append(r.Assets, &base.ReleaseAsset{
@@ -336,7 +423,7 @@ func (g *GithubDownloaderV3) convertGithubRelease(rel *github.RepositoryRelease)
 						if err != nil {
 							return nil, err
 						}
-						resp, err := httpClient.Do(req)
+						resp, err := g.httpClient.Do(req)
 						err1 := g.RefreshRate()
 						if err1 != nil {
 							log.Error("g.getClient().RateLimits: %s", err1)
@@ -460,8 +547,10 @@ func (g *GithubDownloaderV3) GetIssues(page, perPage int) ([]*base.Issue, bool,
 			Reactions:    reactions,
 			Closed:       issue.ClosedAt,
 			IsLocked:     issue.GetLocked(),
+			LockReason:   issue.GetActiveLockReason(),
 			Assignees:    assignees,
 			ForeignIndex: int64(*issue.Number),
+			OriginalURL:  issue.GetHTMLURL(),
 		})
 	}
 
@@ -536,6 +625,7 @@ func (g *GithubDownloaderV3) getComments(commentable base.Commentable) ([]*base.
 				Created:     comment.GetCreatedAt(),
 				Updated:     comment.GetUpdatedAt(),
 				Reactions:   reactions,
+				OriginalURL: comment.GetHTMLURL(),
 			})
 		}
 		if resp.NextPage == 0 {
@@ -612,6 +702,7 @@ func (g *GithubDownloaderV3) GetAllComments(page, perPage int) ([]*base.Comment,
 			Created:     comment.GetCreatedAt(),
 			Updated:     comment.GetUpdatedAt(),
 			Reactions:   reactions,
+			OriginalURL: comment.GetHTMLURL(),
 		})
 	}
 
@@ -692,6 +783,7 @@ func (g *GithubDownloaderV3) GetPullRequests(page, perPage int) ([]*base.PullReq
 			MergeCommitSHA: pr.GetMergeCommitSHA(),
 			MergedTime:     pr.MergedAt,
 			IsLocked:       pr.ActiveLockReason != nil,
+			LockReason:     pr.GetActiveLockReason(),
 			Head: base.PullRequestBranch{
 				Ref:       pr.GetHead().GetRef(),
 				SHA:       pr.GetHead().GetSHA(),
@@ -708,6 +800,7 @@ func (g *GithubDownloaderV3) GetPullRequests(page, perPage int) ([]*base.PullReq
 			PatchURL:     pr.GetPatchURL(),
 			Reactions:    reactions,
 			ForeignIndex: int64(*pr.Number),
+			OriginalURL:  pr.GetHTMLURL(),
 		})
 	}
 
@@ -819,3 +912,37 @@ func (g *GithubDownloaderV3) GetReviews(reviewable base.Reviewable) ([]*base.Rev
 	}
 	return allReviews, nil
 }
+
+// GetCommitStatuses returns the statuses of a commit
+func (g *GithubDownloaderV3) GetCommitStatuses(sha string) ([]*base.CommitStatus, error) {
+	allStatuses := make([]*base.CommitStatus, 0, g.maxPerPage)
+	opt := &github.ListOptions{
+		PerPage: g.maxPerPage,
+	}
+	for {
+		g.waitAndPickClient()
+		statuses, resp, err := g.getClient().Repositories.ListStatuses(g.ctx, g.repoOwner, g.repoName, sha, opt)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing commit statuses: %v", err)
+		}
+		g.setRate(&resp.Rate)
+		for _, status := range statuses {
+			allStatuses = append(allStatuses, &base.CommitStatus{
+				SHA:         sha,
+				State:       status.GetState(),
+				TargetURL:   status.GetTargetURL(),
+				Description: status.GetDescription(),
+				Context:     status.GetContext(),
+				CreatorID:   status.GetCreator().GetID(),
+				CreatorName: status.GetCreator().GetLogin(),
+				Created:     status.GetCreatedAt(),
+				Updated:     status.GetUpdatedAt(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allStatuses, nil
+}
@@ -67,6 +67,8 @@ type GitlabDownloader struct {
 	repoName   string
 	issueCount int64
 	maxPerPage int
+	// httpClient downloads the release assets
+	httpClient *http.Client
 }
 
 // NewGitlabDownloader creates a gitlab Downloader via gitlab API
@@ -127,6 +129,7 @@ func NewGitlabDownloader(ctx context.Context, baseURL, repoPath, username, passw
 		repoID:     gr.ID,
 		repoName:   gr.Name,
 		maxPerPage: 100,
+		httpClient: NewMigrationHTTPClient(),
 	}, nil
 }
 
@@ -169,9 +172,37 @@ func (g *GitlabDownloader) GetRepoInfo() (*base.Repository, error) {
 		OriginalURL:   gr.WebURL,
 		CloneURL:      gr.HTTPURLToRepo,
 		DefaultBranch: gr.DefaultBranch,
+		IsArchived:    gr.Archived,
+		MergeSettings: convertGitlabMergeSettings(gr),
 	}, nil
 }
 
+// convertGitlabMergeSettings converts the merge method and squash option of a gitlab project
+func convertGitlabMergeSettings(gr *gitlab.Project) *base.MergeSettings {
+	settings := &base.MergeSettings{
+		AllowSquash:            gr.SquashOption != gitlab.SquashOptionNever,
+		DeleteBranchAfterMerge: gr.RemoveSourceBranchAfterMerge,
+	}
+	switch gr.MergeMethod {
+	case gitlab.NoFastForwardMerge:
+		settings.AllowMerge = true
+		settings.DefaultMergeStyle = "merge"
+	case gitlab.RebaseMerge:
+		settings.AllowRebaseMerge = true
+		settings.DefaultMergeStyle = "rebase-merge"
+	case gitlab.FastForwardMerge:
+		settings.AllowRebase = true
+		settings.DefaultMergeStyle = "rebase"
+	default:
+		// older versions of GitLab don't return the merge method
+		return nil
+	}
+	if gr.SquashOption == gitlab.SquashOptionAlways || gr.SquashOption == gitlab.SquashOptionDefaultOn {
+		settings.DefaultMergeStyle = "squash"
+	}
+	return settings
+}
+
 // GetTopics return gitlab topics
 func (g *GitlabDownloader) GetTopics() ([]string, error) {
 	gr, _, err := g.client.Projects.GetProject(g.repoID, nil, nil, gitlab.WithContext(g.ctx))
@@ -181,6 +212,18 @@ func (g *GitlabDownloader) GetTopics() ([]string, error) {
 	return gr.TagList, err
 }
 
+// GetRepoAvatar returns the avatar of the project or nil if it has none
+func (g *GitlabDownloader) GetRepoAvatar() (io.ReadCloser, error) {
+	gr, _, err := g.client.Projects.GetProject(g.repoID, nil, nil, gitlab.WithContext(g.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if gr.AvatarURL == "" {
+		return nil, nil
+	}
+	return downloadRepoAvatar(g.ctx, g.httpClient, gr.AvatarURL)
+}
+
 // GetMilestones returns milestones
 func (g *GitlabDownloader) GetMilestones() ([]*base.Milestone, error) {
 	perPage := g.maxPerPage
@@ -203,13 +246,12 @@ func (g *GitlabDownloader) GetMilestones() ([]*base.Milestone, error) {
 			if m.Description != "" {
 				desc = m.Description
 			}
+			// GitLab milestones are either "active" or "closed"
 			state := "open"
 			var closedAt *time.Time
-			if m.State != "" {
+			if m.State == "closed" {
 				state = m.State
-				if state == "closed" {
-					closedAt = m.UpdatedAt
-				}
+				closedAt = m.UpdatedAt
 			}
 
 			var deadline *time.Time
@@ -253,6 +295,15 @@ func (g *GitlabDownloader) normalizeColor(val string) string {
 	return val
 }
 
+// convertGitlabLabelName converts the name of a GitLab scoped label "scope::name" into the one of a
+// scoped label "scope/name". It returns whether the label is scoped, as scoped labels are exclusive in GitLab.
+func convertGitlabLabelName(name string) (string, bool) {
+	if !strings.Contains(name, "::") {
+		return name, false
+	}
+	return strings.ReplaceAll(name, "::", "/"), true
+}
+
 // GetLabels returns labels
 func (g *GitlabDownloader) GetLabels() ([]*base.Label, error) {
 	perPage := g.maxPerPage
@@ -266,10 +317,12 @@ func (g *GitlabDownloader) GetLabels() ([]*base.Label, error) {
 			return nil, err
 		}
 		for _, label := range ls {
+			name, exclusive := convertGitlabLabelName(label.Name)
 			baseLabel := &base.Label{
-				Name:        label.Name,
+				Name:        name,
 				Color:       g.normalizeColor(label.Color),
 				Description: label.Description,
+				Exclusive:   exclusive,
 			}
 			labels = append(labels, baseLabel)
 		}
@@ -292,9 +345,8 @@ func (g *GitlabDownloader) convertGitlabRelease(rel *gitlab.Release) *base.Relea
 		PublisherName:   rel.Author.Username,
 	}
 
-	httpClient := NewMigrationHTTPClient()
-
 	for k, asset := range rel.Assets.Links {
+		linkID := asset.ID // Don't optimize this, for closure we need a local variable
 		r.Assets = append(r.Assets, &base.ReleaseAsset{
 			ID:            int64(asset.ID),
 			Name:          asset.Name,
@@ -302,7 +354,7 @@ func (g *GitlabDownloader) convertGitlabRelease(rel *gitlab.Release) *base.Relea
 			Size:          &zero,
 			DownloadCount: &zero,
 			DownloadFunc: func() (io.ReadCloser, error) {
-				link, _, err := g.client.ReleaseLinks.GetReleaseLink(g.repoID, rel.TagName, asset.ID, gitlab.WithContext(g.ctx))
+				link, _, err := g.client.ReleaseLinks.GetReleaseLink(g.repoID, rel.TagName, linkID, gitlab.WithContext(g.ctx))
 				if err != nil {
 					return nil, err
 				}
@@ -312,7 +364,7 @@ func (g *GitlabDownloader) convertGitlabRelease(rel *gitlab.Release) *base.Relea
 					return nil, err
 				}
 				req = req.WithContext(g.ctx)
-				resp, err := httpClient.Do(req)
+				resp, err := g.httpClient.Do(req)
 				if err != nil {
 					return nil, err
 				}
@@ -350,6 +402,45 @@ func (g *GitlabDownloader) GetReleases() ([]*base.Release, error) {
 
 type gitlabIssueContext struct {
 	IsMergeRequest bool
+	WebURL         string
+}
+
+// totalItems returns the total count of the listed items GitLab returns with a page,
+// GitLab omits it for more than 10000 items
+func totalItems(resp *gitlab.Response, entity string) (int, error) {
+	if resp == nil || resp.Response == nil || resp.Header.Get("X-Total") == "" {
+		return 0, base.ErrNotSupported{Entity: entity}
+	}
+	return resp.TotalItems, nil
+}
+
+// CountIssues returns the count of the issues
+func (g *GitlabDownloader) CountIssues() (int, error) {
+	state := "all"
+	_, resp, err := g.client.Issues.ListProjectIssues(g.repoID, &gitlab.ListProjectIssuesOptions{
+		State:       &state,
+		ListOptions: gitlab.ListOptions{PerPage: 1, Page: 1},
+	}, nil, gitlab.WithContext(g.ctx))
+	if err != nil {
+		return 0, fmt.Errorf("error while counting issues: %v", err)
+	}
+	return totalItems(resp, "Issues")
+}
+
+// CountPullRequests returns the count of the merge requests
+func (g *GitlabDownloader) CountPullRequests() (int, error) {
+	_, resp, err := g.client.MergeRequests.ListProjectMergeRequests(g.repoID, &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1, Page: 1},
+	}, nil, gitlab.WithContext(g.ctx))
+	if err != nil {
+		return 0, fmt.Errorf("error while counting merge requests: %v", err)
+	}
+	return totalItems(resp, "PullRequests")
+}
+
+// CountComments returns ErrNotSupported, the comments can only be listed issue by issue
+func (g *GitlabDownloader) CountComments() (int, error) {
+	return 0, base.ErrNotSupported{Entity: "Comments"}
 }
 
 // GetIssues returns issues according start and limit
@@ -381,8 +472,10 @@ func (g *GitlabDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, er
 
 		labels := make([]*base.Label, 0, len(issue.Labels))
 		for _, l := range issue.Labels {
+			name, exclusive := convertGitlabLabelName(l)
 			labels = append(labels, &base.Label{
-				Name: l,
+				Name:      name,
+				Exclusive: exclusive,
 			})
 		}
 
@@ -425,7 +518,8 @@ func (g *GitlabDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, er
 			IsLocked:     issue.DiscussionLocked,
 			Updated:      *issue.UpdatedAt,
 			ForeignIndex: int64(issue.IID),
-			Context:      gitlabIssueContext{IsMergeRequest: false},
+			OriginalURL:  issue.WebURL,
+			Context:      gitlabIssueContext{IsMergeRequest: false, WebURL: issue.WebURL},
 		})
 
 		// increment issueCount, to be used in GetPullRequests()
@@ -478,6 +572,7 @@ func (g *GitlabDownloader) GetComments(commentable base.Commentable) ([]*base.Co
 						PosterEmail: note.Author.Email,
 						Content:     note.Body,
 						Created:     *note.CreatedAt,
+						OriginalURL: gitlabNoteURL(context, note.ID),
 					})
 				}
 			} else {
@@ -490,6 +585,7 @@ func (g *GitlabDownloader) GetComments(commentable base.Commentable) ([]*base.Co
 					PosterEmail: c.Author.Email,
 					Content:     c.Body,
 					Created:     *c.CreatedAt,
+					OriginalURL: gitlabNoteURL(context, c.ID),
 				})
 			}
 		}
@@ -501,6 +597,14 @@ func (g *GitlabDownloader) GetComments(commentable base.Commentable) ([]*base.Co
 	return allComments, true, nil
 }
 
+// gitlabNoteURL returns the URL of a note of an issue or merge request
+func gitlabNoteURL(context gitlabIssueContext, noteID int) string {
+	if context.WebURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#note_%d", context.WebURL, noteID)
+}
+
 // GetPullRequests returns pull requests according page and perPage
 func (g *GitlabDownloader) GetPullRequests(page, perPage int) ([]*base.PullRequest, bool, error) {
 	if perPage > g.maxPerPage {
@@ -524,8 +628,10 @@ func (g *GitlabDownloader) GetPullRequests(page, perPage int) ([]*base.PullReque
 
 		labels := make([]*base.Label, 0, len(pr.Labels))
 		for _, l := range pr.Labels {
+			name, exclusive := convertGitlabLabelName(l)
 			labels = append(labels, &base.Label{
-				Name: l,
+				Name:      name,
+				Exclusive: exclusive,
 			})
 		}
 
@@ -608,7 +714,8 @@ func (g *GitlabDownloader) GetPullRequests(page, perPage int) ([]*base.PullReque
 			},
 			PatchURL:     pr.WebURL + ".patch",
 			ForeignIndex: int64(pr.IID),
-			Context:      gitlabIssueContext{IsMergeRequest: true},
+			OriginalURL:  pr.WebURL,
+			Context:      gitlabIssueContext{IsMergeRequest: true, WebURL: pr.WebURL},
 		})
 	}
 
@@ -650,6 +757,34 @@ func (g *GitlabDownloader) GetReviews(reviewable base.Reviewable) ([]*base.Revie
 	return reviews, nil
 }
 
+// GetTrackedTimes returns the time spent on an issue or a merge request.
+// GitLab only exposes the total spent time, so it is returned as a single entry without a user.
+func (g *GitlabDownloader) GetTrackedTimes(commentable base.Commentable) ([]*base.TrackedTime, error) {
+	context, ok := commentable.GetContext().(gitlabIssueContext)
+	if !ok {
+		return nil, fmt.Errorf("unexpected context: %+v", commentable.GetContext())
+	}
+
+	var stats *gitlab.TimeStats
+	var err error
+	if !context.IsMergeRequest {
+		stats, _, err = g.client.Issues.GetTimeSpent(g.repoID, int(commentable.GetForeignIndex()), gitlab.WithContext(g.ctx))
+	} else {
+		stats, _, err = g.client.MergeRequests.GetTimeSpent(g.repoID, int(commentable.GetForeignIndex()), gitlab.WithContext(g.ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the time spent on issue #%d: %v", commentable.GetForeignIndex(), err)
+	}
+	if stats.TotalTimeSpent <= 0 {
+		return nil, nil
+	}
+
+	return []*base.TrackedTime{{
+		IssueIndex: commentable.GetLocalIndex(),
+		Time:       int64(stats.TotalTimeSpent),
+	}}, nil
+}
+
 func (g *GitlabDownloader) awardToReaction(award *gitlab.AwardEmoji) *base.Reaction {
 	return &base.Reaction{
 		UserID:   int64(award.User.ID),
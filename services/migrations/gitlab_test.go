@@ -60,7 +60,7 @@ func TestGitlabDownloadRepo(t *testing.T) {
 			Title:   "1.1.0",
 			Created: time.Date(2019, 11, 28, 8, 42, 44, 575000000, time.UTC),
 			Updated: timePtr(time.Date(2019, 11, 28, 8, 42, 44, 575000000, time.UTC)),
-			State:   "active",
+			State:   "open",
 		},
 		{
 			Title:   "1.0.0",
@@ -470,3 +470,52 @@ func TestGitlabGetReviews(t *testing.T) {
 		assertReviewsEqual(t, []*base.Review{&review}, rvs)
 	}
 }
+
+func TestConvertGitlabLabelName(t *testing.T) {
+	for _, testCase := range []struct {
+		name      string
+		expected  string
+		exclusive bool
+	}{
+		{name: "bug", expected: "bug"},
+		{name: "kind/bug", expected: "kind/bug"},
+		{name: "priority::high", expected: "priority/high", exclusive: true},
+		{name: "team::backend::api", expected: "team/backend/api", exclusive: true},
+	} {
+		name, exclusive := convertGitlabLabelName(testCase.name)
+		assert.Equal(t, testCase.expected, name)
+		assert.Equal(t, testCase.exclusive, exclusive, testCase.name)
+	}
+}
+
+func TestConvertGitlabMergeSettings(t *testing.T) {
+	assert.Nil(t, convertGitlabMergeSettings(&gitlab.Project{}))
+
+	assert.Equal(t, &base.MergeSettings{
+		AllowRebase:            true,
+		DefaultMergeStyle:      "rebase",
+		DeleteBranchAfterMerge: true,
+	}, convertGitlabMergeSettings(&gitlab.Project{
+		MergeMethod:                  gitlab.FastForwardMerge,
+		SquashOption:                 gitlab.SquashOptionNever,
+		RemoveSourceBranchAfterMerge: true,
+	}))
+
+	assert.Equal(t, &base.MergeSettings{
+		AllowRebaseMerge:  true,
+		AllowSquash:       true,
+		DefaultMergeStyle: "squash",
+	}, convertGitlabMergeSettings(&gitlab.Project{
+		MergeMethod:  gitlab.RebaseMerge,
+		SquashOption: gitlab.SquashOptionDefaultOn,
+	}))
+
+	assert.Equal(t, &base.MergeSettings{
+		AllowMerge:        true,
+		AllowSquash:       true,
+		DefaultMergeStyle: "merge",
+	}, convertGitlabMergeSettings(&gitlab.Project{
+		MergeMethod:  gitlab.NoFastForwardMerge,
+		SquashOption: gitlab.SquashOptionDefaultOff,
+	}))
+}
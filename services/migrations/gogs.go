@@ -99,7 +99,7 @@ func NewGogsDownloader(ctx context.Context, baseURL, userName, password, token,
 			req.SetBasicAuth(userName, password)
 			return proxy.Proxy()(req)
 		}
-		downloader.transport = transport
+		downloader.transport = newRateLimitedTransport(transport)
 
 		client = gogs.NewClient(baseURL, "")
 		client.SetHTTPClient(&http.Client{
@@ -216,7 +216,9 @@ func (g *GogsDownloader) getIssues(page int, state string) ([]*base.Issue, bool,
 		if issue.PullRequest != nil {
 			continue
 		}
-		allIssues = append(allIssues, convertGogsIssue(issue))
+		baseIssue := convertGogsIssue(issue)
+		baseIssue.OriginalURL = fmt.Sprintf("%s/%s/%s/issues/%d", g.baseURL, g.repoOwner, g.repoName, issue.Index)
+		allIssues = append(allIssues, baseIssue)
 	}
 
 	return allIssues, len(issues) == 0, nil
@@ -243,6 +245,7 @@ func (g *GogsDownloader) GetComments(commentable base.Commentable) ([]*base.Comm
 			Content:     comment.Body,
 			Created:     comment.Created,
 			Updated:     comment.Updated,
+			OriginalURL: comment.HTMLURL,
 		})
 	}
 
@@ -5,18 +5,25 @@
 package migrations
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"sync"
 
 	"code.gitea.io/gitea/modules/hostmatcher"
 	"code.gitea.io/gitea/modules/proxy"
 	"code.gitea.io/gitea/modules/setting"
+
+	"golang.org/x/time/rate"
 )
 
 // NewMigrationHTTPClient returns a HTTP client for migration
 func NewMigrationHTTPClient() *http.Client {
 	return &http.Client{
-		Transport: NewMigrationHTTPTransport(),
+		Transport: newRateLimitedTransport(NewMigrationHTTPTransport()),
 	}
 }
 
@@ -25,6 +32,75 @@ func NewMigrationHTTPTransport() *http.Transport {
 	return &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: setting.Migrations.SkipTLSVerify},
 		Proxy:           proxy.Proxy(),
-		DialContext:     hostmatcher.NewDialContext("migration", allowList, blockList),
+		DialContext:     NewMigrationDialContext(),
+	}
+}
+
+// NewMigrationDialContext returns a DialContext which only connects to the hosts allowed by the migration settings
+func NewMigrationDialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return hostmatcher.NewDialContext("migration", allowList, blockList)
+}
+
+var requestLimiter struct {
+	sync.Mutex
+	limiter           *rate.Limiter
+	requestsPerSecond float64
+}
+
+// migrationRequestLimiter returns the limiter shared by the API clients of all migrations,
+// or nil if setting.Migrations.RequestsPerSecond doesn't cap the requests
+func migrationRequestLimiter() *rate.Limiter {
+	requestLimiter.Lock()
+	defer requestLimiter.Unlock()
+	if setting.Migrations.RequestsPerSecond <= 0 {
+		return nil
+	}
+	if requestLimiter.limiter == nil || requestLimiter.requestsPerSecond != setting.Migrations.RequestsPerSecond {
+		// a burst of one request spaces out all requests evenly
+		requestLimiter.limiter = rate.NewLimiter(rate.Limit(setting.Migrations.RequestsPerSecond), 1)
+		requestLimiter.requestsPerSecond = setting.Migrations.RequestsPerSecond
+	}
+	return requestLimiter.limiter
+}
+
+// rateLimitedTransport waits for a token of the shared limiter before each request
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newRateLimitedTransport wraps the transport of an API client so that the requests of all
+// migrations together stay below setting.Migrations.RequestsPerSecond
+func newRateLimitedTransport(base http.RoundTripper) http.RoundTripper {
+	limiter := migrationRequestLimiter()
+	if limiter == nil {
+		return base
+	}
+	return &rateLimitedTransport{limiter: limiter, base: base}
+}
+
+// downloadRepoAvatar downloads the avatar image of a repository from the source platform
+func downloadRepoAvatar(ctx context.Context, client *http.Client, avatarURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", avatarURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d of avatar %s", resp.StatusCode, avatarURL)
 	}
+	// resp.Body is closed by the caller
+	return resp.Body, nil
 }
@@ -0,0 +1,50 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedTransport(t *testing.T) {
+	defer func(requestsPerSecond float64) {
+		setting.Migrations.RequestsPerSecond = requestsPerSecond
+	}(setting.Migrations.RequestsPerSecond)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	setting.Migrations.RequestsPerSecond = 0
+	_, ok := NewMigrationHTTPClient().Transport.(*http.Transport)
+	assert.True(t, ok, "requests must not be limited without a cap")
+
+	setting.Migrations.RequestsPerSecond = 20
+	_, ok = NewMigrationHTTPClient().Transport.(*rateLimitedTransport)
+	assert.True(t, ok)
+
+	// the clients of different migrations share the same limiter
+	clients := []*http.Client{
+		{Transport: newRateLimitedTransport(http.DefaultTransport)},
+		{Transport: newRateLimitedTransport(http.DefaultTransport)},
+	}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		resp, err := clients[i%2].Get(server.URL)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+	// the first request is sent immediately, each further one waits for 1/20 s
+	assert.GreaterOrEqual(t, time.Since(start), 5*50*time.Millisecond-10*time.Millisecond)
+}
@@ -256,6 +256,9 @@ func assertReviewCommentEqual(t *testing.T, expected, actual *base.ReviewComment
 	assertReactionsEqual(t, expected.Reactions, actual.Reactions)
 	assertTimeEqual(t, expected.CreatedAt, actual.CreatedAt)
 	assertTimeEqual(t, expected.UpdatedAt, actual.UpdatedAt)
+	assert.Equal(t, expected.Resolved, actual.Resolved)
+	assert.Equal(t, expected.ResolverID, actual.ResolverID)
+	assert.Equal(t, expected.ResolverName, actual.ResolverName)
 }
 
 func assertReviewCommentsEqual(t *testing.T, expected, actual []*base.ReviewComment) {
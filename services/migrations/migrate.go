@@ -7,7 +7,9 @@ package migrations
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"path/filepath"
@@ -21,6 +23,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	base "code.gitea.io/gitea/modules/migration"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/typesniffer"
 	"code.gitea.io/gitea/modules/util"
 )
 
@@ -75,12 +78,48 @@ func IsMigrateURLAllowed(remoteURL string, doer *user_model.User) error {
 		return &models.ErrInvalidCloneAddr{Host: u.Host, IsProtocolInvalid: true, IsPermissionDenied: true, IsURLError: true}
 	}
 
-	hostName, _, err := net.SplitHostPort(u.Host)
+	return isMigrateHostAllowed(u)
+}
+
+// innerDownloader returns the downloader wrapped by a RetryDownloader to check the interfaces it implements
+func innerDownloader(downloader base.Downloader) base.Downloader {
+	if retryDownloader, ok := downloader.(*base.RetryDownloader); ok {
+		return retryDownloader.Downloader
+	}
+	return downloader
+}
+
+// localCloneURLDownloader is implemented by the downloaders whose clone URL may be a local path: the plain git
+// downloader, whose address was checked by IsMigrateURLAllowed, and the repository restorer
+type localCloneURLDownloader interface {
+	allowsLocalCloneURL()
+}
+
+// checkCloneURLAllowed checks the clone URL provided by a downloader, which may differ from the address checked
+// by IsMigrateURLAllowed. The clone URLs of the other downloaders come from the API of the source, so they must
+// not be local paths, which would clone repositories of the server.
+func checkCloneURLAllowed(downloader base.Downloader, cloneURL string) error {
+	if cloneURL == "" {
+		// there is no git data to clone
+		return nil
+	}
+	u, err := url.Parse(cloneURL)
 	if err != nil {
-		// u.Host can be "host" or "host:port"
-		err = nil //nolint
-		hostName = u.Host
+		return &models.ErrInvalidCloneAddr{IsURLError: true}
 	}
+	if u.Scheme == "file" || u.Scheme == "" {
+		if _, ok := innerDownloader(downloader).(localCloneURLDownloader); !ok {
+			return &models.ErrInvalidCloneAddr{Host: "<LOCAL_FILESYSTEM>", IsPermissionDenied: true, LocalPath: true}
+		}
+		return nil
+	}
+	return isMigrateHostAllowed(u)
+}
+
+// isMigrateHostAllowed checks the host of a remote URL against the allow and block lists of migrations
+func isMigrateHostAllowed(u *url.URL) error {
+	// u.Host can be "host", "host:port", "[ipv6]" or "[ipv6]:port"
+	hostName := u.Hostname()
 	addrList, err := net.LookupIP(hostName)
 	if err != nil {
 		return &models.ErrInvalidCloneAddr{Host: u.Host, NotResolvedIP: true}
@@ -108,17 +147,22 @@ func IsMigrateURLAllowed(remoteURL string, doer *user_model.User) error {
 
 // MigrateRepository migrate repository according MigrateOptions
 func MigrateRepository(ctx context.Context, doer *user_model.User, ownerName string, opts base.MigrateOptions, messenger base.Messenger) (*repo_model.Repository, error) {
-	err := IsMigrateURLAllowed(opts.CloneAddr, doer)
-	if err != nil {
+	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
+	// an archive import may still record the address it was exported from
+	if len(opts.ArchivePath) == 0 || len(opts.CloneAddr) > 0 {
+		if err := IsMigrateURLAllowed(opts.CloneAddr, doer); err != nil {
+			return nil, err
+		}
+	}
 	if opts.LFS && len(opts.LFSEndpoint) > 0 {
 		err := IsMigrateURLAllowed(opts.LFSEndpoint, doer)
 		if err != nil {
 			return nil, err
 		}
 	}
-	downloader, err := newDownloader(ctx, ownerName, opts)
+	downloader, err := newDownloader(ctx, ownerName, &opts)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +182,9 @@ func MigrateRepository(ctx context.Context, doer *user_model.User, ownerName str
 	return uploader.repo, nil
 }
 
-func newDownloader(ctx context.Context, ownerName string, opts base.MigrateOptions) (base.Downloader, error) {
+// newDownloader creates the downloader of the git service of opts, falling back to cloning a plain git repository.
+// The wiki of a plain git repository is always migrated, the migration form of plain git has no option for it.
+func newDownloader(ctx context.Context, ownerName string, opts *base.MigrateOptions) (base.Downloader, error) {
 	var (
 		downloader base.Downloader
 		err        error
@@ -146,7 +192,7 @@ func newDownloader(ctx context.Context, ownerName string, opts base.MigrateOptio
 
 	for _, factory := range factories {
 		if factory.GitServiceType() == opts.GitServiceType {
-			downloader, err = factory.New(ctx, opts)
+			downloader, err = factory.New(ctx, *opts)
 			if err != nil {
 				return nil, err
 			}
@@ -155,13 +201,7 @@ func newDownloader(ctx context.Context, ownerName string, opts base.MigrateOptio
 	}
 
 	if downloader == nil {
-		opts.Wiki = true
-		opts.Milestones = false
-		opts.Labels = false
-		opts.Releases = false
-		opts.Comments = false
-		opts.Issues = false
-		opts.PullRequests = false
+		opts.SetMigrateUnits(opts.MigrateUnits() | base.MigrateUnitWiki)
 		downloader = NewPlainGitDownloader(ownerName, opts.RepoName, opts.CloneAddr)
 		log.Trace("Will migrate from git: %s", opts.OriginalURL)
 	}
@@ -172,6 +212,49 @@ func newDownloader(ctx context.Context, ownerName string, opts base.MigrateOptio
 	return downloader, nil
 }
 
+// readRepoAvatar reads the avatar of a migrated repository and checks that it is an image
+// which can be used as avatar
+func readRepoAvatar(avatar io.ReadCloser) ([]byte, error) {
+	defer avatar.Close()
+
+	data, err := io.ReadAll(io.LimitReader(avatar, setting.Avatar.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > setting.Avatar.MaxFileSize {
+		return nil, fmt.Errorf("avatar exceeds the maximum size of %d bytes", setting.Avatar.MaxFileSize)
+	}
+	if st := typesniffer.DetectContentType(data); !st.IsImage() || st.IsSvgImage() {
+		return nil, errors.New("avatar is not a supported image")
+	}
+	return data, nil
+}
+
+// discussionToIssue converts a discussion to the issue it is migrated as. The issue is labeled with
+// "Discussion/<category>" to tell it apart from the issues of the source.
+func discussionToIssue(discussion *base.Discussion) *base.Issue {
+	labelName := "Discussion"
+	if discussion.Category != "" {
+		labelName += "/" + discussion.Category
+	}
+	return &base.Issue{
+		Number:       discussion.Number,
+		PosterID:     discussion.PosterID,
+		PosterName:   discussion.PosterName,
+		PosterEmail:  discussion.PosterEmail,
+		Title:        discussion.Title,
+		Content:      discussion.Content,
+		State:        discussion.State,
+		IsLocked:     discussion.IsLocked,
+		Created:      discussion.Created,
+		Updated:      discussion.Updated,
+		Closed:       discussion.Closed,
+		Labels:       []*base.Label{{Name: labelName, Color: "cfd3d7", Description: "Migrated discussion"}},
+		ForeignIndex: discussion.Number,
+		OriginalURL:  discussion.OriginalURL,
+	}
+}
+
 // migrateRepository will download information and then upload it to Uploader, this is a simple
 // process for small repository. For a big repository, save all the data to disk
 // before upload is better
@@ -180,6 +263,10 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		messenger = base.NilMessenger
 	}
 
+	// only the selected units the downloader supports are migrated
+	units := opts.MigrateUnits() & downloader.SupportedUnits()
+	opts.SetMigrateUnits(units)
+
 	repo, err := downloader.GetRepoInfo()
 	if err != nil {
 		if !base.IsErrNotSupported(err) {
@@ -195,6 +282,9 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 	if repo.CloneURL, err = downloader.FormatCloneURL(opts, repo.CloneURL); err != nil {
 		return err
 	}
+	if err = checkCloneURLAllowed(downloader, repo.CloneURL); err != nil {
+		return err
+	}
 
 	log.Trace("migrating git data from %s", repo.CloneURL)
 	messenger("repo.migrate.migrating_git")
@@ -218,7 +308,24 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		}
 	}
 
-	if opts.Milestones {
+	log.Trace("migrating avatar")
+	avatar, err := downloader.GetRepoAvatar()
+	if err != nil {
+		if !base.IsErrNotSupported(err) {
+			return err
+		}
+		log.Warn("migrating avatar is not supported, ignored")
+	}
+	if avatar != nil {
+		data, err := readRepoAvatar(avatar)
+		if err != nil {
+			log.Warn("Ignoring the avatar of migrated repository %s: %v", opts.RepoName, err)
+		} else if err = uploader.CreateRepoAvatar(data); err != nil {
+			return err
+		}
+	}
+
+	if units.Has(base.MigrateUnitMilestones) {
 		log.Trace("migrating milestones")
 		messenger("repo.migrate.migrating_milestones")
 		milestones, err := downloader.GetMilestones()
@@ -242,7 +349,7 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		}
 	}
 
-	if opts.Labels {
+	if units.Has(base.MigrateUnitLabels) {
 		log.Trace("migrating labels")
 		messenger("repo.migrate.migrating_labels")
 		labels, err := downloader.GetLabels()
@@ -266,7 +373,9 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		}
 	}
 
-	if opts.Releases {
+	if opts.SkipReleaseSync {
+		log.Trace("skipping releases and tags")
+	} else if units.Has(base.MigrateUnitReleases) {
 		log.Trace("migrating releases")
 		messenger("repo.migrate.migrating_releases")
 		releases, err := downloader.GetReleases()
@@ -302,7 +411,75 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 
 	supportAllComments := downloader.SupportGetRepoComments()
 
-	if opts.Issues {
+	supportCommentHistory := units.Has(base.MigrateUnitCommentHistory)
+	getCommentHistory := func(comments []*base.Comment) error {
+		for _, comment := range comments {
+			if !supportCommentHistory {
+				return nil
+			}
+			history, err := downloader.GetCommentHistory(comment)
+			if err != nil {
+				if !base.IsErrNotSupported(err) {
+					return err
+				}
+				log.Warn("migrating comment history is not supported, ignored")
+				supportCommentHistory = false
+				return nil
+			}
+			comment.History = history
+		}
+		return nil
+	}
+
+	supportTrackedTimes := units.Has(base.MigrateUnitTrackedTimes)
+	migrateTrackedTimes := func(commentables []base.Commentable) error {
+		if !supportTrackedTimes {
+			return nil
+		}
+		allTimes := make([]*base.TrackedTime, 0, len(commentables))
+		for _, commentable := range commentables {
+			times, err := downloader.GetTrackedTimes(commentable)
+			if err != nil {
+				if !base.IsErrNotSupported(err) {
+					return err
+				}
+				log.Warn("migrating tracked times is not supported, ignored")
+				supportTrackedTimes = false
+				return nil
+			}
+			allTimes = append(allTimes, times...)
+		}
+		if len(allTimes) == 0 {
+			return nil
+		}
+		return uploader.CreateTrackedTimes(allTimes...)
+	}
+
+	supportIssueSubscribers := units.Has(base.MigrateUnitIssueSubscribers)
+	migrateIssueSubscribers := func(commentables []base.Commentable) error {
+		if !supportIssueSubscribers {
+			return nil
+		}
+		allSubscribers := make([]*base.IssueSubscriber, 0, len(commentables))
+		for _, commentable := range commentables {
+			subscribers, err := downloader.GetIssueSubscribers(commentable)
+			if err != nil {
+				if !base.IsErrNotSupported(err) {
+					return err
+				}
+				log.Warn("migrating issue subscribers is not supported, ignored")
+				supportIssueSubscribers = false
+				return nil
+			}
+			allSubscribers = append(allSubscribers, subscribers...)
+		}
+		if len(allSubscribers) == 0 {
+			return nil
+		}
+		return uploader.CreateIssueSubscribers(allSubscribers...)
+	}
+
+	if units.Has(base.MigrateUnitIssues) {
 		log.Trace("migrating issues and comments")
 		messenger("repo.migrate.migrating_issues")
 		issueBatchSize := uploader.MaxBatchInsertSize("issue")
@@ -321,7 +498,18 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				return err
 			}
 
-			if opts.Comments && !supportAllComments {
+			commentables := make([]base.Commentable, 0, len(issues))
+			for _, issue := range issues {
+				commentables = append(commentables, issue)
+			}
+			if err := migrateTrackedTimes(commentables); err != nil {
+				return err
+			}
+			if err := migrateIssueSubscribers(commentables); err != nil {
+				return err
+			}
+
+			if units.Has(base.MigrateUnitComments) && !supportAllComments {
 				allComments := make([]*base.Comment, 0, commentBatchSize)
 				for _, issue := range issues {
 					log.Trace("migrating issue %d's comments", issue.Number)
@@ -332,6 +520,9 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 						}
 						log.Warn("migrating comments is not supported, ignored")
 					}
+					if err := getCommentHistory(comments); err != nil {
+						return err
+					}
 
 					allComments = append(allComments, comments...)
 
@@ -357,10 +548,70 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		}
 	}
 
-	if opts.PullRequests {
+	if units.Has(base.MigrateUnitDiscussions) {
+		log.Trace("migrating discussions")
+		messenger("repo.migrate.migrating_discussions")
+		discussionBatchSize := uploader.MaxBatchInsertSize("issue")
+		createdLabels := make(map[string]bool)
+
+		for i := 1; ; i++ {
+			discussions, isEnd, err := downloader.GetDiscussions(i, discussionBatchSize)
+			if err != nil {
+				if !base.IsErrNotSupported(err) {
+					return err
+				}
+				log.Warn("migrating discussions is not supported, ignored")
+				break
+			}
+
+			issues := make([]*base.Issue, 0, len(discussions))
+			var labels []*base.Label
+			var replies []*base.Comment
+			for _, discussion := range discussions {
+				issue := discussionToIssue(discussion)
+				if label := issue.Labels[0]; !createdLabels[label.Name] {
+					createdLabels[label.Name] = true
+					labels = append(labels, label)
+				}
+				issues = append(issues, issue)
+				if units.Has(base.MigrateUnitComments) {
+					replies = append(replies, discussion.Replies...)
+				}
+			}
+
+			if len(labels) > 0 {
+				if err := uploader.CreateLabels(labels...); err != nil {
+					return err
+				}
+			}
+			if len(issues) > 0 {
+				if err := uploader.CreateIssues(issues...); err != nil {
+					return err
+				}
+			}
+			for len(replies) > 0 {
+				batchSize := commentBatchSize
+				if len(replies) < batchSize {
+					batchSize = len(replies)
+				}
+				if err := uploader.CreateComments(replies[:batchSize]...); err != nil {
+					return err
+				}
+				replies = replies[batchSize:]
+			}
+
+			if isEnd {
+				break
+			}
+		}
+	}
+
+	if units.Has(base.MigrateUnitPullRequests) {
 		log.Trace("migrating pull requests and comments")
 		messenger("repo.migrate.migrating_pulls")
 		prBatchSize := uploader.MaxBatchInsertSize("pullrequest")
+		supportCommitStatuses := true
+		statusSHAs := make(map[string]struct{})
 		for i := 1; ; i++ {
 			prs, isEnd, err := downloader.GetPullRequests(i, prBatchSize)
 			if err != nil {
@@ -375,7 +626,18 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				return err
 			}
 
-			if opts.Comments {
+			commentables := make([]base.Commentable, 0, len(prs))
+			for _, pr := range prs {
+				commentables = append(commentables, pr)
+			}
+			if err := migrateTrackedTimes(commentables); err != nil {
+				return err
+			}
+			if err := migrateIssueSubscribers(commentables); err != nil {
+				return err
+			}
+
+			if units.Has(base.MigrateUnitComments) {
 				if !supportAllComments {
 					// plain comments
 					allComments := make([]*base.Comment, 0, commentBatchSize)
@@ -388,6 +650,9 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 							}
 							log.Warn("migrating comments is not supported, ignored")
 						}
+						if err := getCommentHistory(comments); err != nil {
+							return err
+						}
 
 						allComments = append(allComments, comments...)
 
@@ -433,19 +698,48 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				}
 			}
 
+			// migrate the commit statuses of the pull request heads
+			if supportCommitStatuses {
+				allStatuses := make([]*base.CommitStatus, 0, len(prs))
+				for _, pr := range prs {
+					if _, ok := statusSHAs[pr.Head.SHA]; ok || pr.Head.SHA == "" {
+						continue
+					}
+					statusSHAs[pr.Head.SHA] = struct{}{}
+					statuses, err := downloader.GetCommitStatuses(pr.Head.SHA)
+					if err != nil {
+						if !base.IsErrNotSupported(err) {
+							return err
+						}
+						log.Warn("migrating commit statuses is not supported, ignored")
+						supportCommitStatuses = false
+						break
+					}
+					allStatuses = append(allStatuses, statuses...)
+				}
+				if len(allStatuses) > 0 {
+					if err = uploader.CreateCommitStatuses(allStatuses...); err != nil {
+						return err
+					}
+				}
+			}
+
 			if isEnd {
 				break
 			}
 		}
 	}
 
-	if opts.Comments && supportAllComments {
+	if units.Has(base.MigrateUnitComments) && supportAllComments {
 		log.Trace("migrating comments")
 		for i := 1; ; i++ {
 			comments, isEnd, err := downloader.GetAllComments(i, commentBatchSize)
 			if err != nil {
 				return err
 			}
+			if err := getCommentHistory(comments); err != nil {
+				return err
+			}
 
 			if err := uploader.CreateComments(comments...); err != nil {
 				return err
@@ -457,15 +751,41 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 		}
 	}
 
+	if units.Has(base.MigrateUnitWebhooks) {
+		log.Trace("migrating webhooks")
+		messenger("repo.migrate.migrating_webhooks")
+		webhooks, err := downloader.GetWebhooks()
+		if err != nil {
+			if !base.IsErrNotSupported(err) {
+				return err
+			}
+			log.Warn("migrating webhooks is not supported, ignored")
+		}
+		if len(webhooks) > 0 {
+			if err := uploader.CreateWebhooks(webhooks...); err != nil {
+				return err
+			}
+		}
+	}
+
 	return uploader.Finish()
 }
 
 // Init migrations service
 func Init() error {
-	// TODO: maybe we can deprecate these legacy ALLOWED_DOMAINS/ALLOW_LOCALNETWORKS/BLOCKED_DOMAINS, use ALLOWED_HOST_LIST/BLOCKED_HOST_LIST instead
+	// ALLOWED_HOST_LIST/BLOCKED_HOST_LIST support CIDRs and builtin networks and replace the legacy
+	// ALLOWED_DOMAINS/ALLOW_LOCALNETWORKS/BLOCKED_DOMAINS when they are set
 
-	blockList = hostmatcher.ParseSimpleMatchList("migrations.BLOCKED_DOMAINS", setting.Migrations.BlockedDomains)
+	if setting.Migrations.BlockedHostList != "" {
+		blockList = hostmatcher.ParseHostMatchList("migrations.BLOCKED_HOST_LIST", setting.Migrations.BlockedHostList)
+	} else {
+		blockList = hostmatcher.ParseSimpleMatchList("migrations.BLOCKED_DOMAINS", setting.Migrations.BlockedDomains)
+	}
 
+	if setting.Migrations.AllowedHostList != "" {
+		allowList = hostmatcher.ParseHostMatchList("migrations.ALLOWED_HOST_LIST", setting.Migrations.AllowedHostList)
+		return nil
+	}
 	allowList = hostmatcher.ParseSimpleMatchList("migrations.ALLOWED_DOMAINS/ALLOW_LOCALNETWORKS", setting.Migrations.AllowedDomains)
 	if allowList.IsEmpty() {
 		// the default policy is that migration module can access external hosts
@@ -5,12 +5,17 @@
 package migrations
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
+	"code.gitea.io/gitea/models"
+	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	base "code.gitea.io/gitea/modules/migration"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -74,3 +79,270 @@ func TestMigrateWhiteBlocklist(t *testing.T) {
 
 	setting.ImportLocalPaths = old
 }
+
+func TestMigrateIPv6Blocklist(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	nonAdminUser := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "user2"}).(*user_model.User)
+
+	defer func(allowed, blocked string, allowLocal bool) {
+		setting.Migrations.AllowedDomains = allowed
+		setting.Migrations.BlockedDomains = blocked
+		setting.Migrations.AllowLocalNetworks = allowLocal
+		assert.NoError(t, Init())
+	}(setting.Migrations.AllowedDomains, setting.Migrations.BlockedDomains, setting.Migrations.AllowLocalNetworks)
+
+	setting.Migrations.AllowedDomains = ""
+	setting.Migrations.BlockedDomains = ""
+	setting.Migrations.AllowLocalNetworks = false
+	assert.NoError(t, Init())
+
+	// IPv6 literals are checked without their brackets and port
+	assert.NoError(t, IsMigrateURLAllowed("https://[2001:db8::1]:443/org/repo.git", nonAdminUser))
+	assert.NoError(t, IsMigrateURLAllowed("https://[2001:db8::1]/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("https://[::1]/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("https://[fd00::1]:3000/org/repo.git", nonAdminUser))
+
+	setting.Migrations.BlockedDomains = "2001:db8::1"
+	assert.NoError(t, Init())
+	assert.Error(t, IsMigrateURLAllowed("https://[2001:db8::1]:443/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("https://[2001:db8::1]/org/repo.git", nonAdminUser))
+	assert.NoError(t, IsMigrateURLAllowed("https://[2001:db8::2]/org/repo.git", nonAdminUser))
+
+	setting.Migrations.BlockedDomains = ""
+	setting.Migrations.AllowedDomains = "2001:db8::1"
+	assert.NoError(t, Init())
+	assert.NoError(t, IsMigrateURLAllowed("https://[2001:db8::1]/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("https://[2001:db8::2]:443/org/repo.git", nonAdminUser))
+
+	setting.Migrations.AllowedDomains = ""
+	setting.Migrations.AllowLocalNetworks = true
+	assert.NoError(t, Init())
+	assert.NoError(t, IsMigrateURLAllowed("https://[::1]/org/repo.git", nonAdminUser))
+}
+
+func TestMigrateHostList(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	nonAdminUser := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "user2"}).(*user_model.User)
+
+	defer func(allowedDomains, allowedHosts, blockedHosts string, allowLocal bool) {
+		setting.Migrations.AllowedDomains = allowedDomains
+		setting.Migrations.AllowedHostList = allowedHosts
+		setting.Migrations.BlockedHostList = blockedHosts
+		setting.Migrations.AllowLocalNetworks = allowLocal
+		assert.NoError(t, Init())
+	}(setting.Migrations.AllowedDomains, setting.Migrations.AllowedHostList, setting.Migrations.BlockedHostList, setting.Migrations.AllowLocalNetworks)
+
+	setting.Migrations.AllowedDomains = ""
+	setting.Migrations.AllowedHostList = ""
+	setting.Migrations.BlockedHostList = ""
+	setting.Migrations.AllowLocalNetworks = false
+	assert.NoError(t, Init())
+
+	// internal addresses are rejected by default
+	assert.Error(t, IsMigrateURLAllowed("http://169.254.169.254/", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("http://10.0.0.1/org/repo.git", nonAdminUser))
+	assert.NoError(t, IsMigrateURLAllowed("https://198.51.100.1/org/repo.git", nonAdminUser))
+
+	setting.Migrations.AllowedHostList = "external,10.0.0.0/8"
+	assert.NoError(t, Init())
+	assert.NoError(t, IsMigrateURLAllowed("http://10.0.0.1/org/repo.git", nonAdminUser))
+	assert.NoError(t, IsMigrateURLAllowed("https://198.51.100.1/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("http://169.254.169.254/", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("http://192.168.0.1/org/repo.git", nonAdminUser))
+
+	// the host lists replace the legacy settings
+	setting.Migrations.AllowLocalNetworks = true
+	setting.Migrations.AllowedHostList = "10.0.0.1"
+	assert.NoError(t, Init())
+	assert.NoError(t, IsMigrateURLAllowed("http://10.0.0.1/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("http://10.0.0.2/org/repo.git", nonAdminUser))
+	assert.Error(t, IsMigrateURLAllowed("https://198.51.100.1/org/repo.git", nonAdminUser))
+
+	setting.Migrations.AllowLocalNetworks = false
+	setting.Migrations.AllowedHostList = ""
+	setting.Migrations.BlockedHostList = "198.51.100.0/24"
+	assert.NoError(t, Init())
+	assert.Error(t, IsMigrateURLAllowed("https://198.51.100.1/org/repo.git", nonAdminUser))
+	assert.NoError(t, IsMigrateURLAllowed("https://203.0.113.1/org/repo.git", nonAdminUser))
+}
+
+func TestMigrateCloneURLHostList(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+	assert.NoError(t, Init())
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+
+	// the clone URL provided by the source must not point to an internal address
+	repoName := "clone-url-metadata"
+	downloader := &mockDownloader{
+		repo: &base.Repository{Name: repoName, CloneURL: "http://169.254.169.254/latest/meta-data", OriginalURL: "https://example.com/remote/" + repoName},
+	}
+	uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, repoName)
+	err := migrateRepository(downloader, uploader, base.MigrateOptions{
+		RepoName:  repoName,
+		CloneAddr: "https://example.com/remote/" + repoName,
+	}, nil)
+	assert.True(t, models.IsErrInvalidCloneAddr(err))
+	unittest.AssertNotExistsBean(t, &repo_model.Repository{OwnerID: doer.ID, Name: repoName})
+}
+
+// localPathDownloader is a downloader of a platform whose API returns the clone URL of the repository
+type localPathDownloader struct {
+	base.NullDownloader
+	cloneURL string
+}
+
+func (d *localPathDownloader) GetRepoInfo() (*base.Repository, error) {
+	return &base.Repository{Name: "local-path", CloneURL: d.cloneURL, OriginalURL: "https://example.com/remote/local-path"}, nil
+}
+
+func TestMigrateLocalCloneURL(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+	assert.NoError(t, Init())
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	private := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 2}).(*repo_model.Repository)
+
+	// the API of the source must not make the migration clone a repository of the server
+	for _, cloneURL := range []string{private.RepoPath(), "file://" + private.RepoPath()} {
+		uploader := NewGiteaLocalUploader(context.Background(), doer, doer.Name, "local-path")
+		err := migrateRepository(&localPathDownloader{cloneURL: cloneURL}, uploader, base.MigrateOptions{
+			RepoName:  "local-path",
+			CloneAddr: "https://example.com/remote/local-path",
+		}, nil)
+		assert.True(t, models.IsErrInvalidCloneAddr(err), cloneURL)
+		unittest.AssertNotExistsBean(t, &repo_model.Repository{OwnerID: doer.ID, Name: "local-path"})
+	}
+
+	// the address of the plain git downloader was checked by IsMigrateURLAllowed, also if downloads are retried
+	plainGit := NewPlainGitDownloader(doer.Name, "local-path", private.RepoPath())
+	assert.NoError(t, checkCloneURLAllowed(plainGit, private.RepoPath()))
+	assert.NoError(t, checkCloneURLAllowed(base.NewRetryDownloader(context.Background(), plainGit, 3, 0), private.RepoPath()))
+	restorer, err := NewRepositoryRestorer(context.Background(), t.TempDir(), doer.Name, "local-path", false)
+	assert.NoError(t, err)
+	assert.NoError(t, checkCloneURLAllowed(restorer, private.RepoPath()))
+	assert.Error(t, checkCloneURLAllowed(base.NewRetryDownloader(context.Background(), &localPathDownloader{}, 3, 0), private.RepoPath()))
+}
+
+// recordingDownloader records the data requested by migrateRepository, the source has none of it
+type recordingDownloader struct {
+	base.NullDownloader
+	supported base.MigrateUnits
+	requested []string
+}
+
+func (d *recordingDownloader) GetRepoInfo() (*base.Repository, error) {
+	return &base.Repository{Name: "units", OriginalURL: "https://example.com/remote/units"}, nil
+}
+
+func (d *recordingDownloader) SupportedUnits() base.MigrateUnits {
+	return d.supported
+}
+
+func (d *recordingDownloader) GetMilestones() ([]*base.Milestone, error) {
+	d.requested = append(d.requested, "milestones")
+	return nil, nil
+}
+
+func (d *recordingDownloader) GetLabels() ([]*base.Label, error) {
+	d.requested = append(d.requested, "labels")
+	return nil, nil
+}
+
+func (d *recordingDownloader) GetReleases() ([]*base.Release, error) {
+	d.requested = append(d.requested, "releases")
+	return nil, nil
+}
+
+func (d *recordingDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, error) {
+	d.requested = append(d.requested, "issues")
+	return nil, true, nil
+}
+
+func (d *recordingDownloader) GetDiscussions(page, perPage int) ([]*base.Discussion, bool, error) {
+	d.requested = append(d.requested, "discussions")
+	return nil, true, nil
+}
+
+func (d *recordingDownloader) GetPullRequests(page, perPage int) ([]*base.PullRequest, bool, error) {
+	d.requested = append(d.requested, "pulls")
+	return nil, true, nil
+}
+
+func (d *recordingDownloader) GetWebhooks() ([]*base.Webhook, error) {
+	d.requested = append(d.requested, "webhooks")
+	return nil, nil
+}
+
+func TestMigrateUnitsSelection(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts      base.MigrateOptions
+		supported base.MigrateUnits
+		requested []string
+	}{
+		"IssuesAndReleases": {
+			opts:      base.MigrateOptions{Units: base.MigrateUnitIssues | base.MigrateUnitReleases},
+			supported: base.MigrateUnitsAll,
+			requested: []string{"releases", "issues"},
+		},
+		"UnitsOverrideFlags": {
+			opts:      base.MigrateOptions{Units: base.MigrateUnitPullRequests, Issues: true, Labels: true},
+			supported: base.MigrateUnitsAll,
+			requested: []string{"pulls"},
+		},
+		"Flags": {
+			opts:      base.MigrateOptions{Milestones: true, Labels: true},
+			supported: base.MigrateUnitsAll,
+			requested: []string{"milestones", "labels"},
+		},
+		"Unsupported": {
+			opts:      base.MigrateOptions{Units: base.MigrateUnitIssues | base.MigrateUnitPullRequests | base.MigrateUnitWebhooks},
+			supported: base.MigrateUnitIssues | base.MigrateUnitLabels,
+			requested: []string{"issues"},
+		},
+		"All": {
+			opts:      base.MigrateOptions{Units: base.MigrateUnitsAll},
+			supported: base.MigrateUnitsAll,
+			requested: []string{"milestones", "labels", "releases", "issues", "discussions", "pulls", "webhooks"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			unittest.PrepareTestEnv(t)
+
+			doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+			repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+			opts := tc.opts
+			opts.MigrateToRepoID = repo.ID
+			opts.MergeIntoExisting = true
+
+			downloader := &recordingDownloader{supported: tc.supported}
+			uploader := NewGiteaLocalUploader(context.Background(), doer, repo.OwnerName, repo.Name)
+			assert.NoError(t, migrateRepository(downloader, uploader, opts, nil))
+			assert.Equal(t, tc.requested, downloader.requested)
+		})
+	}
+}
+
+func TestMigratePlainGitWiki(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(importLocalPaths bool) {
+		setting.ImportLocalPaths = importLocalPaths
+	}(setting.ImportLocalPaths)
+	setting.ImportLocalPaths = true
+
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1}).(*user_model.User)
+	source := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	// the migration form of plain git has no wiki option, the wiki is cloned nevertheless
+	repo, err := MigrateRepository(context.Background(), doer, doer.Name, base.MigrateOptions{
+		CloneAddr:      source.RepoPath(),
+		RepoName:       "plain-git-wiki",
+		GitServiceType: structs.PlainGitService,
+	}, nil)
+	assert.NoError(t, err)
+	assert.True(t, repo.HasWiki())
+}
@@ -94,14 +94,14 @@ func NewOneDevDownloader(ctx context.Context, baseURL *url.URL, username, passwo
 		baseURL:  baseURL,
 		repoName: repoName,
 		client: &http.Client{
-			Transport: &http.Transport{
+			Transport: newRateLimitedTransport(&http.Transport{
 				Proxy: func(req *http.Request) (*url.URL, error) {
 					if len(username) > 0 && len(password) > 0 {
 						req.SetBasicAuth(username, password)
 					}
 					return nil, nil
 				},
-			},
+			}),
 		},
 		userMap:      make(map[int64]*onedevUser),
 		milestoneMap: make(map[int64]string),
@@ -182,6 +182,15 @@ func (d *OneDevDownloader) GetRepoInfo() (*base.Repository, error) {
 	}, nil
 }
 
+// projectURL returns the URL of a page of the project, or an empty string if it can't be built
+func (d *OneDevDownloader) projectURL(page string) string {
+	u, err := d.baseURL.Parse(fmt.Sprintf("/projects/%s/%s", d.repoName, page))
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
 // GetMilestones returns milestones
 func (d *OneDevDownloader) GetMilestones() ([]*base.Milestone, error) {
 	rawMilestones := make([]struct {
@@ -215,8 +224,11 @@ func (d *OneDevDownloader) GetMilestones() ([]*base.Milestone, error) {
 
 		for _, milestone := range rawMilestones {
 			d.milestoneMap[milestone.ID] = milestone.Name
+			state := "open"
 			closed := milestone.DueDate
-			if !milestone.Closed {
+			if milestone.Closed {
+				state = "closed"
+			} else {
 				closed = nil
 			}
 
@@ -225,6 +237,7 @@ func (d *OneDevDownloader) GetMilestones() ([]*base.Milestone, error) {
 				Description: milestone.Description,
 				Deadline:    milestone.DueDate,
 				Closed:      closed,
+				State:       state,
 			})
 		}
 	}
@@ -347,6 +360,7 @@ func (d *OneDevDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, er
 			Updated:      issue.SubmitDate,
 			Labels:       []*base.Label{label},
 			ForeignIndex: issue.ID,
+			OriginalURL:  d.projectURL(fmt.Sprintf("issues/%d", issue.Number)),
 			Context:      onedevIssueContext{IsPullRequest: false},
 		})
 
@@ -540,6 +554,7 @@ func (d *OneDevDownloader) GetPullRequests(page, perPage int) ([]*base.PullReque
 				RepoName: d.repoName,
 			},
 			ForeignIndex: pr.ID,
+			OriginalURL:  d.projectURL(fmt.Sprintf("pulls/%d", pr.Number)),
 			Context:      onedevIssueContext{IsPullRequest: true},
 		})
 	}
@@ -46,10 +46,12 @@ func TestOneDevDownloadRepo(t *testing.T) {
 			Title:    "1.0.0",
 			Deadline: &deadline,
 			Closed:   &deadline,
+			State:    "closed",
 		},
 		{
 			Title:       "1.1.0",
 			Description: "next things?",
+			State:       "open",
 		},
 	}, milestones)
 
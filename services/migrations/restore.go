@@ -7,6 +7,7 @@ package migrations
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -41,6 +42,9 @@ func NewRepositoryRestorer(ctx context.Context, baseDir, owner, repoName string,
 	}, nil
 }
 
+// allowsLocalCloneURL implements localCloneURLDownloader, the git data of a dump is cloned from the dump directory
+func (r *RepositoryRestorer) allowsLocalCloneURL() {}
+
 func (r *RepositoryRestorer) commentDir() string {
 	return filepath.Join(r.baseDir, "comments")
 }
@@ -77,6 +81,7 @@ func (r *RepositoryRestorer) GetRepoInfo() (*base.Repository, error) {
 	}
 
 	isPrivate, _ := strconv.ParseBool(opts["is_private"])
+	isArchived, _ := strconv.ParseBool(opts["is_archived"])
 
 	return &base.Repository{
 		Owner:         r.repoOwner,
@@ -86,6 +91,7 @@ func (r *RepositoryRestorer) GetRepoInfo() (*base.Repository, error) {
 		OriginalURL:   opts["original_url"],
 		CloneURL:      filepath.Join(r.baseDir, "git"),
 		DefaultBranch: opts["default_branch"],
+		IsArchived:    isArchived,
 	}, nil
 }
 
@@ -112,6 +118,58 @@ func (r *RepositoryRestorer) GetTopics() ([]string, error) {
 	return topics.Topics, nil
 }
 
+// GetRepoAvatar returns the avatar of the repository or nil if the dump has none
+func (r *RepositoryRestorer) GetRepoAvatar() (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(r.baseDir, "avatar"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// GetIssueSubscribers returns the users subscribed to an issue or a pull request
+func (r *RepositoryRestorer) GetIssueSubscribers(commentable base.Commentable) ([]*base.IssueSubscriber, error) {
+	subscribers := make([]*base.IssueSubscriber, 0, 10)
+	bs, err := os.ReadFile(filepath.Join(r.baseDir, "issue_subscriber.yml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(bs, &subscribers); err != nil {
+		return nil, err
+	}
+
+	issueSubscribers := make([]*base.IssueSubscriber, 0, len(subscribers))
+	for _, s := range subscribers {
+		if s.IssueIndex == commentable.GetLocalIndex() {
+			issueSubscribers = append(issueSubscribers, s)
+		}
+	}
+	return issueSubscribers, nil
+}
+
+// GetWebhooks returns the webhooks of the repository
+func (r *RepositoryRestorer) GetWebhooks() ([]*base.Webhook, error) {
+	webhooks := make([]*base.Webhook, 0, 10)
+	bs, err := os.ReadFile(filepath.Join(r.baseDir, "webhook.yml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(bs, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
 // GetMilestones returns milestones
 func (r *RepositoryRestorer) GetMilestones() ([]*base.Milestone, error) {
 	milestones := make([]*base.Milestone, 0, 10)
@@ -270,3 +328,65 @@ func (r *RepositoryRestorer) GetReviews(reviewable base.Reviewable) ([]*base.Rev
 	}
 	return reviews, nil
 }
+
+// GetCommitStatuses returns the statuses of a commit
+func (r *RepositoryRestorer) GetCommitStatuses(sha string) ([]*base.CommitStatus, error) {
+	statuses := make([]*base.CommitStatus, 0, 10)
+	p := filepath.Join(r.baseDir, "commit_status.yml")
+	_, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	bs, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(bs, &statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	commitStatuses := make([]*base.CommitStatus, 0, len(statuses))
+	for _, status := range statuses {
+		if status.SHA == sha {
+			commitStatuses = append(commitStatuses, status)
+		}
+	}
+	return commitStatuses, nil
+}
+
+// GetTrackedTimes returns the time spent on an issue or a pull request
+func (r *RepositoryRestorer) GetTrackedTimes(commentable base.Commentable) ([]*base.TrackedTime, error) {
+	times := make([]*base.TrackedTime, 0, 10)
+	p := filepath.Join(r.baseDir, "tracked_time.yml")
+	_, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	bs, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(bs, &times)
+	if err != nil {
+		return nil, err
+	}
+
+	issueTimes := make([]*base.TrackedTime, 0, len(times))
+	for _, t := range times {
+		if t.IssueIndex == commentable.GetLocalIndex() {
+			issueTimes = append(issueTimes, t)
+		}
+	}
+	return issueTimes, nil
+}
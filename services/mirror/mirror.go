@@ -7,6 +7,7 @@ package mirror
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/graceful"
@@ -17,6 +18,26 @@ import (
 
 var mirrorQueue queue.UniqueQueue
 
+// maintenanceMode is 1 while the synchronization of all mirrors is paused
+var maintenanceMode int32
+
+// SetMaintenanceMode pauses or resumes the synchronization of all mirrors. While paused, no mirrors
+// are queued and queued syncs are skipped; due mirrors are synchronized again once it is resumed.
+func SetMaintenanceMode(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	if atomic.SwapInt32(&maintenanceMode, v) != v {
+		log.Info("Mirror maintenance mode: %t", enabled)
+	}
+}
+
+// IsMaintenanceMode returns whether the synchronization of all mirrors is paused
+func IsMaintenanceMode() bool {
+	return atomic.LoadInt32(&maintenanceMode) == 1
+}
+
 // SyncType type of sync request
 type SyncType int
 
@@ -57,6 +78,10 @@ func Update(ctx context.Context, pullLimit, pushLimit int) error {
 		log.Warn("Mirror feature disabled, but cron job enabled: skip update")
 		return nil
 	}
+	if IsMaintenanceMode() {
+		log.Trace("Mirror maintenance mode: skip update")
+		return nil
+	}
 	log.Trace("Doing: Update")
 
 	handler := func(idx int, bean interface{}) error {
@@ -153,6 +178,7 @@ func InitSyncMirrors() {
 	if !setting.Mirror.Enabled {
 		return
 	}
+	SetMaintenanceMode(setting.Mirror.Maintenance)
 	mirrorQueue = queue.CreateUniqueQueue("mirror", queueHandle, new(SyncRequest))
 
 	go graceful.GetManager().RunWithShutdownFns(mirrorQueue.Run)
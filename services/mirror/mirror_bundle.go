@@ -0,0 +1,94 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// BundleDir returns the directory the bundles of the push mirror are written to.
+func BundleDir(m *repo_model.PushMirror) string {
+	return filepath.Join(setting.Mirror.BundlePath, strings.ToLower(m.Repo.OwnerName), strings.ToLower(m.Repo.Name), m.RemoteName)
+}
+
+// parseBundleRefs parses the "<sha> <refname>" lines stored in PushMirror.LastBundleRefs
+func parseBundleRefs(refs string) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(refs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		result[fields[1]] = fields[0]
+	}
+	return result
+}
+
+// runBundleSync writes all objects added since the last bundle of the push mirror into a new
+// incremental git bundle. The refs contained in the bundle are remembered in LastBundleRefs.
+func runBundleSync(ctx context.Context, m *repo_model.PushMirror, timeout time.Duration) (string, error) {
+	repoPath := m.Repo.RepoPath()
+
+	stdout, err := git.NewCommand(ctx, "for-each-ref", "--format=%(objectname) %(refname)", git.BranchPrefix, git.TagPrefix).RunInDirTimeout(timeout, repoPath)
+	if err != nil {
+		return "", fmt.Errorf("for-each-ref: %v", err)
+	}
+	currentRefs := strings.TrimSpace(string(stdout))
+	if currentRefs == "" {
+		log.Trace("Skipping bundle of empty repository %-v for push mirror[%d]", m.Repo, m.ID)
+		return "", nil
+	}
+	if currentRefs == strings.TrimSpace(m.LastBundleRefs) {
+		log.Trace("No changes since the last bundle of push mirror[%d]", m.ID)
+		return "", nil
+	}
+
+	gitRepo, err := git.OpenRepositoryCtx(ctx, repoPath)
+	if err != nil {
+		return "", fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	// Exclude everything reachable from the previous bundle, unless it has been removed since
+	excluded := make([]string, 0, 10)
+	for _, sha := range parseBundleRefs(m.LastBundleRefs) {
+		if gitRepo.IsObjectExist(sha) {
+			excluded = append(excluded, "^"+sha)
+		}
+	}
+	sort.Strings(excluded)
+
+	bundleDir := BundleDir(m)
+	if err := os.MkdirAll(bundleDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("MkdirAll: %v", err)
+	}
+	bundlePath := filepath.Join(bundleDir, fmt.Sprintf("%d.bundle", time.Now().UnixNano()))
+
+	cmd := git.NewCommand(ctx, "bundle", "create", bundlePath, "--branches", "--tags")
+	cmd.AddArguments(excluded...)
+	if _, err := cmd.RunInDirTimeout(timeout, repoPath); err != nil {
+		_ = os.Remove(bundlePath)
+		if strings.Contains(err.Error(), "Refusing to create empty bundle") {
+			// The refs only moved to objects which have been bundled before
+			m.LastBundleRefs = currentRefs
+			return "", nil
+		}
+		return "", fmt.Errorf("bundle create: %v", err)
+	}
+
+	m.LastBundleRefs = currentRefs
+	return bundlePath, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/services/migrations"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSFTPServer starts a SFTP server on the loopback interface which accepts the user "mirror" with the
+// password "secret", it returns the address and the fingerprint of the host key of the server
+func startSFTPServer(t *testing.T) (string, string) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	assert.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "mirror" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+	return listener.Addr().String(), ssh.FingerprintSHA256(signer.PublicKey())
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				// the payload of the subsystem request is the length prefixed name of the subsystem
+				isSFTP := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(isSFTP, nil)
+				if isSFTP {
+					server, err := sftp.NewServer(channel)
+					if err == nil {
+						_ = server.Serve()
+					}
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestSFTPBundleUploader(t *testing.T) {
+	addr, fingerprint := startSFTPServer(t)
+	dir := t.TempDir()
+
+	newUploader := func(t *testing.T, user, fingerprint string) BundleUploader {
+		u, err := url.Parse("sftp://" + user + "@" + addr + dir + "?fingerprint=" + url.QueryEscape(fingerprint))
+		assert.NoError(t, err)
+		uploader, err := newSFTPBundleUploader(u)
+		assert.NoError(t, err)
+		return uploader
+	}
+	assertBundles := func(t *testing.T, expected map[string]string) {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		actual := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			assert.NoError(t, err)
+			actual[entry.Name()] = string(content)
+		}
+		assert.Equal(t, expected, actual)
+	}
+
+	// the server listens on the loopback interface which migrations may not access by default
+	defer func(allowLocalNetworks bool) {
+		setting.Migrations.AllowLocalNetworks = allowLocalNetworks
+		assert.NoError(t, migrations.Init())
+	}(setting.Migrations.AllowLocalNetworks)
+	setting.Migrations.AllowLocalNetworks = false
+	assert.NoError(t, migrations.Init())
+
+	err := newUploader(t, "mirror:secret", fingerprint).Upload(git.DefaultContext, "1.bundle", strings.NewReader("first"))
+	assert.Error(t, err)
+	assertBundles(t, map[string]string{})
+
+	setting.Migrations.AllowLocalNetworks = true
+	assert.NoError(t, migrations.Init())
+
+	uploader := newUploader(t, "mirror:secret", fingerprint)
+	assert.NoError(t, uploader.Upload(git.DefaultContext, "1.bundle", strings.NewReader("first")))
+	assertBundles(t, map[string]string{"1.bundle": "first"})
+
+	// an existing bundle is replaced
+	assert.NoError(t, uploader.Upload(git.DefaultContext, "1.bundle", strings.NewReader("second")))
+	assertBundles(t, map[string]string{"1.bundle": "second"})
+
+	err = newUploader(t, "mirror:wrong", fingerprint).Upload(git.DefaultContext, "2.bundle", strings.NewReader("third"))
+	assert.Error(t, err)
+	err = newUploader(t, "mirror:secret", "SHA256:wrong").Upload(git.DefaultContext, "2.bundle", strings.NewReader("third"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "fingerprint")
+	}
+	assertBundles(t, map[string]string{"1.bundle": "second"})
+}
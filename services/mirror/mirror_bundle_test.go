@@ -0,0 +1,78 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listBundleHeads(t *testing.T, repoPath, bundlePath string) map[string]string {
+	stdout, err := git.NewCommand(git.DefaultContext, "bundle", "list-heads", bundlePath).RunInDir(repoPath)
+	assert.NoError(t, err)
+	return parseBundleRefs(stdout)
+}
+
+func TestRunBundleSync(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(bundlePath string) {
+		setting.Mirror.BundlePath = bundlePath
+	}(setting.Mirror.BundlePath)
+	setting.Mirror.BundlePath = t.TempDir()
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	repoPath := repo.RepoPath()
+	m := &repo_model.PushMirror{ID: 1, RepoID: repo.ID, Repo: repo, RemoteName: "bundle", IsBundle: true}
+
+	gitRepo, err := git.OpenRepositoryCtx(git.DefaultContext, repoPath)
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	masterSHA, err := gitRepo.GetBranchCommitID("master")
+	assert.NoError(t, err)
+
+	// The first bundle contains everything
+	bundlePath, err := runBundleSync(git.DefaultContext, m, -1)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(bundlePath, BundleDir(m)))
+	heads := listBundleHeads(t, repoPath, bundlePath)
+	assert.Equal(t, masterSHA, heads["refs/heads/master"])
+	assert.Equal(t, parseBundleRefs(m.LastBundleRefs), heads)
+
+	// Nothing changed, so no new bundle is written
+	bundlePath, err = runBundleSync(git.DefaultContext, m, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, bundlePath)
+
+	// Add a commit to master
+	env := append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	stdout, err := git.NewCommand(git.DefaultContext, "commit-tree", "-p", masterSHA, "-m", "incremental", masterSHA+"^{tree}").RunInDirWithEnv(repoPath, env)
+	assert.NoError(t, err)
+	newSHA := strings.TrimSpace(stdout)
+	_, err = git.NewCommand(git.DefaultContext, "update-ref", git.BranchPrefix+"master", newSHA).RunInDir(repoPath)
+	assert.NoError(t, err)
+
+	// The incremental bundle only contains the changed ref and requires the previous bundle
+	bundlePath, err = runBundleSync(git.DefaultContext, m, -1)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, bundlePath)
+	heads = listBundleHeads(t, repoPath, bundlePath)
+	assert.Equal(t, map[string]string{"refs/heads/master": newSHA}, heads)
+	assert.Equal(t, newSHA, parseBundleRefs(m.LastBundleRefs)["refs/heads/master"])
+
+	stdout, err = git.NewCommand(git.DefaultContext, "bundle", "verify", bundlePath).RunInDir(repoPath)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, masterSHA)
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/log"
+)
+
+// BundleUploader uploads the bundles of a bundle push mirror to a destination downstream sites fetch them from
+type BundleUploader interface {
+	// Upload stores the content as a file with the name in the destination.
+	// A failed upload must not leave a partial file with the name behind.
+	Upload(ctx context.Context, name string, content io.Reader) error
+}
+
+// BundleUploaderFactory creates the uploader of a PushMirror.BundleUploadURL, it must validate the URL
+type BundleUploaderFactory func(uploadURL *url.URL) (BundleUploader, error)
+
+var (
+	bundleUploaderFactoriesMutex sync.RWMutex
+	bundleUploaderFactories      = map[string]BundleUploaderFactory{
+		"sftp": newSFTPBundleUploader,
+	}
+)
+
+// RegisterBundleUploader registers the uploader factory for the bundle upload URLs with the scheme,
+// replacing the factory registered before
+func RegisterBundleUploader(scheme string, factory BundleUploaderFactory) {
+	bundleUploaderFactoriesMutex.Lock()
+	defer bundleUploaderFactoriesMutex.Unlock()
+	bundleUploaderFactories[scheme] = factory
+}
+
+// UnregisterBundleUploader removes the uploader factory of the scheme
+func UnregisterBundleUploader(scheme string) {
+	bundleUploaderFactoriesMutex.Lock()
+	defer bundleUploaderFactoriesMutex.Unlock()
+	delete(bundleUploaderFactories, scheme)
+}
+
+// newBundleUploader creates the uploader of a bundle upload URL with the factory registered for its scheme
+func newBundleUploader(uploadURL string) (BundleUploader, error) {
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return nil, errors.New("unable to parse the bundle upload URL")
+	}
+	bundleUploaderFactoriesMutex.RLock()
+	factory, ok := bundleUploaderFactories[u.Scheme]
+	bundleUploaderFactoriesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("bundles can't be uploaded to %q URLs", u.Scheme)
+	}
+	return factory(u)
+}
+
+// ValidateBundleUpload checks if the bundle upload URL of the push mirror can be used
+func ValidateBundleUpload(m *repo_model.PushMirror) error {
+	if m.BundleUploadURL == "" {
+		return nil
+	}
+	if !m.IsBundle {
+		return errors.New("only bundle push mirrors can upload bundles")
+	}
+	_, err := newBundleUploader(m.BundleUploadURL)
+	return err
+}
+
+// uploadBundle uploads a bundle written by runBundleSync to the bundle upload URL of the push mirror
+func uploadBundle(ctx context.Context, m *repo_model.PushMirror, bundlePath string) error {
+	uploader, err := newBundleUploader(m.BundleUploadURL)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := filepath.Base(bundlePath)
+	if err := uploader.Upload(ctx, name, f); err != nil {
+		return fmt.Errorf("unable to upload bundle %s: %v", name, err)
+	}
+	log.Trace("Push mirror[%d] bundle %s uploaded", m.ID, name)
+	return nil
+}
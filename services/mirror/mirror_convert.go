@@ -0,0 +1,157 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// ConvertMirrorDirection converts a pull mirror into a push mirror to the same remote, or the only
+// push mirror of a repository into a pull mirror. The git remotes of the repository and its wiki
+// are reconfigured accordingly.
+func ConvertMirrorDirection(ctx context.Context, repo *repo_model.Repository, toPush bool) error {
+	if !repository.StartRepoSync(repo.ID) {
+		return repository.ErrRepoSyncRunning
+	}
+	defer repository.StopRepoSync(repo.ID)
+
+	if toPush {
+		return convertPullToPushMirror(ctx, repo)
+	}
+	return convertPushToPullMirror(ctx, repo)
+}
+
+func convertPullToPushMirror(ctx context.Context, repo *repo_model.Repository) error {
+	if !repo.IsMirror {
+		return fmt.Errorf("repository %s is not a pull mirror", repo.FullName())
+	}
+	pullMirror, err := repo_model.GetMirrorByRepoID(repo.ID)
+	if err != nil {
+		return fmt.Errorf("GetMirrorByRepoID: %v", err)
+	}
+	remoteAddr, err := git.GetRemoteAddress(ctx, repo.RepoPath(), pullMirror.GetRemoteName())
+	if err != nil {
+		return fmt.Errorf("GetRemoteAddress: %v", err)
+	}
+
+	remoteName, err := NewPushMirrorRemoteName(ctx, repo, remoteAddr.String())
+	if err != nil {
+		return err
+	}
+
+	// removes the pull mirror remotes of the repository and the wiki
+	repo.IsMirror = false
+	if _, err := repository.CleanUpMigrateInfo(ctx, repo); err != nil {
+		return fmt.Errorf("CleanUpMigrateInfo: %v", err)
+	}
+	if err := repo_model.DeleteMirrorByRepoID(repo.ID); err != nil {
+		return fmt.Errorf("DeleteMirrorByRepoID: %v", err)
+	}
+
+	m := &repo_model.PushMirror{
+		RepoID:     repo.ID,
+		Repo:       repo,
+		RemoteName: remoteName,
+		Interval:   pullMirror.Interval,
+	}
+	if err := repo_model.InsertPushMirror(m); err != nil {
+		return fmt.Errorf("InsertPushMirror: %v", err)
+	}
+	if err := AddPushMirrorRemote(ctx, m, remoteAddr.String()); err != nil {
+		if err := repo_model.DeletePushMirrorByID(m.ID); err != nil {
+			log.Error("DeletePushMirrorByID %v", err)
+		}
+		return fmt.Errorf("AddPushMirrorRemote: %v", err)
+	}
+
+	log.Trace("Pull mirror of %s converted to push mirror[%d]", repo.FullName(), m.ID)
+	return nil
+}
+
+func convertPushToPullMirror(ctx context.Context, repo *repo_model.Repository) error {
+	if repo.IsMirror {
+		return fmt.Errorf("repository %s is already a pull mirror", repo.FullName())
+	}
+	pushMirrors, err := repo_model.GetPushMirrorsByRepoID(repo.ID)
+	if err != nil {
+		return fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+	}
+	if len(pushMirrors) != 1 {
+		return fmt.Errorf("repository %s has %d push mirrors, only a single one can be converted", repo.FullName(), len(pushMirrors))
+	}
+	m := pushMirrors[0]
+	m.Repo = repo
+	if m.IsBundle {
+		return fmt.Errorf("bundle push mirror[%d] cannot be converted", m.ID)
+	}
+
+	remoteAddr, err := git.GetRemoteAddress(ctx, repo.RepoPath(), m.RemoteName)
+	if err != nil {
+		return fmt.Errorf("GetRemoteAddress: %v", err)
+	}
+	var wikiRemoteAddr string
+	if repo.HasWiki() {
+		// the wiki remote only exists if the remote wiki could be found when the push mirror was added
+		if addr, err := git.GetRemoteAddress(ctx, repo.WikiPath(), m.RemoteName); err == nil {
+			wikiRemoteAddr = addr.String()
+		}
+	}
+
+	if err := RemovePushMirrorRemote(ctx, m); err != nil {
+		return fmt.Errorf("RemovePushMirrorRemote: %v", err)
+	}
+	if err := repo_model.DeletePushMirrorByID(m.ID); err != nil {
+		return fmt.Errorf("DeletePushMirrorByID: %v", err)
+	}
+
+	pullMirror := &repo_model.Mirror{
+		RepoID:      repo.ID,
+		Repo:        repo,
+		Interval:    m.Interval,
+		EnablePrune: true,
+	}
+	if pullMirror.Interval != 0 && pullMirror.Interval < setting.Mirror.MinInterval {
+		pullMirror.Interval = setting.Mirror.DefaultInterval
+	}
+	if pullMirror.Interval != 0 {
+		pullMirror.NextUpdateUnix = timeutil.TimeStampNow().AddDuration(pullMirror.Interval)
+	}
+	if err := repo_model.InsertMirror(pullMirror); err != nil {
+		return fmt.Errorf("InsertMirror: %v", err)
+	}
+
+	addFetchRemote := func(repoPath, addr string) error {
+		_, err := git.NewCommand(ctx, "remote", "add", pullMirror.GetRemoteName(), "--mirror=fetch", addr).RunInDir(repoPath)
+		return err
+	}
+	if err := addFetchRemote(repo.RepoPath(), remoteAddr.String()); err != nil {
+		return fmt.Errorf("add remote: %v", err)
+	}
+	if wikiRemoteAddr != "" {
+		if err := addFetchRemote(repo.WikiPath(), wikiRemoteAddr); err != nil {
+			return fmt.Errorf("add wiki remote: %v", err)
+		}
+	}
+
+	// don't keep the credentials of the remote in the original URL
+	originalURL := *remoteAddr
+	originalURL.User = nil
+	repo.IsMirror = true
+	repo.OriginalURL = originalURL.String()
+	if err := repo_model.UpdateRepositoryCols(repo, "is_mirror", "original_url"); err != nil {
+		return fmt.Errorf("UpdateRepositoryCols: %v", err)
+	}
+
+	log.Trace("Push mirror[%d] of %s converted to pull mirror", m.ID, repo.FullName())
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertMirrorDirection(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	assert.True(t, repo.HasWiki())
+
+	remoteDir := t.TempDir()
+	remotePath := filepath.Join(remoteDir, "remote.git")
+	remoteWikiPath := filepath.Join(remoteDir, "remote.wiki.git")
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remoteWikiPath, true))
+
+	remoteConfig := func(repoPath, key string) string {
+		stdout, _ := git.NewCommand(git.DefaultContext, "config", "--get-all", key).RunInDir(repoPath)
+		return strings.TrimSpace(stdout)
+	}
+
+	pushMirror := &repo_model.PushMirror{RepoID: repo.ID, Repo: repo, RemoteName: "remote_mirror_convert", Interval: setting.Mirror.DefaultInterval}
+	assert.NoError(t, repo_model.InsertPushMirror(pushMirror))
+	assert.NoError(t, AddPushMirrorRemote(git.DefaultContext, pushMirror, remotePath))
+
+	t.Run("PushToPull", func(t *testing.T) {
+		assert.NoError(t, ConvertMirrorDirection(git.DefaultContext, repo, false))
+
+		unittest.AssertNotExistsBean(t, &repo_model.PushMirror{ID: pushMirror.ID})
+		pullMirror := unittest.AssertExistsAndLoadBean(t, &repo_model.Mirror{RepoID: repo.ID}).(*repo_model.Mirror)
+		assert.Equal(t, pushMirror.Interval, pullMirror.Interval)
+		assert.True(t, pullMirror.EnablePrune)
+		assert.NotZero(t, pullMirror.NextUpdateUnix)
+		repo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: repo.ID}).(*repo_model.Repository)
+		assert.True(t, repo.IsMirror)
+		assert.Equal(t, remotePath, repo.OriginalURL)
+
+		for path, addr := range map[string]string{repo.RepoPath(): remotePath, repo.WikiPath(): remoteWikiPath} {
+			assert.Empty(t, remoteConfig(path, "remote.remote_mirror_convert.url"))
+			assert.Equal(t, addr, remoteConfig(path, "remote.origin.url"))
+			assert.Equal(t, "+refs/*:refs/*", remoteConfig(path, "remote.origin.fetch"))
+		}
+	})
+
+	t.Run("PullToPush", func(t *testing.T) {
+		assert.NoError(t, ConvertMirrorDirection(git.DefaultContext, repo, true))
+
+		unittest.AssertNotExistsBean(t, &repo_model.Mirror{RepoID: repo.ID})
+		pushMirrors, err := repo_model.GetPushMirrorsByRepoID(repo.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, pushMirrors, 1) {
+			assert.Equal(t, setting.Mirror.DefaultInterval, pushMirrors[0].Interval)
+			assert.True(t, strings.HasPrefix(pushMirrors[0].RemoteName, "remote_mirror_"))
+		}
+		repo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: repo.ID}).(*repo_model.Repository)
+		assert.False(t, repo.IsMirror)
+
+		for path, addr := range map[string]string{repo.RepoPath(): remotePath, repo.WikiPath(): remoteWikiPath} {
+			assert.Empty(t, remoteConfig(path, "remote.origin.url"))
+			assert.Equal(t, addr, remoteConfig(path, "remote."+pushMirrors[0].RemoteName+".url"))
+			assert.Equal(t, "true", remoteConfig(path, "remote."+pushMirrors[0].RemoteName+".mirror"))
+		}
+
+		// the push mirror can be synced to the remote
+		_, err = runPushSync(git.DefaultContext, pushMirrors[0])
+		assert.NoError(t, err)
+	})
+
+	t.Run("AlreadyRunning", func(t *testing.T) {
+		assert.True(t, repository.StartRepoSync(repo.ID))
+		defer repository.StopRepoSync(repo.ID)
+		assert.ErrorIs(t, ConvertMirrorDirection(git.DefaultContext, repo, false), repository.ErrRepoSyncRunning)
+	})
+}
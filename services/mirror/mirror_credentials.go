@@ -0,0 +1,162 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+)
+
+// CredentialProvider generates the credentials of push mirror remotes at push time instead of using the
+// static credentials stored in the remote address, e.g. for remotes which require short-lived passwords
+// derived by a request signing process
+type CredentialProvider interface {
+	// Match returns whether the provider is responsible for the credentials of the remote
+	Match(remoteAddr *url.URL) bool
+	// Credentials returns the username and password for the next push to the remote
+	Credentials(ctx context.Context, remoteAddr *url.URL) (username, password string, err error)
+}
+
+var (
+	credentialProvidersMutex sync.RWMutex
+	credentialProviders      []CredentialProvider
+)
+
+// RegisterCredentialProvider registers a credential provider for push mirror remotes,
+// the first registered provider matching a remote is used
+func RegisterCredentialProvider(provider CredentialProvider) {
+	credentialProvidersMutex.Lock()
+	defer credentialProvidersMutex.Unlock()
+	credentialProviders = append(credentialProviders, provider)
+}
+
+// UnregisterCredentialProvider removes a registered credential provider
+func UnregisterCredentialProvider(provider CredentialProvider) {
+	credentialProvidersMutex.Lock()
+	defer credentialProvidersMutex.Unlock()
+	for i, p := range credentialProviders {
+		if p == provider {
+			credentialProviders = append(credentialProviders[:i], credentialProviders[i+1:]...)
+			return
+		}
+	}
+}
+
+// StaticCredentialProvider provides the same credentials for all HTTP(S) remotes of a host
+type StaticCredentialProvider struct {
+	// Host is the host of the remotes, optionally with a port which then has to match too
+	Host     string
+	Username string
+	Password string
+}
+
+// Match implements CredentialProvider
+func (p *StaticCredentialProvider) Match(remoteAddr *url.URL) bool {
+	return isHTTPRemoteOfHost(remoteAddr, p.Host)
+}
+
+// Credentials implements CredentialProvider
+func (p *StaticCredentialProvider) Credentials(ctx context.Context, remoteAddr *url.URL) (string, string, error) {
+	return p.Username, p.Password, nil
+}
+
+// remoteAddrWithProvidedCredentials returns the remote address with the credentials of the first matching
+// credential provider, or nil if no provider is responsible for the remote
+func remoteAddrWithProvidedCredentials(ctx context.Context, remoteAddr *url.URL) (*url.URL, error) {
+	credentialProvidersMutex.RLock()
+	var provider CredentialProvider
+	for _, p := range credentialProviders {
+		if p.Match(remoteAddr) {
+			provider = p
+			break
+		}
+	}
+	credentialProvidersMutex.RUnlock()
+	if provider == nil {
+		return nil, nil
+	}
+
+	username, password, err := provider.Credentials(ctx, remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the credentials of the remote: %v", err)
+	}
+	addr := *remoteAddr
+	addr.User = url.UserPassword(username, password)
+	return &addr, nil
+}
+
+const (
+	providedUsernameEnv = "GITEA_PUSH_MIRROR_USERNAME"
+	providedPasswordEnv = "GITEA_PUSH_MIRROR_PASSWORD"
+
+	// providedCredentialsHelper answers the credential requests of git with the credentials in the environment
+	providedCredentialsHelper = `!f() { test "$1" != get || printf 'username=%s\npassword=%s\n' "$` + providedUsernameEnv + `" "$` + providedPasswordEnv + `"; }; f`
+)
+
+// providedCredentialsConfig returns the config values and the environment which pass the credentials of an address
+// returned by remoteAddrWithProvidedCredentials to a git command addressing the remote by its name.
+// The credentials are only passed in the environment, so they don't show up in the arguments of the git process.
+// Credentials stored in the address of the remote itself take precedence. Both are nil if providedAddr is nil.
+func providedCredentialsConfig(providedAddr *url.URL) (config, env []string) {
+	if providedAddr == nil || providedAddr.User == nil {
+		return nil, nil
+	}
+	password, _ := providedAddr.User.Password()
+	// the global empty credential.helper is overridden, other helpers must not get the credentials
+	config = []string{"credential.helper=", "credential.helper=" + providedCredentialsHelper}
+	env = append(os.Environ(), providedUsernameEnv+"="+providedAddr.User.Username(), providedPasswordEnv+"="+password)
+	return config, env
+}
+
+// configArgs returns the arguments of a git command for the given config values
+func configArgs(config []string) []string {
+	args := make([]string, 0, 2*len(config))
+	for _, value := range config {
+		args = append(args, "-c", value)
+	}
+	return args
+}
+
+// PullMirrorCredentials returns the credentials stored in the pull mirror remote of the repository if the
+// remote is a HTTP(S) URL of the same scheme and host as the push mirror address, so that they don't have to be
+// entered again. It returns nil if the repository is no pull mirror or the schemes or hosts differ.
+func PullMirrorCredentials(ctx context.Context, repo *repo_model.Repository, pushAddr string) (*url.Userinfo, error) {
+	if !repo.IsMirror {
+		return nil, nil
+	}
+	pushURL, err := url.Parse(pushAddr)
+	if err != nil {
+		// the address is validated when the push mirror is added
+		return nil, nil
+	}
+
+	m, err := repo_model.GetMirrorByRepoID(repo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMirrorByRepoID: %v", err)
+	}
+	pullURL, err := git.GetRemoteAddress(ctx, repo.RepoPath(), m.GetRemoteName())
+	if err != nil {
+		return nil, fmt.Errorf("GetRemoteAddress: %v", err)
+	}
+	if pullURL.User == nil || !strings.EqualFold(pushURL.Host, pullURL.Host) {
+		return nil, nil
+	}
+	// the credentials of a HTTPS remote must not be sent in cleartext to a HTTP remote
+	if !strings.EqualFold(pushURL.Scheme, pullURL.Scheme) {
+		return nil, nil
+	}
+	// other schemes, e.g. SSH, don't use the credentials of the address in the same way
+	if !isHTTPRemoteOfHost(pushURL, pushURL.Host) || !isHTTPRemoteOfHost(pullURL, pullURL.Host) {
+		return nil, nil
+	}
+	return pullURL.User, nil
+}
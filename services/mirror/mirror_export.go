@@ -0,0 +1,205 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/secret"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// PushMirrorExport is the configuration of a push mirror for backup and restore.
+// The secrets, i.e. the remote address including its credentials, the notify webhook URL and the bundle upload URL,
+// are only exported encrypted with setting.SecretKey, so they can only be imported by an instance with the same key.
+type PushMirrorExport struct {
+	RemoteName            string `json:"remote_name"`
+	RemoteAddress         string `json:"remote_address,omitempty"`
+	IsBundle              bool   `json:"is_bundle,omitempty"`
+	AllowUnrelatedHistory bool   `json:"allow_unrelated_history,omitempty"`
+	TagFilter             string `json:"tag_filter,omitempty"`
+	LFSOnly               bool   `json:"lfs_only,omitempty"`
+	SkipLFS               bool   `json:"skip_lfs,omitempty"`
+	RefMapping            string `json:"ref_mapping,omitempty"`
+	Interval              string `json:"interval"`
+	TimeoutSeconds        int64  `json:"timeout_seconds,omitempty"`
+
+	RemoteAddressEncrypted    string `json:"remote_address_encrypted,omitempty"`
+	NotifyWebhookURLEncrypted string `json:"notify_webhook_url_encrypted,omitempty"`
+	BundleUploadURLEncrypted  string `json:"bundle_upload_url_encrypted,omitempty"`
+}
+
+// ExportPushMirrors returns the configurations of the push mirrors of a repository.
+// The remote addresses are exported without their credentials, withSecrets additionally exports
+// the complete addresses and the notify webhook URLs encrypted.
+func ExportPushMirrors(ctx context.Context, repoID int64, withSecrets bool) ([]PushMirrorExport, error) {
+	repo, err := repo_model.GetRepositoryByID(repoID)
+	if err != nil {
+		return nil, err
+	}
+	mirrors, err := repo_model.GetPushMirrorsByRepoID(repoID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+	}
+
+	exports := make([]PushMirrorExport, 0, len(mirrors))
+	for _, m := range mirrors {
+		export := PushMirrorExport{
+			RemoteName:            m.RemoteName,
+			IsBundle:              m.IsBundle,
+			AllowUnrelatedHistory: m.AllowUnrelatedHistory,
+			TagFilter:             m.TagFilter,
+			LFSOnly:               m.LFSOnly,
+			SkipLFS:               m.SkipLFS,
+			RefMapping:            m.RefMapping,
+			Interval:              m.Interval.String(),
+			TimeoutSeconds:        m.TimeoutSeconds,
+		}
+
+		if !m.IsBundle {
+			remoteAddr, err := git.GetRemoteAddress(ctx, repo.RepoPath(), m.RemoteName)
+			if err != nil {
+				return nil, fmt.Errorf("GetRemoteAddress of push mirror[%d]: %v", m.ID, err)
+			}
+			if withSecrets && remoteAddr.User != nil {
+				if export.RemoteAddressEncrypted, err = secret.EncryptSecret(setting.SecretKey, remoteAddr.String()); err != nil {
+					return nil, err
+				}
+			}
+			addr := *remoteAddr
+			addr.User = nil
+			export.RemoteAddress = addr.String()
+		}
+
+		if withSecrets && m.NotifyWebhookURL != "" {
+			if export.NotifyWebhookURLEncrypted, err = secret.EncryptSecret(setting.SecretKey, m.NotifyWebhookURL); err != nil {
+				return nil, err
+			}
+		}
+		if withSecrets && m.BundleUploadURL != "" {
+			if export.BundleUploadURLEncrypted, err = secret.EncryptSecret(setting.SecretKey, m.BundleUploadURL); err != nil {
+				return nil, err
+			}
+		}
+
+		exports = append(exports, export)
+	}
+	return exports, nil
+}
+
+// ImportPushMirrors recreates exported push mirrors for a repository, including their remotes.
+// The remote names must not be used by other push mirrors of the repository.
+// The remote addresses are not checked against the migration allow and block lists, callers have to do that.
+func ImportPushMirrors(ctx context.Context, repoID int64, exports []PushMirrorExport) error {
+	repo, err := repo_model.GetRepositoryByID(repoID)
+	if err != nil {
+		return err
+	}
+	existing, err := repo_model.GetPushMirrorsByRepoID(repoID)
+	if err != nil {
+		return fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+	}
+	remoteNames := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		remoteNames[m.RemoteName] = true
+	}
+
+	for _, export := range exports {
+		if export.RemoteName == "" || remoteNames[export.RemoteName] {
+			return fmt.Errorf("remote name %q of push mirror is empty or already used", export.RemoteName)
+		}
+		remoteNames[export.RemoteName] = true
+
+		m, addr, err := pushMirrorFromExport(repo, export)
+		if err != nil {
+			return fmt.Errorf("push mirror %s: %v", export.RemoteName, err)
+		}
+
+		if err := repo_model.InsertPushMirror(m); err != nil {
+			return fmt.Errorf("InsertPushMirror: %v", err)
+		}
+		if m.IsBundle {
+			continue
+		}
+		if err := AddPushMirrorRemote(ctx, m, addr); err != nil {
+			if err := repo_model.DeletePushMirrorByID(m.ID); err != nil {
+				log.Error("DeletePushMirrorByID %v", err)
+			}
+			return fmt.Errorf("AddPushMirrorRemote of push mirror %s: %v", m.RemoteName, err)
+		}
+	}
+	return nil
+}
+
+// pushMirrorFromExport validates an exported push mirror and returns it with its remote address
+func pushMirrorFromExport(repo *repo_model.Repository, export PushMirrorExport) (*repo_model.PushMirror, string, error) {
+	interval, err := setting.ParseMirrorInterval(export.Interval)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid interval: %v", err)
+	}
+
+	m := &repo_model.PushMirror{
+		RepoID:                repo.ID,
+		Repo:                  repo,
+		RemoteName:            export.RemoteName,
+		IsBundle:              export.IsBundle,
+		AllowUnrelatedHistory: export.AllowUnrelatedHistory,
+		TagFilter:             export.TagFilter,
+		LFSOnly:               export.LFSOnly,
+		SkipLFS:               export.SkipLFS,
+		RefMapping:            export.RefMapping,
+		Interval:              interval,
+		TimeoutSeconds:        export.TimeoutSeconds,
+	}
+	if export.NotifyWebhookURLEncrypted != "" {
+		if m.NotifyWebhookURL, err = secret.DecryptSecret(setting.SecretKey, export.NotifyWebhookURLEncrypted); err != nil {
+			return nil, "", fmt.Errorf("unable to decrypt the notify webhook URL: %v", err)
+		}
+		if err := ValidateNotifyWebhookURL(m.NotifyWebhookURL); err != nil {
+			return nil, "", fmt.Errorf("invalid notify webhook URL: %v", err)
+		}
+	}
+
+	if export.BundleUploadURLEncrypted != "" {
+		if m.BundleUploadURL, err = secret.DecryptSecret(setting.SecretKey, export.BundleUploadURLEncrypted); err != nil {
+			return nil, "", fmt.Errorf("unable to decrypt the bundle upload URL: %v", err)
+		}
+		if err := ValidateBundleUpload(m); err != nil {
+			return nil, "", fmt.Errorf("invalid bundle upload URL: %v", err)
+		}
+	}
+
+	if err := ValidateTagFilter(m.TagFilter); err != nil {
+		return nil, "", fmt.Errorf("invalid tag filter %q: %v", m.TagFilter, err)
+	}
+	if err := ValidatePushMirrorTimeout(m.TimeoutSeconds); err != nil {
+		return nil, "", fmt.Errorf("invalid timeout: %v", err)
+	}
+	if err := ValidateLFSOnly(m); err != nil {
+		return nil, "", fmt.Errorf("invalid LFS-only push mirror: %v", err)
+	}
+	if _, err := ValidateRefMapping(m); err != nil {
+		return nil, "", fmt.Errorf("invalid ref mapping: %v", err)
+	}
+
+	if m.IsBundle {
+		return m, "", nil
+	}
+	addr := export.RemoteAddress
+	if export.RemoteAddressEncrypted != "" {
+		if addr, err = secret.DecryptSecret(setting.SecretKey, export.RemoteAddressEncrypted); err != nil {
+			return nil, "", fmt.Errorf("unable to decrypt the remote address: %v", err)
+		}
+	}
+	if _, err := url.Parse(addr); err != nil || addr == "" {
+		return nil, "", fmt.Errorf("invalid remote address %q", export.RemoteAddress)
+	}
+	return m, addr, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// gcMirrorRepository is replaced in tests to observe the garbage collections
+var gcMirrorRepository = runAutoGC
+
+// autoGCMirror counts a successful sync of a mirror of the repository and runs `git gc --auto` every
+// setting.Mirror.AutoGCInterval syncs. It returns the number of syncs since the last garbage collection.
+func autoGCMirror(ctx context.Context, repo *repo_model.Repository, syncsSinceGC int) int {
+	if setting.Mirror.AutoGCInterval <= 0 {
+		return 0
+	}
+	syncsSinceGC++
+	if syncsSinceGC < setting.Mirror.AutoGCInterval {
+		return syncsSinceGC
+	}
+
+	if err := gcMirrorRepository(ctx, repo); err != nil {
+		// keep counting, the garbage collection is retried after the next sync
+		log.Error("Auto gc of mirror repository %-v failed: %v", repo, err)
+		return syncsSinceGC
+	}
+	return 0
+}
+
+// runAutoGC runs `git gc --auto` in the repository within the gc timeout
+func runAutoGC(ctx context.Context, repo *repo_model.Repository) error {
+	log.Trace("Running git gc --auto on mirror repository %-v", repo)
+	command := git.NewCommand(ctx, "gc", "--auto").
+		SetDescription(fmt.Sprintf("Mirror Garbage Collection: %s", repo.FullName()))
+
+	var stdout string
+	var err error
+	if timeout := time.Duration(setting.Git.Timeout.GC) * time.Second; timeout > 0 {
+		var stdoutBytes []byte
+		stdoutBytes, err = command.RunInDirTimeout(timeout, repo.RepoPath())
+		stdout = string(stdoutBytes)
+	} else {
+		stdout, err = command.RunInDir(repo.RepoPath())
+	}
+	if err != nil {
+		return fmt.Errorf("git gc --auto: %v, stdout: %s", err, stdout)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPushMirrorAutoGC(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(interval int, lfsServer bool) {
+		setting.Mirror.AutoGCInterval = interval
+		setting.LFS.StartServer = lfsServer
+		gcMirrorRepository = runAutoGC
+	}(setting.Mirror.AutoGCInterval, setting.LFS.StartServer)
+	setting.Mirror.AutoGCInterval = 3
+	setting.LFS.StartServer = false
+
+	gcRuns := 0
+	gcMirrorRepository = func(ctx context.Context, repo *repo_model.Repository) error {
+		gcRuns++
+		return runAutoGC(ctx, repo)
+	}
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	remotePath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+
+	m := &repo_model.PushMirror{RepoID: repo.ID, Repo: repo, RemoteName: "auto_gc", Interval: time.Hour}
+	assert.NoError(t, repo_model.InsertPushMirror(m))
+	assert.NoError(t, AddPushMirrorRemote(git.DefaultContext, m, remotePath))
+	defer func() {
+		assert.NoError(t, RemovePushMirrorRemote(git.DefaultContext, m))
+	}()
+
+	for i := 1; i < 3; i++ {
+		assert.True(t, SyncPushMirror(git.DefaultContext, m.ID))
+		m = unittest.AssertExistsAndLoadBean(t, &repo_model.PushMirror{ID: m.ID}).(*repo_model.PushMirror)
+		assert.Equal(t, i, m.SyncsSinceGC)
+		assert.Zero(t, gcRuns)
+	}
+
+	assert.True(t, SyncPushMirror(git.DefaultContext, m.ID))
+	m = unittest.AssertExistsAndLoadBean(t, &repo_model.PushMirror{ID: m.ID}).(*repo_model.PushMirror)
+	assert.Zero(t, m.SyncsSinceGC)
+	assert.Equal(t, 1, gcRuns)
+}
+
+func TestAutoGCMirror(t *testing.T) {
+	defer func(interval int) {
+		setting.Mirror.AutoGCInterval = interval
+		gcMirrorRepository = runAutoGC
+	}(setting.Mirror.AutoGCInterval)
+
+	gcRuns := 0
+	var gcErr error
+	gcMirrorRepository = func(ctx context.Context, repo *repo_model.Repository) error {
+		gcRuns++
+		return gcErr
+	}
+	repo := &repo_model.Repository{ID: 1}
+
+	// disabled
+	setting.Mirror.AutoGCInterval = 0
+	assert.Zero(t, autoGCMirror(git.DefaultContext, repo, 5))
+	assert.Zero(t, gcRuns)
+
+	setting.Mirror.AutoGCInterval = 2
+	assert.Equal(t, 1, autoGCMirror(git.DefaultContext, repo, 0))
+	assert.Zero(t, gcRuns)
+	assert.Zero(t, autoGCMirror(git.DefaultContext, repo, 1))
+	assert.Equal(t, 1, gcRuns)
+
+	// a failed garbage collection is retried after the next sync
+	gcErr = errors.New("gc failed")
+	assert.Equal(t, 2, autoGCMirror(git.DefaultContext, repo, 1))
+	assert.Equal(t, 3, autoGCMirror(git.DefaultContext, repo, 2))
+	assert.Equal(t, 3, gcRuns)
+	gcErr = nil
+	assert.Zero(t, autoGCMirror(git.DefaultContext, repo, 3))
+	assert.Equal(t, 4, gcRuns)
+}
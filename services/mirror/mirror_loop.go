@@ -0,0 +1,267 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/private"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// ErrPushMirrorLoop is returned if the push mirrors of repositories push to each other in a cycle, either on
+// this instance or across instances which sent the repositories they pushed from along with their pushes
+type ErrPushMirrorLoop struct {
+	Repos []string
+}
+
+func (err ErrPushMirrorLoop) Error() string {
+	return fmt.Sprintf("push mirror loop detected: %s", strings.Join(err.Repos, " -> "))
+}
+
+// IsErrPushMirrorLoop checks if an error is a ErrPushMirrorLoop
+func IsErrPushMirrorLoop(err error) bool {
+	_, ok := err.(ErrPushMirrorLoop)
+	return ok
+}
+
+// ErrPushMirrorSameInstance is returned if a push mirror would push to a repository of this instance
+// and setting.Mirror.AllowSameInstancePush is disabled
+type ErrPushMirrorSameInstance struct {
+	Repo string
+}
+
+func (err ErrPushMirrorSameInstance) Error() string {
+	return fmt.Sprintf("push mirror to the repository %s of this instance is not allowed", err.Repo)
+}
+
+// IsErrPushMirrorSameInstance checks if an error is a ErrPushMirrorSameInstance
+func IsErrPushMirrorSameInstance(err error) bool {
+	_, ok := err.(ErrPushMirrorSameInstance)
+	return ok
+}
+
+// ValidatePushMirrorAddress rejects push mirror addresses pointing at a repository of this instance,
+// which easily leads to hooks triggering each other, unless setting.Mirror.AllowSameInstancePush is enabled
+func ValidatePushMirrorAddress(addr string) error {
+	if setting.Mirror.AllowSameInstancePush {
+		return nil
+	}
+	remoteAddr, err := parseRemoteAddr(addr)
+	if err != nil {
+		// invalid addresses are rejected by git
+		return nil
+	}
+	if parts := localRepoPathFromRemote(remoteAddr); parts != nil {
+		return ErrPushMirrorSameInstance{Repo: strings.Join(parts, "/")}
+	}
+	return nil
+}
+
+// scpSyntax matches the scp-like syntax of ssh addresses, e.g. git@try.gitea.io:owner/repo.git
+var scpSyntax = regexp.MustCompile(`^(?:([a-zA-Z0-9._-]+)@)?([a-zA-Z0-9._-]+):(.*)$`)
+
+// parseRemoteAddr parses a remote address like git, which also accepts the scp-like syntax of ssh addresses
+func parseRemoteAddr(addr string) (*url.URL, error) {
+	if !strings.Contains(addr, "://") {
+		if m := scpSyntax.FindStringSubmatch(addr); m != nil {
+			u := &url.URL{Scheme: "ssh", Host: m[2], Path: "/" + strings.TrimPrefix(m[3], "/")}
+			if m[1] != "" {
+				u.User = url.User(m[1])
+			}
+			return u, nil
+		}
+	}
+	return url.Parse(addr)
+}
+
+// remoteAddress returns the address of the remote of the repository, including addresses in the scp-like syntax
+func remoteAddress(ctx context.Context, repoPath, remoteName string) (*url.URL, error) {
+	stdout, err := git.NewCommand(ctx, "config", "--get", "remote."+remoteName+".url").RunInDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseRemoteAddr(strings.TrimSpace(stdout))
+}
+
+// urlPort returns the port of the URL, or the default port of its scheme
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	case "ssh", "git+ssh", "ssh+git":
+		return "22"
+	}
+	return ""
+}
+
+// localRepoPathFromRemote returns the owner and name of the repository on this instance a remote address points to,
+// either by its clone URL, its ssh address or by its path on disk. It returns nil if the remote is not on this instance.
+func localRepoPathFromRemote(remoteAddr *url.URL) []string {
+	var repoPath string
+	switch strings.ToLower(remoteAddr.Scheme) {
+	case "", "file":
+		rel, err := filepath.Rel(setting.RepoRootPath, remoteAddr.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil
+		}
+		repoPath = filepath.ToSlash(rel)
+	case "http", "https":
+		appURL, err := url.Parse(setting.AppURL)
+		if err != nil || !strings.EqualFold(remoteAddr.Scheme, appURL.Scheme) ||
+			!strings.EqualFold(remoteAddr.Hostname(), appURL.Hostname()) || urlPort(remoteAddr) != urlPort(appURL) {
+			return nil
+		}
+		subPath := strings.TrimSuffix(appURL.Path, "/") + "/"
+		if !strings.HasPrefix(remoteAddr.Path, subPath) {
+			return nil
+		}
+		repoPath = strings.TrimPrefix(remoteAddr.Path, subPath)
+	case "ssh", "git+ssh", "ssh+git":
+		if setting.SSH.Disabled || !strings.EqualFold(remoteAddr.Hostname(), setting.SSH.Domain) ||
+			urlPort(remoteAddr) != strconv.Itoa(setting.SSH.Port) {
+			return nil
+		}
+		repoPath = remoteAddr.Path
+	default:
+		return nil
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git"), "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	return parts
+}
+
+// localRepoFromRemote returns the repository on this instance a remote address points to, either by its
+// clone URL or by its path on disk. It returns nil if the remote is not a repository of this instance.
+func localRepoFromRemote(remoteAddr *url.URL) *repo_model.Repository {
+	parts := localRepoPathFromRemote(remoteAddr)
+	if parts == nil {
+		return nil
+	}
+	repo, err := repo_model.GetRepositoryByOwnerAndName(parts[0], parts[1])
+	if err != nil {
+		return nil
+	}
+	return repo
+}
+
+// pushMirrorSourcesConfigKey is the git config key the repositories whose push mirrors pushed to a repository are stored under
+const pushMirrorSourcesConfigKey = "gitea.pushmirrorsources"
+
+// pushMirrorSources returns the URLs of the repositories, possibly on other instances, whose push mirrors pushed to the
+// repository, as received with the last push which had the private.GitPushOptionPushMirror push option
+func pushMirrorSources(ctx context.Context, repoPath string) []string {
+	stdout, err := git.NewCommand(ctx, "config", "--get", pushMirrorSourcesConfigKey).RunInDir(repoPath)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(stdout)
+}
+
+// RecordPushMirrorSources stores the value of the private.GitPushOptionPushMirror push option of a push to the repository,
+// the push mirrors of the repository refuse to push back to any of the listed repositories
+func RecordPushMirrorSources(ctx context.Context, repo *repo_model.Repository, sources string) error {
+	fields := strings.Fields(sources)
+	if len(fields) == 0 {
+		return nil
+	}
+	_, err := git.NewCommand(ctx, "config", pushMirrorSourcesConfigKey, strings.Join(fields, " ")).RunInDir(repo.RepoPath())
+	return err
+}
+
+// pushMirrorOption returns the private.GitPushOptionPushMirror push option the push mirrors of the repository send,
+// the sources of the repository followed by the repository itself
+func pushMirrorOption(ctx context.Context, repo *repo_model.Repository) string {
+	sources := append(pushMirrorSources(ctx, repo.RepoPath()), repo.HTMLURL())
+	return private.GitPushOptionPushMirror + "=" + strings.Join(sources, " ")
+}
+
+// isSameRemoteRepo checks if the remote address points to the repository of the URL, regardless of the protocol
+func isSameRemoteRepo(repoURL string, remoteAddr *url.URL) bool {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	repoPath := func(p string) string {
+		return strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	}
+	return strings.EqualFold(u.Hostname(), remoteAddr.Hostname()) && strings.EqualFold(repoPath(u.Path), repoPath(remoteAddr.Path))
+}
+
+// checkPushMirrorLoop returns an ErrPushMirrorLoop if the target of the push mirror is one of the repositories whose
+// push mirrors pushed to the repository of the push mirror. It also follows the push mirrors of the repositories on
+// this instance, starting at the target of the push mirror, and checks if they lead back to the repository.
+func checkPushMirrorLoop(ctx context.Context, m *repo_model.PushMirror, remoteAddr *url.URL) error {
+	sources := pushMirrorSources(ctx, m.Repo.RepoPath())
+	for i, source := range sources {
+		if isSameRemoteRepo(source, remoteAddr) {
+			chain := make([]string, 0, len(sources)-i+2)
+			chain = append(chain, sources[i:]...)
+			return ErrPushMirrorLoop{Repos: append(chain, m.Repo.HTMLURL(), source)}
+		}
+	}
+
+	target := localRepoFromRemote(remoteAddr)
+	if target == nil {
+		return nil
+	}
+
+	type step struct {
+		repo  *repo_model.Repository
+		chain []string
+	}
+	visited := map[int64]bool{}
+	queue := []step{{repo: target, chain: []string{m.Repo.FullName(), target.FullName()}}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.repo.ID == m.RepoID {
+			return ErrPushMirrorLoop{Repos: current.chain}
+		}
+		if visited[current.repo.ID] {
+			continue
+		}
+		visited[current.repo.ID] = true
+
+		pushMirrors, err := repo_model.GetPushMirrorsByRepoID(current.repo.ID)
+		if err != nil {
+			return fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+		}
+		for _, pm := range pushMirrors {
+			// neither bundles nor LFS objects update the refs of the remote
+			if pm.IsBundle || pm.LFSOnly {
+				continue
+			}
+			addr, err := remoteAddress(ctx, current.repo.RepoPath(), pm.RemoteName)
+			if err != nil {
+				log.Warn("Unable to get the remote address of push mirror[%d] of %s: %v", pm.ID, current.repo.FullName(), err)
+				continue
+			}
+			if next := localRepoFromRemote(addr); next != nil {
+				chain := make([]string, len(current.chain), len(current.chain)+1)
+				copy(chain, current.chain)
+				queue = append(queue, step{repo: next, chain: append(chain, next.FullName())})
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/webhook"
+)
+
+// pushMirrorNotifyWebhookURL returns the chat webhook notified about the push mirror, the one of the
+// push mirror takes precedence over setting.Mirror.PushNotifyWebhookURL
+func pushMirrorNotifyWebhookURL(m *repo_model.PushMirror) string {
+	if m.NotifyWebhookURL != "" {
+		return m.NotifyWebhookURL
+	}
+	return setting.Mirror.PushNotifyWebhookURL
+}
+
+// ValidateNotifyWebhookURL checks if the chat webhook of a push mirror is a HTTP(S) URL
+func ValidateNotifyWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not a HTTP(S) URL", webhookURL)
+	}
+	return nil
+}
+
+// notifyPushMirrorSync notifies the chat webhook of the push mirror if it started failing or recovered,
+// previousError is the error of the previous sync
+func notifyPushMirrorSync(ctx context.Context, m *repo_model.PushMirror, previousError string) {
+	webhookURL := pushMirrorNotifyWebhookURL(m)
+	if webhookURL == "" || (m.LastError == "") == (previousError == "") {
+		return
+	}
+
+	remote, lastError := m.RemoteName, m.LastError
+	if remoteAddr, err := git.GetRemoteAddress(ctx, m.Repo.RepoPath(), m.RemoteName); err == nil {
+		lastError = util.NewURLSanitizer(remoteAddr, true).Replace(lastError)
+		addr := *remoteAddr
+		addr.User = nil
+		remote = addr.String()
+	}
+
+	var text string
+	if lastError != "" {
+		text = fmt.Sprintf("Push mirror of %s to %s failed: %s", m.Repo.FullName(), remote, lastError)
+	} else {
+		text = fmt.Sprintf("Push mirror of %s to %s recovered", m.Repo.FullName(), remote)
+	}
+	if err := sendPushMirrorNotification(ctx, webhookURL, text); err != nil {
+		log.Error("Unable to notify the chat webhook of push mirror[%d] of %s: %v", m.ID, m.Repo.FullName(), err)
+	}
+}
+
+// sendPushMirrorNotification posts text to a Slack or Discord compatible webhook
+func sendPushMirrorNotification(ctx context.Context, webhookURL, text string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(pushMirrorNotificationPayload(u, text))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhook.HTTPClient().Do(req)
+	if err != nil {
+		// the path of chat webhooks contains their secret
+		return util.NewSanitizedError(err, webhookURL, u.Scheme+"://"+u.Host)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// pushMirrorNotificationPayload returns the Discord payload for Discord webhooks and the Slack payload otherwise
+func pushMirrorNotificationPayload(webhookURL *url.URL, text string) map[string]string {
+	if host := strings.ToLower(webhookURL.Hostname()); host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+		return map[string]string{"content": text}
+	}
+	return map[string]string{"text": text}
+}
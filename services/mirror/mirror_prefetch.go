@@ -0,0 +1,97 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/repository"
+
+	"xorm.io/builder"
+)
+
+// prefetchMirrorRepository is replaced in tests to observe the prefetches
+var prefetchMirrorRepository = runPrefetch
+
+// PrefetchMirrors runs `git maintenance run --task=prefetch` in the repositories of the pull mirrors whose size
+// is at least minSize bytes, so most objects have been fetched already when the mirrors are synced.
+// Mirrors which also have push mirrors are skipped, the refs/prefetch/ refs would be pushed to them.
+func PrefetchMirrors(ctx context.Context, minSize int64, timeout time.Duration) error {
+	if IsMaintenanceMode() {
+		log.Trace("PrefetchMirrors: Skipping, mirrors are in maintenance mode")
+		return nil
+	}
+	if err := git.CheckGitVersionAtLeast("2.30"); err != nil {
+		return fmt.Errorf("prefetching mirrors needs git 2.30 or later: %v", err)
+	}
+
+	log.Trace("Doing: PrefetchMirrors")
+	if err := db.Iterate(
+		ctx,
+		new(repo_model.Repository),
+		builder.Eq{"is_mirror": true}.And(builder.Gte{"size": minSize}),
+		func(idx int, bean interface{}) error {
+			repo := bean.(*repo_model.Repository)
+			select {
+			case <-ctx.Done():
+				return db.ErrCancelledf("before prefetching mirror %s", repo.FullName())
+			default:
+			}
+
+			pushMirrors, err := repo_model.GetPushMirrorsByRepoID(repo.ID)
+			if err != nil {
+				return fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+			}
+			if len(pushMirrors) > 0 {
+				log.Trace("PrefetchMirrors [repo: %-v]: Skipping, the repository has push mirrors", repo)
+				return nil
+			}
+
+			if !repository.StartRepoSync(repo.ID) {
+				log.Trace("PrefetchMirrors [repo: %-v]: Skipping, a migration or mirror sync of the repository is running", repo)
+				return nil
+			}
+			defer repository.StopRepoSync(repo.ID)
+
+			// a failed prefetch only makes the next sync slower, so the other mirrors are prefetched nevertheless
+			if err := prefetchMirrorRepository(ctx, repo, timeout); err != nil {
+				log.Error("PrefetchMirrors [repo: %-v]: %v", repo, err)
+			}
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	log.Trace("Finished: PrefetchMirrors")
+	return nil
+}
+
+// runPrefetch runs `git maintenance run --task=prefetch` in the repository within the timeout
+func runPrefetch(ctx context.Context, repo *repo_model.Repository, timeout time.Duration) error {
+	log.Trace("Running git maintenance run --task=prefetch on mirror repository %-v", repo)
+	command := git.NewCommand(ctx, "maintenance", "run", "--task=prefetch").
+		SetDescription(fmt.Sprintf("Mirror Prefetch: %s", repo.FullName()))
+
+	var stdout string
+	var err error
+	if timeout > 0 {
+		var stdoutBytes []byte
+		stdoutBytes, err = command.RunInDirTimeout(timeout, repo.RepoPath())
+		stdout = string(stdoutBytes)
+	} else {
+		stdout, err = command.RunInDir(repo.RepoPath())
+	}
+	if err != nil {
+		return fmt.Errorf("git maintenance run --task=prefetch: %v, stdout: %s", err, stdout)
+	}
+	return nil
+}
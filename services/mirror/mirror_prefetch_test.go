@@ -0,0 +1,79 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetchMirrors(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+	defer func() {
+		prefetchMirrorRepository = runPrefetch
+	}()
+
+	var prefetched []int64
+	prefetchMirrorRepository = func(ctx context.Context, repo *repo_model.Repository, timeout time.Duration) error {
+		prefetched = append(prefetched, repo.ID)
+		return nil
+	}
+
+	_, err := db.GetEngine(db.DefaultContext).ID(4).Cols("is_mirror", "size").Update(&repo_model.Repository{IsMirror: true, Size: 2048})
+	assert.NoError(t, err)
+	_, err = db.GetEngine(db.DefaultContext).ID(10).Cols("is_mirror", "size").Update(&repo_model.Repository{IsMirror: true, Size: 4096})
+	assert.NoError(t, err)
+	_, err = db.GetEngine(db.DefaultContext).ID(3).Cols("is_mirror").Update(&repo_model.Repository{IsMirror: true})
+	assert.NoError(t, err)
+	// the prefetched refs would be pushed to the push mirrors
+	assert.NoError(t, repo_model.InsertPushMirror(&repo_model.PushMirror{RepoID: 10, RemoteName: "prefetch", Interval: time.Hour}))
+
+	assert.NoError(t, PrefetchMirrors(git.DefaultContext, 0, time.Minute))
+	assert.Contains(t, prefetched, int64(3))
+	assert.Contains(t, prefetched, int64(4))
+	assert.NotContains(t, prefetched, int64(10))
+	// repositories which aren't mirrors are not prefetched
+	assert.NotContains(t, prefetched, int64(1))
+
+	// only the mirrors of at least the minimum size are prefetched
+	prefetched = nil
+	assert.NoError(t, PrefetchMirrors(git.DefaultContext, 1024, time.Minute))
+	assert.Equal(t, []int64{4}, prefetched)
+
+	// nothing is prefetched while mirrors are in maintenance mode
+	prefetched = nil
+	SetMaintenanceMode(true)
+	defer SetMaintenanceMode(false)
+	assert.NoError(t, PrefetchMirrors(git.DefaultContext, 0, time.Minute))
+	assert.Empty(t, prefetched)
+}
+
+func TestRunPrefetch(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	sourcePath := t.TempDir()
+	assert.NoError(t, git.Clone(git.DefaultContext, repo.RepoPath(), sourcePath, git.CloneRepoOptions{Bare: true, Mirror: true}))
+	_, err := git.NewCommand(git.DefaultContext, "branch", "prefetched", "master").RunInDir(sourcePath)
+	assert.NoError(t, err)
+	_, err = git.NewCommand(git.DefaultContext, "remote", "add", "--mirror=fetch", "origin", sourcePath).RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+
+	assert.NoError(t, runPrefetch(git.DefaultContext, repo, time.Minute))
+
+	// the objects are fetched without touching the refs of the mirror
+	_, err = git.NewCommand(git.DefaultContext, "rev-parse", "--verify", "refs/prefetch/heads/prefetched").RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+	_, err = git.NewCommand(git.DefaultContext, "rev-parse", "--verify", "refs/heads/prefetched").RunInDir(repo.RepoPath())
+	assert.Error(t, err)
+}
@@ -278,8 +278,10 @@ func runSync(ctx context.Context, m *repo_model.Mirror) ([]*mirrorSyncResult, bo
 	if m.LFS && setting.LFS.StartServer {
 		log.Trace("SyncMirrors [repo: %-v]: syncing LFS objects...", m.Repo)
 		endpoint := lfs.DetermineEndpoint(remoteAddr.String(), m.LFSEndpoint)
-		lfsClient := lfs.NewClient(endpoint, nil)
-		if err = repo_module.StoreMissingLfsObjectsInRepository(ctx, m.Repo, gitRepo, lfsClient); err != nil {
+		transport, err := lfs.NewHTTPTransport(setting.LFSClient.CAFile)
+		if err != nil {
+			log.Error("SyncMirrors [repo: %-v]: failed to create the LFS transport: %v", m.Repo, err)
+		} else if err = repo_module.StoreMissingLfsObjectsInRepository(ctx, m.Repo, gitRepo, lfs.NewClient(endpoint, transport), setting.LFS.MaxFileSize); err != nil {
 			log.Error("SyncMirrors [repo: %-v]: failed to synchronize LFS objects for repository: %v", m.Repo, err)
 		}
 	}
@@ -387,6 +389,11 @@ func SyncPullMirror(ctx context.Context, repoID int64) bool {
 		log.Error("PANIC whilst SyncMirrors[repo_id: %d] Panic: %v\nStacktrace: %s", repoID, err, log.Stack(2))
 	}()
 
+	if IsMaintenanceMode() {
+		log.Trace("SyncMirrors [repo_id: %v]: Skipping, mirrors are in maintenance mode", repoID)
+		return false
+	}
+
 	m, err := repo_model.GetMirrorByRepoID(repoID)
 	if err != nil {
 		log.Error("SyncMirrors [repo_id: %v]: unable to GetMirrorByRepoID: %v", repoID, err)
@@ -402,6 +409,8 @@ func SyncPullMirror(ctx context.Context, repoID int64) bool {
 		return false
 	}
 
+	m.SyncsSinceGC = autoGCMirror(ctx, m.Repo, m.SyncsSinceGC)
+
 	log.Trace("SyncMirrors [repo: %-v]: Scheduling next update", m.Repo)
 	m.ScheduleNextUpdate()
 	if err = repo_model.UpdateMirror(m); err != nil {
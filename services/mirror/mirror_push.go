@@ -5,15 +5,27 @@
 package mirror
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/git/pipeline"
 	"code.gitea.io/gitea/modules/lfs"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/process"
@@ -21,33 +33,169 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
 )
 
 var stripExitStatus = regexp.MustCompile(`exit status \d+ - `)
 
+// pushedRefPattern matches the lines of the git push output listing new, updated and deleted refs, e.g.
+// " * [new branch]      master -> master", " + 1a2b3c4...5d6e7f8 master -> master (forced update)" or " - [deleted]         old"
+var pushedRefPattern = regexp.MustCompile(`^ [ +*-] (?:\[[^\]]+\]|[0-9a-f]+\.\.\.?[0-9a-f]+)\s+(?:\S+ -> )?(\S+)`)
+
+// gitPush pushes to the push mirror remotes, it is replaced in tests
+var gitPush = git.Push
+
+// ValidatePushMirrorTimeout checks if the timeout of a push mirror is within setting.Mirror.MaxPushTimeout,
+// 0 uses the global mirror timeout
+func ValidatePushMirrorTimeout(timeoutSeconds int64) error {
+	if timeoutSeconds < 0 || timeoutSeconds > setting.Mirror.MaxPushTimeout {
+		return fmt.Errorf("timeout %ds must be between 0 and %ds", timeoutSeconds, setting.Mirror.MaxPushTimeout)
+	}
+	return nil
+}
+
+// pushMirrorTimeout returns the timeout of the git and LFS operations of a push mirror sync
+func pushMirrorTimeout(m *repo_model.PushMirror) time.Duration {
+	if m.TimeoutSeconds > 0 {
+		return time.Duration(m.TimeoutSeconds) * time.Second
+	}
+	return time.Duration(setting.Git.Timeout.Mirror) * time.Second
+}
+
+// ValidateTagFilter checks if the tag filter of a push mirror is a valid glob
+func ValidateTagFilter(filter string) error {
+	_, err := path.Match(filter, "")
+	return err
+}
+
+// ValidateLFSOnly checks if a push mirror may only push LFS objects
+func ValidateLFSOnly(m *repo_model.PushMirror) error {
+	if !m.LFSOnly {
+		return nil
+	}
+	if m.SkipLFS {
+		return errors.New("LFS-only push mirrors cannot skip the LFS objects")
+	}
+	if !setting.LFS.StartServer {
+		return errors.New("LFS is disabled")
+	}
+	if m.IsBundle {
+		return errors.New("bundle push mirrors cannot push LFS objects")
+	}
+	return nil
+}
+
+// RefMapping maps a local ref to a differently named ref of the push mirror remote
+type RefMapping struct {
+	Local  string
+	Remote string
+}
+
+// expandRefName returns the full name of a ref, names without "refs/" are branches
+func expandRefName(name string) string {
+	if strings.HasPrefix(name, "refs/") {
+		return name
+	}
+	return git.BranchPrefix + name
+}
+
+// ParseRefMapping parses the "<local>:<remote>" lines of PushMirror.RefMapping, e.g. "develop:main".
+// Both sides must be valid ref names without wildcards, names without "refs/" are expanded to branches.
+func ParseRefMapping(mapping string) ([]RefMapping, error) {
+	var mappings []RefMapping
+	remotes := make(map[string]bool)
+	for _, line := range strings.Split(mapping, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q is not a <local>:<remote> mapping", line)
+		}
+		for _, name := range fields {
+			if name == "" || validation.GitRefNamePatternInvalid.MatchString(name) || !validation.CheckGitRefAdditionalRulesValid(name) {
+				return nil, fmt.Errorf("%q is not a valid ref name", name)
+			}
+		}
+		local, remote := expandRefName(fields[0]), expandRefName(fields[1])
+		if remotes[remote] {
+			return nil, fmt.Errorf("%s is mapped more than once", remote)
+		}
+		remotes[remote] = true
+		mappings = append(mappings, RefMapping{Local: local, Remote: remote})
+	}
+	return mappings, nil
+}
+
+// ValidateRefMapping checks if the ref mapping of a push mirror is valid and returns the parsed mappings
+func ValidateRefMapping(m *repo_model.PushMirror) ([]RefMapping, error) {
+	mappings, err := ParseRefMapping(m.RefMapping)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) > 0 && (m.IsBundle || m.LFSOnly) {
+		return nil, errors.New("only push mirrors which push refs can map them")
+	}
+	return mappings, nil
+}
+
 // AddPushMirrorRemote registers the push mirror remote.
 func AddPushMirrorRemote(ctx context.Context, m *repo_model.PushMirror, addr string) error {
-	addRemoteAndConfig := func(addr, path string) error {
-		if _, err := git.NewCommand(ctx, "remote", "add", "--mirror=push", m.RemoteName, addr).RunInDir(path); err != nil {
-			return err
+	if err := ValidatePushMirrorAddress(addr); err != nil {
+		return err
+	}
+	if err := ValidateTagFilter(m.TagFilter); err != nil {
+		return fmt.Errorf("invalid tag filter %q: %v", m.TagFilter, err)
+	}
+	if err := ValidateLFSOnly(m); err != nil {
+		return fmt.Errorf("invalid LFS-only push mirror: %v", err)
+	}
+	mappings, err := ValidateRefMapping(m)
+	if err != nil {
+		return fmt.Errorf("invalid ref mapping: %v", err)
+	}
+
+	tagRefspec := "+refs/tags/*:refs/tags/*"
+	if m.TagFilter != "" {
+		// refspecs only support a single "*", other patterns are expanded when pushing
+		tagRefspec = ""
+		if !strings.ContainsAny(m.TagFilter, "?[\\") && strings.Count(m.TagFilter, "*") <= 1 {
+			tagRefspec = "+refs/tags/" + m.TagFilter + ":refs/tags/" + m.TagFilter
 		}
-		if _, err := git.NewCommand(ctx, "config", "--add", "remote."+m.RemoteName+".push", "+refs/heads/*:refs/heads/*").RunInDir(path); err != nil {
-			return err
+	}
+
+	addRemoteAndConfig := func(addr, path string, mappings []RefMapping) error {
+		cmd := git.NewCommand(ctx, "remote", "add")
+		if m.TagFilter == "" && len(mappings) == 0 {
+			// a mirror remote always pushes all refs
+			cmd.AddArguments("--mirror=push")
 		}
-		if _, err := git.NewCommand(ctx, "config", "--add", "remote."+m.RemoteName+".push", "+refs/tags/*:refs/tags/*").RunInDir(path); err != nil {
+		if _, err := cmd.AddArguments(m.RemoteName, addr).RunInDir(path); err != nil {
 			return err
 		}
+		for _, refspec := range branchRefspecs(mappings) {
+			if _, err := git.NewCommand(ctx, "config", "--add", "remote."+m.RemoteName+".push", refspec).RunInDir(path); err != nil {
+				return err
+			}
+		}
+		if tagRefspec != "" {
+			if _, err := git.NewCommand(ctx, "config", "--add", "remote."+m.RemoteName+".push", tagRefspec).RunInDir(path); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
-	if err := addRemoteAndConfig(addr, m.Repo.RepoPath()); err != nil {
+	if err := addRemoteAndConfig(addr, m.Repo.RepoPath(), mappings); err != nil {
 		return err
 	}
 
 	if m.Repo.HasWiki() {
 		wikiRemoteURL := repository.WikiRemoteURL(ctx, addr)
 		if len(wikiRemoteURL) > 0 {
-			if err := addRemoteAndConfig(wikiRemoteURL, m.Repo.WikiPath()); err != nil {
+			// the ref mapping only applies to the branches of the repository
+			if err := addRemoteAndConfig(wikiRemoteURL, m.Repo.WikiPath(), nil); err != nil {
 				return err
 			}
 		}
@@ -74,6 +222,87 @@ func RemovePushMirrorRemote(ctx context.Context, m *repo_model.PushMirror) error
 	return nil
 }
 
+// UpdatePushMirrorCredentialsByHost replaces the credentials of all push mirrors whose remote
+// is a HTTP(S) URL of the given host, e.g. after a shared token has been rotated.
+func UpdatePushMirrorCredentialsByHost(ctx context.Context, host, username, password string) error {
+	updated := 0
+	for page := 1; ; page++ {
+		mirrors, err := repo_model.FindPushMirrors(db.ListOptions{Page: page, PageSize: 50})
+		if err != nil {
+			return fmt.Errorf("FindPushMirrors: %v", err)
+		}
+		for _, m := range mirrors {
+			ok, err := updatePushMirrorCredentials(ctx, m, host, username, password)
+			if err != nil {
+				return fmt.Errorf("push mirror[%d]: %v", m.ID, err)
+			}
+			if ok {
+				updated++
+			}
+		}
+		if len(mirrors) < 50 {
+			break
+		}
+	}
+	log.Info("Updated the credentials of %d push mirrors of host %s", updated, host)
+	return nil
+}
+
+func updatePushMirrorCredentials(ctx context.Context, m *repo_model.PushMirror, host, username, password string) (bool, error) {
+	if m.IsBundle {
+		return false, nil
+	}
+	if m.Repo == nil {
+		var err error
+		if m.Repo, err = repo_model.GetRepositoryByID(m.RepoID); repo_model.IsErrRepoNotExist(err) {
+			log.Warn("Skipping push mirror[%d]: %v", m.ID, repo_model.ErrPushMirrorRepoNotExist)
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	remoteAddr, err := git.GetRemoteAddress(ctx, m.Repo.RepoPath(), m.RemoteName)
+	if err != nil {
+		log.Warn("Skipping push mirror[%d] of %s, its remote %s can't be read: %v", m.ID, m.Repo.FullName(), m.RemoteName, err)
+		return false, nil
+	}
+	if !isHTTPRemoteOfHost(remoteAddr, host) {
+		return false, nil
+	}
+
+	setURL := func(path string, addr *url.URL) error {
+		u := *addr
+		u.User = nil
+		if len(username) > 0 || len(password) > 0 {
+			u.User = url.UserPassword(username, password)
+		}
+		_, err := git.NewCommand(ctx, "remote", "set-url", m.RemoteName, u.String()).RunInDir(path)
+		return err
+	}
+	if err := setURL(m.Repo.RepoPath(), remoteAddr); err != nil {
+		return false, err
+	}
+	if m.Repo.HasWiki() {
+		// the wiki remote only exists if the remote wiki could be found when the push mirror was added
+		if wikiAddr, err := git.GetRemoteAddress(ctx, m.Repo.WikiPath(), m.RemoteName); err == nil {
+			if err := setURL(m.Repo.WikiPath(), wikiAddr); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// isHTTPRemoteOfHost checks if the remote is a HTTP(S) URL of the host. The host matches
+// with or without a port and, for IPv6 addresses, with or without brackets.
+func isHTTPRemoteOfHost(remoteAddr *url.URL, host string) bool {
+	if !strings.EqualFold(remoteAddr.Scheme, "http") && !strings.EqualFold(remoteAddr.Scheme, "https") {
+		return false
+	}
+	return strings.EqualFold(remoteAddr.Host, host) || strings.EqualFold(remoteAddr.Hostname(), strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+}
+
 // SyncPushMirror starts the sync of the push mirror and schedules the next run.
 func SyncPushMirror(ctx context.Context, mirrorID int64) bool {
 	log.Trace("SyncPushMirror [mirror: %d]", mirrorID)
@@ -86,22 +315,51 @@ func SyncPushMirror(ctx context.Context, mirrorID int64) bool {
 		log.Error("PANIC whilst syncPushMirror[%d] Panic: %v\nStacktrace: %s", mirrorID, err, log.Stack(2))
 	}()
 
+	if IsMaintenanceMode() {
+		log.Trace("SyncPushMirror [mirror: %d]: Skipping, mirrors are in maintenance mode", mirrorID)
+		return false
+	}
+
 	m, err := repo_model.GetPushMirrorByID(mirrorID)
 	if err != nil {
 		log.Error("GetPushMirrorByID [%d]: %v", mirrorID, err)
 		return false
 	}
 
+	if m.Repo == nil {
+		log.Error("SyncPushMirror [mirror: %d][repo: %d]: %v", m.ID, m.RepoID, repo_model.ErrPushMirrorRepoNotExist)
+		m.LastError = repo_model.ErrPushMirrorRepoNotExist.Error()
+		m.LastUpdateUnix = timeutil.TimeStampNow()
+		if err := repo_model.UpdatePushMirror(m); err != nil {
+			log.Error("UpdatePushMirror [%d]: %v", m.ID, err)
+		}
+		return false
+	}
+
+	if !repository.StartRepoSync(m.RepoID) {
+		log.Trace("SyncPushMirror [mirror: %d][repo: %-v]: Skipping, a migration or mirror sync of the repository is already running", m.ID, m.Repo)
+		return false
+	}
+	defer repository.StopRepoSync(m.RepoID)
+
+	previousError := m.LastError
 	m.LastError = ""
 
 	ctx, _, finished := process.GetManager().AddContext(ctx, fmt.Sprintf("Syncing PushMirror %s/%s to %s", m.Repo.OwnerName, m.Repo.Name, m.RemoteName))
 	defer finished()
 
 	log.Trace("SyncPushMirror [mirror: %d][repo: %-v]: Running Sync", m.ID, m.Repo)
-	err = runPushSync(ctx, m)
+	start := time.Now()
+	changedRefs, err := runPushSync(ctx, m)
 	if err != nil {
 		log.Error("SyncPushMirror [mirror: %d][repo: %-v]: %v", m.ID, m.Repo, err)
 		m.LastError = stripExitStatus.ReplaceAllLiteralString(err.Error(), "")
+		if description := git.ClassifyGitError(err).Description(); description != "" {
+			m.LastError = description + ": " + m.LastError
+		}
+	} else {
+		m.LastChangedRefs = len(changedRefs)
+		m.SyncsSinceGC = autoGCMirror(ctx, m.GetRepository(), m.SyncsSinceGC)
 	}
 
 	m.LastUpdateUnix = timeutil.TimeStampNow()
@@ -112,14 +370,97 @@ func SyncPushMirror(ctx context.Context, mirrorID int64) bool {
 		return false
 	}
 
+	notifyPushMirrorSync(ctx, m, previousError)
+
+	if setting.Mirror.PushSyncLogLength > 0 {
+		if err := repo_model.InsertPushMirrorSyncLog(&repo_model.PushMirrorSyncLog{
+			PushMirrorID: m.ID,
+			RepoID:       m.RepoID,
+			Success:      err == nil,
+			Duration:     time.Since(start),
+			Error:        m.LastError,
+			RefsChanged:  strings.Join(changedRefs, "\n"),
+		}, setting.Mirror.PushSyncLogLength); err != nil {
+			log.Error("InsertPushMirrorSyncLog [%d]: %v", m.ID, err)
+		}
+	}
+
 	log.Trace("SyncPushMirror [mirror: %d][repo: %-v]: Finished", m.ID, m.Repo)
 
 	return err == nil
 }
 
-func runPushSync(ctx context.Context, m *repo_model.PushMirror) error {
-	timeout := time.Duration(setting.Git.Timeout.Mirror) * time.Second
+// runPushSync pushes the repository and its wiki to the push mirror remote.
+// It returns the refs of the repository which have been updated by the push.
+func runPushSync(ctx context.Context, m *repo_model.PushMirror) ([]string, error) {
+	timeout := pushMirrorTimeout(m)
+
+	if m.IsBundle {
+		previousBundleRefs := m.LastBundleRefs
+		bundlePath, err := runBundleSync(ctx, m, timeout)
+		if err != nil {
+			log.Error("Error creating bundle for push mirror[%d]: %v", m.ID, err)
+			return nil, err
+		}
+		if bundlePath == "" {
+			return nil, nil
+		}
+		log.Trace("Push mirror[%d] bundle written to %s", m.ID, bundlePath)
+
+		if m.BundleUploadURL != "" {
+			uploadCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				uploadCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			if err := uploadBundle(uploadCtx, m, bundlePath); err != nil {
+				// downstream sites can't apply later bundles without this one, so the next bundle has to contain its objects
+				m.LastBundleRefs = previousBundleRefs
+				if err := util.Remove(bundlePath); err != nil {
+					log.Error("Error removing bundle %s of push mirror[%d]: %v", bundlePath, m.ID, err)
+				}
+				log.Error("Error uploading bundle of push mirror[%d]: %v", m.ID, err)
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	if m.LFSOnly {
+		// the git data is pushed by external tooling, so the remote can't tell which pointers are new
+		if err := ValidateLFSOnly(m); err != nil {
+			return nil, fmt.Errorf("push mirror[%d]: %v", m.ID, err)
+		}
+		path := m.Repo.RepoPath()
+		remoteAddr, err := git.GetRemoteAddress(ctx, path, m.RemoteName)
+		if err != nil {
+			log.Error("GetRemoteAddress(%s) Error %v", path, err)
+			return nil, errors.New("Unexpected error")
+		}
+		if providedAddr, err := remoteAddrWithProvidedCredentials(ctx, remoteAddr); err != nil {
+			return nil, util.NewURLSanitizedError(err, remoteAddr, true)
+		} else if providedAddr != nil {
+			remoteAddr = providedAddr
+		}
+		return nil, pushMirrorLFSObjects(ctx, m, path, remoteAddr, false)
+	}
+
+	remoteAddr, err := git.GetRemoteAddress(ctx, m.Repo.RepoPath(), m.RemoteName)
+	if err != nil {
+		log.Error("GetRemoteAddress(%s) Error %v", m.Repo.RepoPath(), err)
+		return nil, errors.New("Unexpected error")
+	}
+	if err := checkPushMirrorLoop(ctx, m, remoteAddr); err != nil {
+		log.Error("Not syncing push mirror[%d] of %s: %v", m.ID, m.Repo.FullName(), err)
+		return nil, err
+	}
+	mappings, err := ValidateRefMapping(m)
+	if err != nil {
+		return nil, fmt.Errorf("push mirror[%d]: invalid ref mapping: %v", m.ID, err)
+	}
 
+	var pushOutput strings.Builder
 	performPush := func(path string) error {
 		remoteAddr, err := git.GetRemoteAddress(ctx, path, m.RemoteName)
 		if err != nil {
@@ -127,31 +468,88 @@ func runPushSync(ctx context.Context, m *repo_model.PushMirror) error {
 			return errors.New("Unexpected error")
 		}
 
-		if setting.LFS.StartServer {
-			log.Trace("SyncMirrors [repo: %-v]: syncing LFS objects...", m.Repo)
+		// the credentials generated by a credential provider are passed to git in the environment
+		var config, env []string
+		if providedAddr, err := remoteAddrWithProvidedCredentials(ctx, remoteAddr); err != nil {
+			log.Error("Unable to get the credentials of %s mirror[%d]: %v", path, m.ID, err)
+			return util.NewURLSanitizedError(err, remoteAddr, true)
+		} else if providedAddr != nil {
+			remoteAddr = providedAddr
+			config, env = providedCredentialsConfig(providedAddr)
+		}
+
+		// nothing, not even the LFS objects, is pushed to a remote which is refused
+		if setting.Mirror.CheckUnrelatedPushRemote && !m.AllowUnrelatedHistory {
+			if err := checkPushRemoteRelated(ctx, path, m.RemoteName, config, env, timeout); err != nil {
+				log.Error("Push mirror[%d] remote %s of %s: %v", m.ID, m.RemoteName, path, err)
+				return util.NewURLSanitizedError(err, remoteAddr, true)
+			}
+		}
 
-			gitRepo, err := git.OpenRepositoryCtx(ctx, path)
+		syncLFS, pendingOnly := setting.LFS.StartServer && !m.SkipLFS, false
+		if syncLFS {
+			hasPointers, err := pushContainsLFSPointers(ctx, path, m.RemoteName, config, env, timeout)
 			if err != nil {
-				log.Error("OpenRepository: %v", err)
-				return errors.New("Unexpected error")
+				log.Warn("Unable to check for new LFS pointers of %s mirror[%d]: %v", path, m.ID, err)
+			} else if !hasPointers && (path != m.Repo.RepoPath() || m.PendingLFSObjects == "") {
+				log.Trace("SyncMirrors [repo: %-v]: no new LFS pointers, skipping LFS sync", m.Repo)
+				syncLFS = false
+			} else if !hasPointers {
+				// only the objects which failed to upload during the last sync are missing
+				pendingOnly = true
 			}
-			defer gitRepo.Close()
+		}
 
-			endpoint := lfs.DetermineEndpoint(remoteAddr.String(), "")
-			lfsClient := lfs.NewClient(endpoint, nil)
-			if err := pushAllLFSObjects(ctx, gitRepo, lfsClient); err != nil {
-				return util.NewURLSanitizedError(err, remoteAddr, true)
+		if syncLFS {
+			if err := pushMirrorLFSObjects(ctx, m, path, remoteAddr, pendingOnly); err != nil {
+				return err
 			}
 		}
 
 		log.Trace("Pushing %s mirror[%d] remote %s", path, m.ID, m.RemoteName)
 
-		if err := git.Push(ctx, path, git.PushOptions{
+		pushOutput.Reset()
+		pushOpts := git.PushOptions{
 			Remote:  m.RemoteName,
 			Force:   true,
 			Mirror:  true,
+			Atomic:  setting.Mirror.PushAtomic,
+			Options: []string{pushMirrorOption(ctx, m.Repo)},
+			Config:  config,
+			Env:     env,
 			Timeout: timeout,
-		}); err != nil {
+			Stderr:  &pushOutput,
+		}
+		var pathMappings []RefMapping
+		if path == m.Repo.RepoPath() {
+			pathMappings = mappings
+		}
+		if m.TagFilter != "" || len(pathMappings) > 0 {
+			refspecs, err := pushMirrorRefspecs(ctx, path, m.TagFilter, pathMappings)
+			if err != nil {
+				log.Error("Error listing tags of %s for push mirror[%d]: %v", path, m.ID, err)
+				return err
+			}
+			pushOpts.Mirror = false
+			pushOpts.Prune = true
+			pushOpts.Refspecs = refspecs
+		}
+
+		err = gitPush(ctx, path, pushOpts)
+		if git.IsErrPushOptionsNotSupported(err) {
+			// only remotes on instances which read the push option could detect a loop anyway
+			log.Trace("Push mirror[%d] remote %s of %s doesn't support push options, pushing without", m.ID, m.RemoteName, path)
+			pushOutput.Reset()
+			pushOpts.Options = nil
+			err = gitPush(ctx, path, pushOpts)
+		}
+		if git.IsErrPushAtomicNotSupported(err) {
+			log.Warn("Push mirror[%d] remote %s of %s doesn't support atomic pushes, pushing non-atomically", m.ID, m.RemoteName, path)
+			pushOutput.Reset()
+			pushOpts.Atomic = false
+			err = gitPush(ctx, path, pushOpts)
+		}
+		if err != nil {
 			log.Error("Error pushing %s mirror[%d] remote %s: %v", path, m.ID, m.RemoteName, err)
 
 			return util.NewURLSanitizedError(err, remoteAddr, true)
@@ -160,10 +558,10 @@ func runPushSync(ctx context.Context, m *repo_model.PushMirror) error {
 		return nil
 	}
 
-	err := performPush(m.Repo.RepoPath())
-	if err != nil {
-		return err
+	if err := performPush(m.Repo.RepoPath()); err != nil {
+		return nil, err
 	}
+	changedRefs := parsePushedRefs(pushOutput.String())
 
 	if m.Repo.HasWiki() {
 		wikiPath := m.Repo.WikiPath()
@@ -171,25 +569,384 @@ func runPushSync(ctx context.Context, m *repo_model.PushMirror) error {
 		if err == nil {
 			err := performPush(wikiPath)
 			if err != nil {
-				return err
+				return changedRefs, err
 			}
 		} else {
 			log.Trace("Skipping wiki: No remote configured")
 		}
 	}
 
+	return changedRefs, nil
+}
+
+// pushMirrorLFSObjects uploads the LFS objects of the repository at path to the LFS server of the push mirror remote.
+// The objects of the repository which can't be uploaded are remembered in PushMirror.PendingLFSObjects, if pendingOnly
+// is set only these are uploaded again.
+func pushMirrorLFSObjects(ctx context.Context, m *repo_model.PushMirror, path string, remoteAddr *url.URL, pendingOnly bool) error {
+	log.Trace("SyncMirrors [repo: %-v]: syncing LFS objects...", m.Repo)
+
+	if m.TimeoutSeconds > 0 {
+		// the LFS transfers are only limited by the timeout of the push mirror itself
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pushMirrorTimeout(m))
+		defer cancel()
+	}
+
+	gitRepo, err := git.OpenRepositoryCtx(ctx, path)
+	if err != nil {
+		log.Error("OpenRepository: %v", err)
+		return errors.New("Unexpected error")
+	}
+	defer gitRepo.Close()
+
+	transport, err := lfs.NewHTTPTransport(setting.LFSClient.CAFile)
+	if err != nil {
+		log.Error("Unable to create the LFS transport of %s mirror[%d]: %v", path, m.ID, err)
+		return err
+	}
+	endpoint := lfs.DetermineEndpoint(remoteAddr.String(), "")
+	lfsClient := lfs.NewClient(endpoint, transport)
+
+	var failed []lfs.Pointer
+	if pendingOnly {
+		pending := parseLFSPointerList(m.PendingLFSObjects)
+		log.Trace("SyncMirrors [repo: %-v]: retrying %d LFS objects which failed to upload", m.Repo, len(pending))
+		failed, err = uploadLFSObjects(ctx, lfsClient, lfs.NewContentStore(), pending)
+		if len(failed) > 0 {
+			err = fmt.Errorf("%d LFS objects could not be uploaded: %v", len(failed), err)
+		}
+	} else {
+		failed, err = pushAllLFSObjects(ctx, gitRepo, lfsClient)
+	}
+	if path == m.Repo.RepoPath() && (err == nil || len(failed) > 0) {
+		m.PendingLFSObjects = formatLFSPointerList(failed)
+	}
+	if err != nil {
+		return util.NewURLSanitizedError(err, remoteAddr, true)
+	}
 	return nil
 }
 
-func pushAllLFSObjects(ctx context.Context, gitRepo *git.Repository, lfsClient lfs.Client) error {
-	contentStore := lfs.NewContentStore()
+// formatLFSPointerList formats pointers as "<oid> <size>" lines to be stored in PushMirror.PendingLFSObjects
+func formatLFSPointerList(pointers []lfs.Pointer) string {
+	lines := make([]string, 0, len(pointers))
+	for _, p := range pointers {
+		lines = append(lines, fmt.Sprintf("%s %d", p.Oid, p.Size))
+	}
+	return strings.Join(lines, "\n")
+}
 
-	pointerChan := make(chan lfs.PointerBlob)
-	errChan := make(chan error, 1)
-	go lfs.SearchPointerBlobs(ctx, gitRepo, pointerChan, errChan)
+// parseLFSPointerList parses the "<oid> <size>" lines written by formatLFSPointerList, invalid lines are skipped
+func parseLFSPointerList(list string) []lfs.Pointer {
+	var pointers []lfs.Pointer
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if p := (lfs.Pointer{Oid: fields[0], Size: size}); p.IsValid() {
+			pointers = append(pointers, p)
+		}
+	}
+	return pointers
+}
 
-	uploadObjects := func(pointers []lfs.Pointer) error {
-		err := lfsClient.Upload(ctx, pointers, func(p lfs.Pointer, objectError error) (io.ReadCloser, error) {
+// parsePushedRefs returns the names of the refs which have been created, updated or deleted
+// according to the output of git push
+func parsePushedRefs(output string) []string {
+	var refs []string
+	for _, line := range strings.Split(output, "\n") {
+		if matches := pushedRefPattern.FindStringSubmatch(strings.TrimRight(line, "\r")); matches != nil {
+			refs = append(refs, matches[1])
+		}
+	}
+	return refs
+}
+
+// pushMirrorRefspecs returns the refspecs pushing the mapped refs or all branches, and the tags matching the filter
+func pushMirrorRefspecs(ctx context.Context, repoPath, tagFilter string, mappings []RefMapping) ([]string, error) {
+	refspecs := branchRefspecs(mappings)
+	if tagFilter == "" {
+		return append(refspecs, "+refs/tags/*:refs/tags/*"), nil
+	}
+
+	stdout, err := git.NewCommand(ctx, "for-each-ref", "--format=%(refname:strip=2)", git.TagPrefix).RunInDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		if tag == "" {
+			continue
+		}
+		if matched, _ := path.Match(tagFilter, tag); matched {
+			refspecs = append(refspecs, "+"+git.TagPrefix+tag+":"+git.TagPrefix+tag)
+		}
+	}
+	return refspecs, nil
+}
+
+// branchRefspecs returns the refspecs pushing the mapped refs, or all branches if there is no ref mapping
+func branchRefspecs(mappings []RefMapping) []string {
+	if len(mappings) == 0 {
+		return []string{"+refs/heads/*:refs/heads/*"}
+	}
+	refspecs := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		refspecs = append(refspecs, "+"+mapping.Local+":"+mapping.Remote)
+	}
+	return refspecs
+}
+
+// pushContainsLFSPointers checks if the objects which the push to the remote transfers contain LFS pointers.
+// If the remote contains refs unknown to the repository, it is assumed that they do.
+// The config values and environment are passed to git when listing the refs of the remote.
+func pushContainsLFSPointers(ctx context.Context, repoPath, remoteName string, config, env []string, timeout time.Duration) (bool, error) {
+	args := append(configArgs(config), "ls-remote", "--heads", "--tags", remoteName)
+	stdout, err := git.NewCommand(ctx, args...).RunInDirTimeoutEnv(env, timeout, repoPath)
+	if err != nil {
+		return true, fmt.Errorf("ls-remote: %v", err)
+	}
+
+	gitRepo, err := git.OpenRepositoryCtx(ctx, repoPath)
+	if err != nil {
+		return true, fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	// everything reachable from the refs of the remote doesn't have to be pushed
+	excluded := new(bytes.Buffer)
+	for _, line := range strings.Split(string(stdout), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if !gitRepo.IsObjectExist(fields[0]) {
+			return true, nil
+		}
+		excluded.WriteString("^" + fields[0] + "\n")
+	}
+
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the objects are piped from rev-list through cat-file --batch-check to cat-file --batch like lfs.SearchPointerBlobs
+	revListReader, revListWriter := io.Pipe()
+	shasToCheckReader, shasToCheckWriter := io.Pipe()
+	catFileCheckReader, catFileCheckWriter := io.Pipe()
+	shasToBatchReader, shasToBatchWriter := io.Pipe()
+	catFileBatchReader, catFileBatchWriter := io.Pipe()
+
+	wg := sync.WaitGroup{}
+	wg.Add(5)
+	var revListErr error
+	go func() {
+		defer wg.Done()
+		stderr := new(strings.Builder)
+		if err := git.NewCommand(ctx, "rev-list", "--objects", "--all", "--stdin").RunWithContext(&git.RunContext{
+			Timeout: -1,
+			Dir:     repoPath,
+			Stdin:   excluded,
+			Stdout:  revListWriter,
+			Stderr:  stderr,
+		}); err != nil {
+			revListErr = git.ConcatenateError(err, stderr.String())
+		}
+		_ = revListWriter.CloseWithError(revListErr)
+	}()
+	go pipeline.BlobsFromRevListObjects(revListReader, shasToCheckWriter, &wg)
+	go pipeline.CatFileBatchCheck(ctx, shasToCheckReader, catFileCheckWriter, &wg, repoPath)
+	go pipeline.BlobsLessThan1024FromCatFileBatchCheck(catFileCheckReader, shasToBatchWriter, &wg)
+	go pipeline.CatFileBatch(ctx, shasToBatchReader, catFileBatchWriter, &wg, repoPath)
+
+	found, err := readContainsLFSPointer(catFileBatchReader)
+	// a pointer may be found before all objects are read, which stops the pipeline
+	_ = catFileBatchReader.Close()
+	cancel()
+	wg.Wait()
+
+	if found {
+		return true, nil
+	} else if revListErr != nil {
+		return true, fmt.Errorf("rev-list: %v", revListErr)
+	} else if err != nil {
+		return true, fmt.Errorf("cat-file --batch: %v", err)
+	}
+	return false, nil
+}
+
+// readContainsLFSPointer reads the output of cat-file --batch line by line until it finds a LFS pointer
+func readContainsLFSPointer(r io.Reader) (bool, error) {
+	rd := bufio.NewReader(r)
+	for {
+		_, _, size, err := git.ReadBatchLine(rd)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		buf := make([]byte, size+1) // content followed by a newline
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return false, err
+		}
+		if pointer, _ := lfs.ReadPointerFromBuffer(buf[:size]); pointer.IsValid() {
+			return true, nil
+		}
+	}
+}
+
+// checkPushRemoteRelated returns an error if the branches and tags of the remote have no common ancestor with the
+// branches and tags of the repository, as the forced mirror push would then overwrite unrelated history.
+// The tips of the remote are listed with the given config values and environment. Only if none of them is known
+// to the repository, the remote is fetched into a temporary repository which borrows the objects of the repository.
+func checkPushRemoteRelated(ctx context.Context, path, remoteName string, config, env []string, timeout time.Duration) error {
+	args := append(configArgs(config), "ls-remote", "--heads", "--tags", remoteName)
+	lsRemote, err := git.NewCommand(ctx, args...).RunInDirTimeoutEnv(env, timeout, path)
+	if err != nil {
+		return fmt.Errorf("ls-remote: %v", err)
+	}
+	remoteRefs := parseLsRemoteCommits(string(lsRemote))
+	if len(remoteRefs) == 0 {
+		// nothing to overwrite
+		return nil
+	}
+	unrelatedErr := fmt.Errorf("the %d refs of the remote have no common ancestor with the repository, refusing to overwrite the unrelated history", len(remoteRefs))
+
+	stdout, err := git.NewCommand(ctx, "for-each-ref", "--format=%(objecttype) %(objectname) %(*objecttype) %(*objectname)", git.BranchPrefix, git.TagPrefix).RunInDir(path)
+	if err != nil {
+		return fmt.Errorf("for-each-ref: %v", err)
+	}
+	localTips := parseRefCommits(stdout)
+	if len(localTips) == 0 {
+		return unrelatedErr
+	}
+
+	gitRepo, err := git.OpenRepositoryCtx(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer gitRepo.Close()
+
+	remoteTips := make([]string, 0, len(remoteRefs))
+	knownTips := make([]string, 0, len(remoteRefs))
+	for _, tip := range remoteRefs {
+		remoteTips = append(remoteTips, tip)
+		if gitRepo.IsObjectExist(tip) {
+			knownTips = append(knownTips, tip)
+		}
+	}
+
+	checkPath := path
+	if len(knownTips) == 0 {
+		tmpPath, err := models.CreateTemporaryPath("push-mirror-check")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := models.RemoveTemporaryPath(tmpPath); err != nil {
+				log.Error("Unable to remove temporary directory %s: %v", tmpPath, err)
+			}
+		}()
+		if err := fetchIntoBorrowingRepository(ctx, path, tmpPath, remoteName, config, env, timeout); err != nil {
+			return err
+		}
+		checkPath, knownTips = tmpPath, remoteTips
+	}
+
+	// git merge-base A B C finds the common ancestors of A and any of B and C
+	for _, tip := range knownTips {
+		stdout, err := git.NewCommand(ctx, append([]string{"merge-base", tip}, localTips...)...).RunInDir(checkPath)
+		if err == nil && strings.TrimSpace(stdout) != "" {
+			return nil
+		}
+	}
+	return unrelatedErr
+}
+
+// parseLsRemoteCommits returns the commits of the refs listed by git ls-remote, annotated tags are peeled
+func parseLsRemoteCommits(stdout string) map[string]string {
+	commits := make(map[string]string)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// the peeled commit of a tag is listed after the tag with the ^{} suffix
+		commits[strings.TrimSuffix(fields[1], "^{}")] = fields[0]
+	}
+	return commits
+}
+
+// parseRefCommits returns the distinct commits of the refs listed by git for-each-ref with the format
+// "%(objecttype) %(objectname) %(*objecttype) %(*objectname)", annotated tags are peeled and other objects skipped
+func parseRefCommits(stdout string) []string {
+	seen := make(map[string]bool)
+	commits := make([]string, 0)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		var commit string
+		if len(fields) == 4 && fields[2] == "commit" {
+			commit = fields[3]
+		} else if len(fields) == 2 && fields[0] == "commit" {
+			commit = fields[1]
+		}
+		if commit != "" && !seen[commit] {
+			seen[commit] = true
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+// fetchIntoBorrowingRepository fetches the branches and tags of the remote of the repository at path into a new
+// repository at tmpPath, which uses the repository as alternate and includes its config to know the remote.
+// The fetched objects are therefore never stored in the repository itself.
+func fetchIntoBorrowingRepository(ctx context.Context, path, tmpPath, remoteName string, config, env []string, timeout time.Duration) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := git.InitRepository(ctx, tmpPath, true); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmpPath, "objects", "info", "alternates"), []byte(filepath.Join(absPath, "objects")+"\n"), 0o644); err != nil {
+		return err
+	}
+	if _, err := git.NewCommand(ctx, "config", "include.path", filepath.Join(absPath, "config")).RunInDir(tmpPath); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+
+	args := append(configArgs(config), "fetch", "--no-tags", "--quiet", remoteName,
+		"+"+git.BranchPrefix+"*:"+git.BranchPrefix+"*", "+"+git.TagPrefix+"*:"+git.TagPrefix+"*")
+	if _, err := git.NewCommand(ctx, args...).RunInDirTimeoutEnv(env, timeout, tmpPath); err != nil {
+		return fmt.Errorf("fetch: %v", err)
+	}
+	return nil
+}
+
+// lfsUploadRetries is the number of times an LFS object is uploaded again after its batch failed to upload.
+// The first retry waits lfsUploadRetryDelay, the delay doubles with every further retry.
+var (
+	lfsUploadRetries    = 3
+	lfsUploadRetryDelay = time.Second
+)
+
+// uploadLFSObjects uploads a batch of LFS objects. If the batch fails, every object of it is retried on its own
+// and the objects which still fail are returned with the last upload error. The remote only requests the
+// objects it is missing, so objects which have been uploaded before are not transferred again.
+func uploadLFSObjects(ctx context.Context, lfsClient lfs.Client, contentStore *lfs.ContentStore, pointers []lfs.Pointer) ([]lfs.Pointer, error) {
+	upload := func(pointers []lfs.Pointer) error {
+		return lfsClient.Upload(ctx, pointers, func(p lfs.Pointer, objectError error) (io.ReadCloser, error) {
 			if objectError != nil {
 				return nil, objectError
 			}
@@ -200,13 +957,75 @@ func pushAllLFSObjects(ctx context.Context, gitRepo *git.Repository, lfsClient l
 			}
 			return content, err
 		})
-		if err != nil {
+	}
+
+	batchErr := upload(pointers)
+	if batchErr == nil {
+		return nil, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	log.Warn("Uploading a batch of %d LFS objects failed, retrying them one by one: %v", len(pointers), batchErr)
+
+	var failed []lfs.Pointer
+	var lastErr error
+	for _, p := range pointers {
+		err := batchErr
+		delay := lfsUploadRetryDelay
+		for retry := 0; retry < lfsUploadRetries && err != nil; retry++ {
 			select {
 			case <-ctx.Done():
-				return nil
-			default:
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+
+			if err = upload([]lfs.Pointer{p}); err != nil && ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
 		}
+		if err != nil {
+			log.Error("Unable to upload LFS object %v: %v", p, err)
+			failed = append(failed, p)
+			lastErr = err
+		}
+	}
+	return failed, lastErr
+}
+
+// pushAllLFSObjects uploads the LFS objects of all pointers in the repository. Objects which can't be uploaded
+// don't stop the upload of the others, they are returned together with an error.
+func pushAllLFSObjects(ctx context.Context, gitRepo *git.Repository, lfsClient lfs.Client) ([]lfs.Pointer, error) {
+	contentStore := lfs.NewContentStore()
+
+	ctx, cancel := context.WithCancel(ctx)
+	pointerChan := make(chan lfs.PointerBlob, setting.LFSClient.PointerChannelBuffer)
+	errChan := make(chan error, 1)
+	go lfs.SearchPointerBlobs(ctx, gitRepo, pointerChan, errChan)
+	defer func() {
+		// Stop the enumeration and wait for it to finish on every return path,
+		// otherwise an early return would leave it blocked on pointerChan
+		cancel()
+		for range pointerChan {
+		}
+		for range errChan {
+		}
+	}()
+
+	var failed []lfs.Pointer
+	var uploadErr error
+	var throttle lfs.BatchThrottle
+	uploadObjects := func(pointers []lfs.Pointer) error {
+		if err := throttle.Wait(ctx); err != nil {
+			return err
+		}
+		batchFailed, err := uploadLFSObjects(ctx, lfsClient, contentStore, pointers)
+		if len(batchFailed) > 0 {
+			failed = append(failed, batchFailed...)
+			uploadErr = err
+			return nil
+		}
 		return err
 	}
 
@@ -215,7 +1034,7 @@ func pushAllLFSObjects(ctx context.Context, gitRepo *git.Repository, lfsClient l
 		exists, err := contentStore.Exists(pointerBlob.Pointer)
 		if err != nil {
 			log.Error("Error checking if LFS object %v exists: %v", pointerBlob.Pointer, err)
-			return err
+			return failed, err
 		}
 		if !exists {
 			log.Trace("Skipping missing LFS object %v", pointerBlob.Pointer)
@@ -225,22 +1044,25 @@ func pushAllLFSObjects(ctx context.Context, gitRepo *git.Repository, lfsClient l
 		batch = append(batch, pointerBlob.Pointer)
 		if len(batch) >= lfsClient.BatchSize() {
 			if err := uploadObjects(batch); err != nil {
-				return err
+				return failed, err
 			}
 			batch = nil
 		}
 	}
 	if len(batch) > 0 {
 		if err := uploadObjects(batch); err != nil {
-			return err
+			return failed, err
 		}
 	}
 
 	err, has := <-errChan
 	if has {
 		log.Error("Error enumerating LFS objects for repository: %v", err)
-		return err
+		return failed, err
 	}
 
-	return nil
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("%d LFS objects could not be uploaded: %v", len(failed), uploadErr)
+	}
+	return nil, nil
 }
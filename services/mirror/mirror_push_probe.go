@@ -0,0 +1,106 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// testPushRefPrefix is the namespace of the throwaway refs pushed by TestPushMirror, real refs are never below it
+const testPushRefPrefix = "refs/gitea-mirror-test/"
+
+// ErrPushMirrorAccessDenied is returned by TestPushMirror if the remote refuses the push,
+// e.g. because of wrong credentials or missing write permissions
+type ErrPushMirrorAccessDenied struct {
+	RemoteName string
+	Reason     string
+}
+
+func (err ErrPushMirrorAccessDenied) Error() string {
+	return fmt.Sprintf("push mirror remote %s denied the test push: %s", err.RemoteName, err.Reason)
+}
+
+// IsErrPushMirrorAccessDenied checks if an error is a ErrPushMirrorAccessDenied
+func IsErrPushMirrorAccessDenied(err error) bool {
+	_, ok := err.(ErrPushMirrorAccessDenied)
+	return ok
+}
+
+// TestPushMirror checks that the remote of a push mirror accepts pushes without touching its branches and tags.
+// It pushes the HEAD commit of the repository to a throwaway ref below refs/gitea-mirror-test/ and deletes the ref again.
+func TestPushMirror(ctx context.Context, m *repo_model.PushMirror) error {
+	if m.IsBundle {
+		return fmt.Errorf("bundle push mirror[%d] has no remote to push to", m.ID)
+	}
+	repo := m.GetRepository()
+	if repo == nil {
+		return repo_model.ErrPushMirrorRepoNotExist
+	}
+	repoPath := repo.RepoPath()
+	timeout := pushMirrorTimeout(m)
+
+	remoteAddr, err := git.GetRemoteAddress(ctx, repoPath, m.RemoteName)
+	if err != nil {
+		return fmt.Errorf("GetRemoteAddress: %v", err)
+	}
+	// pushing to a mirror remote by its name implies --mirror, which can't be combined with a refspec
+	config := []string{"remote." + m.RemoteName + ".mirror=false"}
+	var env []string
+	if providedAddr, err := remoteAddrWithProvidedCredentials(ctx, remoteAddr); err != nil {
+		return util.NewURLSanitizedError(err, remoteAddr, true)
+	} else if providedAddr != nil {
+		remoteAddr = providedAddr
+		var providedConfig []string
+		providedConfig, env = providedCredentialsConfig(providedAddr)
+		config = append(config, providedConfig...)
+	}
+
+	stdout, err := git.NewCommand(ctx, "rev-parse", "--verify", "HEAD^{commit}").RunInDir(repoPath)
+	if err != nil {
+		return fmt.Errorf("repository %s has no commit to push: %v", repo.FullName(), err)
+	}
+	suffix, err := util.CryptoRandomString(16)
+	if err != nil {
+		return err
+	}
+	refName := testPushRefPrefix + suffix
+
+	push := func(refspec string) error {
+		var stderr strings.Builder
+		err := gitPush(ctx, repoPath, git.PushOptions{
+			Remote:   m.RemoteName,
+			Refspecs: []string{refspec},
+			Config:   config,
+			Env:      env,
+			Timeout:  timeout,
+			Stderr:   &stderr,
+		})
+		if err == nil {
+			return nil
+		}
+		// other rejections, e.g. by a hook of the remote, are no access problem
+		if git.ClassifyGitError(fmt.Errorf("%v - %s", err, stderr.String())) == git.ErrorClassAuth {
+			return ErrPushMirrorAccessDenied{
+				RemoteName: m.RemoteName,
+				Reason:     util.NewURLSanitizer(remoteAddr, true).Replace(strings.TrimSpace(stderr.String())),
+			}
+		}
+		return util.NewURLSanitizedError(err, remoteAddr, true)
+	}
+
+	if err := push(strings.TrimSpace(stdout) + ":" + refName); err != nil {
+		return err
+	}
+	if err := push(":" + refName); err != nil {
+		return fmt.Errorf("unable to delete the test ref %s: %v", refName, err)
+	}
+	return nil
+}
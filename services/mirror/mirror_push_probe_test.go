@@ -0,0 +1,89 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestPushMirror(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+	defer func() {
+		gitPush = git.Push
+	}()
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	remotePath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+
+	m := &repo_model.PushMirror{ID: 1, RepoID: repo.ID, Repo: repo, RemoteName: "probe_test"}
+	assert.NoError(t, AddPushMirrorRemote(git.DefaultContext, m, remotePath))
+	defer func() {
+		assert.NoError(t, RemovePushMirrorRemote(git.DefaultContext, m))
+	}()
+
+	remoteRefs := func() string {
+		stdout, err := git.NewCommand(git.DefaultContext, "for-each-ref", "--format=%(refname)").RunInDir(remotePath)
+		assert.NoError(t, err)
+		return strings.TrimSpace(stdout)
+	}
+
+	var pushedRefs []string
+	gitPush = func(ctx context.Context, repoPath string, opts git.PushOptions) error {
+		if err := git.Push(ctx, repoPath, opts); err != nil {
+			return err
+		}
+		pushedRefs = append(pushedRefs, remoteRefs())
+		return nil
+	}
+
+	assert.NoError(t, TestPushMirror(git.DefaultContext, m))
+	// the temporary ref existed after the first push and was deleted by the second one
+	if assert.Len(t, pushedRefs, 2) {
+		assert.True(t, strings.HasPrefix(pushedRefs[0], testPushRefPrefix), pushedRefs[0])
+		assert.Empty(t, pushedRefs[1])
+	}
+	// the real branches and tags are not pushed
+	assert.Empty(t, remoteRefs())
+
+	t.Run("AccessDenied", func(t *testing.T) {
+		hook := filepath.Join(remotePath, "hooks", "pre-receive")
+		assert.NoError(t, os.WriteFile(hook, []byte("#!/bin/sh\necho 'Permission denied to user2' >&2\nexit 1\n"), 0o755))
+		defer os.Remove(hook)
+
+		err := TestPushMirror(git.DefaultContext, m)
+		if assert.True(t, IsErrPushMirrorAccessDenied(err), "%v", err) {
+			assert.Contains(t, err.Error(), "Permission denied to user2")
+		}
+		assert.Empty(t, remoteRefs())
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		hook := filepath.Join(remotePath, "hooks", "pre-receive")
+		assert.NoError(t, os.WriteFile(hook, []byte("#!/bin/sh\necho 'file too large' >&2\nexit 1\n"), 0o755))
+		defer os.Remove(hook)
+
+		// the remote accepts pushes in general, it only rejected the ref
+		err := TestPushMirror(git.DefaultContext, m)
+		assert.Error(t, err)
+		assert.False(t, IsErrPushMirrorAccessDenied(err), "%v", err)
+		assert.Empty(t, remoteRefs())
+	})
+
+	t.Run("Bundle", func(t *testing.T) {
+		assert.Error(t, TestPushMirror(git.DefaultContext, &repo_model.PushMirror{ID: 2, RepoID: repo.ID, Repo: repo, IsBundle: true}))
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var invalidRemoteNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// NewPushMirrorRemoteName returns the name of the git remote of a new push mirror of the repository,
+// following setting.Mirror.PushRemoteNames
+func NewPushMirrorRemoteName(ctx context.Context, repo *repo_model.Repository, addr string) (string, error) {
+	if setting.Mirror.PushRemoteNames != "host" {
+		remoteSuffix, err := util.CryptoRandomString(10)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("remote_mirror_%s", remoteSuffix), nil
+	}
+
+	used, err := usedRemoteNames(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	return hostRemoteName(addr, used), nil
+}
+
+// hostRemoteName returns the first name push-<host>-<n> which is not used yet
+func hostRemoteName(addr string, used map[string]bool) string {
+	host := "local"
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		host = strings.Trim(invalidRemoteNameChars.ReplaceAllString(strings.ToLower(u.Host), "-"), ".-")
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("push-%s-%d", host, n)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+// usedRemoteNames returns the names of the push mirrors and the git remotes of the repository
+func usedRemoteNames(ctx context.Context, repo *repo_model.Repository) (map[string]bool, error) {
+	used := make(map[string]bool)
+	pushMirrors, err := repo_model.GetPushMirrorsByRepoID(repo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPushMirrorsByRepoID: %v", err)
+	}
+	for _, m := range pushMirrors {
+		used[m.RemoteName] = true
+	}
+
+	stdout, err := git.NewCommand(ctx, "remote").RunInDir(repo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("git remote: %v", err)
+	}
+	for _, name := range strings.Fields(stdout) {
+		used[name] = true
+	}
+	return used, nil
+}
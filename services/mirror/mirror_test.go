@@ -0,0 +1,126 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(lfsServer bool) {
+		setting.LFS.StartServer = lfsServer
+	}(setting.LFS.StartServer)
+	setting.LFS.StartServer = false
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+	remotePath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+
+	m := &repo_model.PushMirror{RepoID: repo.ID, Repo: repo, RemoteName: "maintenance", Interval: time.Hour}
+	assert.NoError(t, repo_model.InsertPushMirror(m))
+	assert.NoError(t, AddPushMirrorRemote(git.DefaultContext, m, remotePath))
+	defer func() {
+		assert.NoError(t, RemovePushMirrorRemote(git.DefaultContext, m))
+	}()
+
+	SetMaintenanceMode(true)
+	defer SetMaintenanceMode(false)
+	assert.True(t, IsMaintenanceMode())
+
+	// the due push mirror isn't queued, the queue isn't even initialized here
+	assert.NoError(t, Update(git.DefaultContext, 10, 10))
+
+	assert.False(t, SyncPushMirror(git.DefaultContext, m.ID))
+	assert.False(t, SyncPullMirror(git.DefaultContext, repo.ID))
+	m = unittest.AssertExistsAndLoadBean(t, &repo_model.PushMirror{ID: m.ID}).(*repo_model.PushMirror)
+	assert.Zero(t, m.LastUpdateUnix)
+	_, err := git.NewCommand(git.DefaultContext, "rev-parse", "--verify", "master").RunInDir(remotePath)
+	assert.Error(t, err)
+
+	// the mirror is synchronized once the maintenance mode is left
+	SetMaintenanceMode(false)
+	assert.False(t, IsMaintenanceMode())
+	assert.True(t, SyncPushMirror(git.DefaultContext, m.ID))
+	m = unittest.AssertExistsAndLoadBean(t, &repo_model.PushMirror{ID: m.ID}).(*repo_model.PushMirror)
+	assert.NotZero(t, m.LastUpdateUnix)
+	_, err = git.NewCommand(git.DefaultContext, "rev-parse", "--verify", "master").RunInDir(remotePath)
+	assert.NoError(t, err)
+}
+
+func TestSyncPullMirrorLFS(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(lfsServer bool) {
+		setting.LFS.StartServer = lfsServer
+	}(setting.LFS.StartServer)
+	setting.LFS.StartServer = true
+
+	// the source stores its LFS objects like a local LFS endpoint, next to the git data
+	remotePath := filepath.Join(t.TempDir(), "source.git")
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+	workPath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, workPath, false))
+	signature := git.Signature{Email: "test@example.com", Name: "test", When: time.Now()}
+	commitLFSObject := func(name, content string) lfs.Pointer {
+		pointer, err := lfs.GeneratePointer(strings.NewReader(content))
+		assert.NoError(t, err)
+		objectPath := filepath.Join(remotePath, "lfs", "objects", pointer.Oid[0:2], pointer.Oid[2:4], pointer.Oid)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(objectPath), os.ModePerm))
+		assert.NoError(t, os.WriteFile(objectPath, []byte(content), 0o644))
+
+		assert.NoError(t, os.WriteFile(filepath.Join(workPath, name), []byte(pointer.StringContent()), 0o644))
+		assert.NoError(t, git.AddChanges(workPath, true))
+		assert.NoError(t, git.CommitChanges(workPath, git.CommitChangesOptions{Committer: &signature, Author: &signature, Message: "Add " + name}))
+		_, err = git.NewCommand(git.DefaultContext, "push", remotePath, "HEAD:refs/heads/master").RunInDir(workPath)
+		assert.NoError(t, err)
+		return pointer
+	}
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 2}).(*repo_model.Repository)
+	_, err := git.NewCommand(git.DefaultContext, "remote", "add", "--mirror=fetch", "origin", remotePath).RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+	m := &repo_model.Mirror{RepoID: repo.ID, Repo: repo, EnablePrune: true, LFS: true}
+	assert.NoError(t, db.Insert(db.DefaultContext, m))
+
+	first := commitLFSObject("first.bin", "first LFS object")
+	_, ok := runSync(git.DefaultContext, m)
+	assert.True(t, ok)
+	_, err = models.GetLFSMetaObjectByOid(repo.ID, first.Oid)
+	assert.NoError(t, err)
+
+	// the update of the mirror introduces a new pointer whose object is fetched too
+	second := commitLFSObject("second.bin", "second LFS object")
+	_, ok = runSync(git.DefaultContext, m)
+	assert.True(t, ok)
+	_, err = models.GetLFSMetaObjectByOid(repo.ID, second.Oid)
+	assert.NoError(t, err)
+	exist, err := lfs.NewContentStore().Exists(second)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	// mirrors without LFS only fetch the pointers
+	m.LFS = false
+	third := commitLFSObject("third.bin", "third LFS object")
+	_, ok = runSync(git.DefaultContext, m)
+	assert.True(t, ok)
+	_, err = models.GetLFSMetaObjectByOid(repo.ID, third.Oid)
+	assert.ErrorIs(t, err, models.ErrLFSObjectNotExist)
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// RefMismatch is a branch or tag which points to different objects in a repository and its push mirror remote.
+// An empty SHA means that the ref doesn't exist on that side.
+type RefMismatch struct {
+	RefName   string
+	LocalSHA  string
+	RemoteSHA string
+}
+
+// VerifyPushMirror compares the branches and tags of the repository with the ones of the push mirror remote
+// and returns the refs which don't match. Tags excluded by the tag filter of the push mirror are ignored.
+func VerifyPushMirror(ctx context.Context, m *repo_model.PushMirror) ([]RefMismatch, error) {
+	if m.IsBundle {
+		return nil, fmt.Errorf("bundle push mirror[%d] has no remote to verify", m.ID)
+	}
+
+	repoPath := m.GetRepository().RepoPath()
+	timeout := time.Duration(setting.Git.Timeout.Mirror) * time.Second
+
+	remoteAddr, err := git.GetRemoteAddress(ctx, repoPath, m.RemoteName)
+	if err != nil {
+		return nil, fmt.Errorf("GetRemoteAddress: %v", err)
+	}
+
+	stdout, err := git.NewCommand(ctx, "for-each-ref", "--format=%(objectname) %(refname)", git.BranchPrefix, git.TagPrefix).RunInDir(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("for-each-ref: %v", err)
+	}
+	localRefs := parseRefList(stdout, m.TagFilter)
+
+	var config, env []string
+	if providedAddr, err := remoteAddrWithProvidedCredentials(ctx, remoteAddr); err != nil {
+		return nil, util.NewURLSanitizedError(err, remoteAddr, true)
+	} else if providedAddr != nil {
+		remoteAddr = providedAddr
+		config, env = providedCredentialsConfig(providedAddr)
+	}
+	args := append(configArgs(config), "ls-remote", "--heads", "--tags", m.RemoteName)
+	remoteStdout, err := git.NewCommand(ctx, args...).RunInDirTimeoutEnv(env, timeout, repoPath)
+	if err != nil {
+		return nil, util.NewURLSanitizedError(fmt.Errorf("ls-remote: %v", err), remoteAddr, true)
+	}
+	remoteRefs := parseRefList(string(remoteStdout), m.TagFilter)
+
+	mappings, err := ParseRefMapping(m.RefMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ref mapping: %v", err)
+	}
+	if len(mappings) > 0 {
+		localRefs, remoteRefs = applyRefMapping(localRefs, remoteRefs, mappings)
+	}
+
+	var mismatches []RefMismatch
+	for refName, localSHA := range localRefs {
+		if remoteSHA := remoteRefs[refName]; remoteSHA != localSHA {
+			mismatches = append(mismatches, RefMismatch{RefName: refName, LocalSHA: localSHA, RemoteSHA: remoteSHA})
+		}
+	}
+	for refName, remoteSHA := range remoteRefs {
+		if _, ok := localRefs[refName]; !ok {
+			mismatches = append(mismatches, RefMismatch{RefName: refName, RemoteSHA: remoteSHA})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].RefName < mismatches[j].RefName
+	})
+	return mismatches, nil
+}
+
+// parseRefList parses "<sha> <ref>" lines as printed by for-each-ref and ls-remote into a map of ref names to SHAs.
+// Peeled tags and the tags not matching the tag filter are skipped.
+func parseRefList(output, tagFilter string) map[string]string {
+	refs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasSuffix(fields[1], "^{}") {
+			continue
+		}
+		if tagFilter != "" && strings.HasPrefix(fields[1], git.TagPrefix) {
+			if matched, _ := path.Match(tagFilter, strings.TrimPrefix(fields[1], git.TagPrefix)); !matched {
+				continue
+			}
+		}
+		refs[fields[1]] = fields[0]
+	}
+	return refs
+}
+
+// applyRefMapping renames the mapped local branches to their remote names and drops the branches which are not mapped
+func applyRefMapping(localRefs, remoteRefs map[string]string, mappings []RefMapping) (map[string]string, map[string]string) {
+	mappedLocal := make(map[string]string)
+	mappedRemote := make(map[string]string)
+	for refName, sha := range localRefs {
+		if strings.HasPrefix(refName, git.TagPrefix) {
+			mappedLocal[refName] = sha
+		}
+	}
+	for refName, sha := range remoteRefs {
+		if strings.HasPrefix(refName, git.TagPrefix) {
+			mappedRemote[refName] = sha
+		}
+	}
+	for _, mapping := range mappings {
+		if sha, ok := localRefs[mapping.Local]; ok {
+			mappedLocal[mapping.Remote] = sha
+		}
+		if sha, ok := remoteRefs[mapping.Remote]; ok {
+			mappedRemote[mapping.Remote] = sha
+		}
+	}
+	return mappedLocal, mappedRemote
+}
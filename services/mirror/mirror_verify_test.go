@@ -0,0 +1,79 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"strings"
+	"testing"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPushMirror(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+
+	defer func(lfsServer bool) {
+		setting.LFS.StartServer = lfsServer
+	}(setting.LFS.StartServer)
+	setting.LFS.StartServer = false
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1}).(*repo_model.Repository)
+
+	remotePath := t.TempDir()
+	assert.NoError(t, git.InitRepository(git.DefaultContext, remotePath, true))
+
+	m := &repo_model.PushMirror{ID: 1, RepoID: repo.ID, Repo: repo, RemoteName: "verify_test"}
+	assert.NoError(t, AddPushMirrorRemote(git.DefaultContext, m, remotePath))
+	defer func() {
+		assert.NoError(t, RemovePushMirrorRemote(git.DefaultContext, m))
+	}()
+
+	_, err := runPushSync(git.DefaultContext, m)
+	assert.NoError(t, err)
+
+	mismatches, err := VerifyPushMirror(git.DefaultContext, m)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	masterSHA, err := git.NewCommand(git.DefaultContext, "rev-parse", "master").RunInDir(remotePath)
+	assert.NoError(t, err)
+	branch2SHA, err := git.NewCommand(git.DefaultContext, "rev-parse", "branch2").RunInDir(remotePath)
+	assert.NoError(t, err)
+	tagSHA, err := git.NewCommand(git.DefaultContext, "rev-parse", "v1.1").RunInDir(remotePath)
+	assert.NoError(t, err)
+
+	// the remote is behind on branch2, lost a tag and got an unknown branch
+	for _, args := range [][]string{
+		{"update-ref", git.BranchPrefix + "branch2", "master"},
+		{"update-ref", "-d", git.TagPrefix + "v1.1"},
+		{"update-ref", git.BranchPrefix + "remote-only", "master"},
+	} {
+		_, err := git.NewCommand(git.DefaultContext, args...).RunInDir(remotePath)
+		assert.NoError(t, err)
+	}
+
+	mismatches, err = VerifyPushMirror(git.DefaultContext, m)
+	assert.NoError(t, err)
+	assert.Equal(t, []RefMismatch{
+		{RefName: git.BranchPrefix + "branch2", LocalSHA: strings.TrimSpace(branch2SHA), RemoteSHA: strings.TrimSpace(masterSHA)},
+		{RefName: git.BranchPrefix + "remote-only", RemoteSHA: strings.TrimSpace(masterSHA)},
+		{RefName: git.TagPrefix + "v1.1", LocalSHA: strings.TrimSpace(tagSHA)},
+	}, mismatches)
+
+	// tags excluded by the tag filter are not expected on the remote
+	m.TagFilter = "v2*"
+	mismatches, err = VerifyPushMirror(git.DefaultContext, m)
+	assert.NoError(t, err)
+	assert.Len(t, mismatches, 2)
+
+	m.IsBundle = true
+	_, err = VerifyPushMirror(git.DefaultContext, m)
+	assert.Error(t, err)
+}
@@ -375,7 +375,7 @@ func pushUpdateAddTags(ctx context.Context, repo *repo_model.Repository, gitRepo
 				NumCommits:   commitsCount,
 				Note:         "",
 				IsDraft:      false,
-				IsPrerelease: false,
+				IsPrerelease: repo_module.IsPrereleaseTag(tags[i]),
 				IsTag:        true,
 				CreatedUnix:  timeutil.TimeStamp(createdAt.Unix()),
 			}
@@ -392,6 +392,9 @@ func pushUpdateAddTags(ctx context.Context, repo *repo_model.Repository, gitRepo
 			if rel.IsTag && author != nil {
 				rel.PublisherID = author.ID
 			}
+			if rel.IsTag {
+				rel.IsPrerelease = repo_module.IsPrereleaseTag(tags[i])
+			}
 			if err = models.UpdateRelease(ctx, rel); err != nil {
 				return fmt.Errorf("Update: %v", err)
 			}
@@ -13,6 +13,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -258,9 +259,10 @@ func DeliverHooks(ctx context.Context) {
 }
 
 var (
-	webhookHTTPClient *http.Client
-	once              sync.Once
-	hostMatchers      []glob.Glob
+	webhookHTTPClient     *http.Client
+	webhookHTTPClientOnce sync.Once
+	once                  sync.Once
+	hostMatchers          []glob.Glob
 )
 
 func webhookProxy() func(req *http.Request) (*url.URL, error) {
@@ -288,24 +290,57 @@ func webhookProxy() func(req *http.Request) (*url.URL, error) {
 	}
 }
 
-// InitDeliverHooks starts the hooks delivery thread
-func InitDeliverHooks() {
-	timeout := time.Duration(setting.Webhook.DeliverTimeout) * time.Second
-
+// webhookAllowedHostMatcher returns the matcher of setting.Webhook.AllowedHostList, only external hosts are allowed by default
+func webhookAllowedHostMatcher() *hostmatcher.HostMatchList {
 	allowedHostListValue := setting.Webhook.AllowedHostList
 	if allowedHostListValue == "" {
 		allowedHostListValue = hostmatcher.MatchBuiltinExternal
 	}
-	allowedHostMatcher := hostmatcher.ParseHostMatchList("webhook.ALLOWED_HOST_LIST", allowedHostListValue)
-
-	webhookHTTPClient = &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: setting.Webhook.SkipTLSVerify},
-			Proxy:           webhookProxy(),
-			DialContext:     hostmatcher.NewDialContext("webhook", allowedHostMatcher, nil),
-		},
+	return hostmatcher.ParseHostMatchList("webhook.ALLOWED_HOST_LIST", allowedHostListValue)
+}
+
+// IsWebhookURLAllowed checks if webhooks can be delivered to the URL, i.e. it is a HTTP(S) URL whose host
+// is allowed by setting.Webhook.AllowedHostList. A host which is not allowed by its name must resolve to allowed IPs only.
+func IsWebhookURLAllowed(hookURL string) bool {
+	u, err := url.Parse(hookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	allowedHostMatcher := webhookAllowedHostMatcher()
+	if allowedHostMatcher.MatchHostName(u.Hostname()) {
+		return true
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !allowedHostMatcher.MatchIPAddr(ip) {
+			return false
+		}
 	}
+	return true
+}
+
+// HTTPClient returns the HTTP client webhooks are delivered with, it respects the webhook settings and only
+// connects to the hosts allowed by setting.Webhook.AllowedHostList. The client is created on the first call.
+func HTTPClient() *http.Client {
+	webhookHTTPClientOnce.Do(func() {
+		webhookHTTPClient = &http.Client{
+			Timeout: time.Duration(setting.Webhook.DeliverTimeout) * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: setting.Webhook.SkipTLSVerify},
+				Proxy:           webhookProxy(),
+				DialContext:     hostmatcher.NewDialContext("webhook", webhookAllowedHostMatcher(), nil),
+			},
+		}
+	})
+	return webhookHTTPClient
+}
+
+// InitDeliverHooks starts the hooks delivery thread
+func InitDeliverHooks() {
+	HTTPClient()
 
 	go graceful.GetManager().RunWithShutdownContext(DeliverHooks)
 }